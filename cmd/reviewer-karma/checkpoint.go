@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/master-wayne7/reviewer-karma-action/internal/atomicfile"
+	"github.com/master-wayne7/reviewer-karma-action/internal/config"
+	"github.com/master-wayne7/reviewer-karma-action/internal/githubapi"
+)
+
+// checkpointInterval is how many scored PRs a long run saves its progress after
+const checkpointInterval = 100
+
+// maxCheckpointAge is how long a full recreation checkpoint can be resumed.
+// PRs scored long ago may have had activity since.
+const maxCheckpointAge = 24 * time.Hour
+
+// checkpoint records the progress of a full recreation, so an interrupted
+// run continues where it stopped instead of starting over
+type checkpoint struct {
+	StartedAt          time.Time      `json:"started_at"`
+	ScoringFingerprint string         `json:"scoring_fingerprint"`
+	Reviewers          map[string]int `json:"reviewers"`
+	ProcessedPRs       []int          `json:"processed_prs"`
+
+	// Totals of each leaderboard window, anchored at StartedAt
+	WindowReviewers []map[string]int `json:"window_reviewers,omitempty"`
+}
+
+// runFingerprint identifies what a full recreation scores: the scoring
+// settings and, when set, the time window and the leaderboard windows
+func runFingerprint(cfg config.Config) string {
+	fingerprint := cfg.ScoringFingerprint()
+	if !cfg.Since.IsZero() || !cfg.Until.IsZero() {
+		fingerprint += fmt.Sprintf(" %s..%s", cfg.Since.Format(time.RFC3339), cfg.Until.Format(time.RFC3339))
+	}
+	if len(cfg.LeaderboardWindows) > 0 {
+		days := make([]string, len(cfg.LeaderboardWindows))
+		for i, window := range cfg.LeaderboardWindows {
+			days[i] = strconv.Itoa(window.Days)
+		}
+		fingerprint += " windows " + strings.Join(days, ",")
+	}
+	return fingerprint
+}
+
+// loadCheckpoint reads the checkpoint at path. A missing checkpoint, or one
+// that is too old or was scored with a different configuration, starts a
+// fresh one.
+func loadCheckpoint(path, fingerprint string) (*checkpoint, error) {
+	fresh := &checkpoint{
+		StartedAt:          time.Now().UTC(),
+		ScoringFingerprint: fingerprint,
+		Reviewers:          make(map[string]int),
+	}
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return fresh, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+
+	var cp checkpoint
+	if err := json.Unmarshal(content, &cp); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint %s: %w; remove it to start over", path, err)
+	}
+
+	switch {
+	case cp.ScoringFingerprint != fingerprint:
+		fmt.Printf("⚙️ Ignoring checkpoint %s, it was scored with a different configuration\n", path)
+		return fresh, nil
+	case time.Since(cp.StartedAt) > maxCheckpointAge:
+		fmt.Printf("⌛ Ignoring checkpoint %s, it was started more than %s ago\n", path, maxCheckpointAge)
+		return fresh, nil
+	}
+
+	if cp.Reviewers == nil {
+		cp.Reviewers = make(map[string]int)
+	}
+	return &cp, nil
+}
+
+// processed returns the set of PRs already scored
+func (cp *checkpoint) processed() map[int]bool {
+	done := make(map[int]bool, len(cp.ProcessedPRs))
+	for _, prNumber := range cp.ProcessedPRs {
+		done[prNumber] = true
+	}
+	return done
+}
+
+// save writes the checkpoint to path
+func (cp *checkpoint) save(path string) error {
+	content, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint: %w", err)
+	}
+	if err := atomicfile.Write(path, content, 0644); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	return nil
+}
+
+// removeCheckpoint deletes the checkpoint at path once a run completed
+func removeCheckpoint(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove checkpoint: %w", err)
+	}
+	return nil
+}
+
+// stopsRun reports whether a fetch error means the remaining PRs can't be
+// fetched either, because the rate limit is exhausted or the run was canceled
+func stopsRun(ctx context.Context, err error) bool {
+	return err != nil && (githubapi.IsRateLimit(err) || ctx.Err() != nil)
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/master-wayne7/reviewer-karma-action/internal/config"
+	"github.com/master-wayne7/reviewer-karma-action/internal/githubapi"
+	"github.com/master-wayne7/reviewer-karma-action/internal/karma"
+	"github.com/master-wayne7/reviewer-karma-action/internal/scorer"
+	"github.com/master-wayne7/reviewer-karma-action/internal/storage"
+)
+
+// runEventUpdate re-scores only the pull request of the event that triggered
+// the run. When the PR's events are stored and the payload carries the
+// changed review or comment, it is patched into the stored events without
+// any API calls; otherwise the PR's activity is fetched.
+func runEventUpdate(ctx context.Context, source githubapi.Source, cfg config.Config, trigger *githubapi.Trigger) error {
+	pr := trigger.PullRequest
+	fmt.Printf("⚡ Running in event mode for %s (%s) on PR #%d: %s\n", trigger.Event, trigger.Action, pr.Number, pr.Title)
+
+	store, err := openStorage(cfg)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	tx, err := store.Begin()
+	if err != nil {
+		return fmt.Errorf("error loading karma data: %w; fix or remove %s to start fresh", err, cfg.DataFile)
+	}
+	karmaData := tx.Data()
+	tx.SetRescorer(rescorer(newEngine(cfg)))
+
+	if err := checkScoringFingerprint(karmaData, cfg); err != nil {
+		return err
+	}
+
+	processedAt, processed := karmaData.ProcessedPRs[pr.Number]
+	legacy := processed && !karmaData.HasPRRecord(pr.Number)
+	if legacy {
+		fmt.Printf("ℹ️ PR #%d was processed by an older version, only its activity since then is scored\n", pr.Number)
+	}
+
+	sc := newScorer(source, cfg)
+	summary := newRunSummary()
+
+	var events []karma.Event
+	var awards []karma.Award
+	stored, hasEvents := karmaData.Events[pr.Number]
+	patched, ok := scorer.ApplyTrigger(replayEvents(pr.Number, stored), trigger)
+	if processed && hasEvents && ok {
+		fmt.Printf("🩹 Applying the changed activity to the stored events of PR #%d\n", pr.Number)
+		result := sc.Engine().EvaluateAll(patched)
+		for name, count := range result.Excluded {
+			summary.excluded[name] += count
+		}
+		events, awards = patched, result.Awards
+		// The stored time is kept, so the next full incremental update
+		// still checks the PR for activity the event didn't carry
+	} else {
+		fmt.Printf("🔍 Processing PR #%d: %s\n", pr.Number, pr.Title)
+		events, awards, err = calculatePRKarma(ctx, sc, pr, summary)
+		if err != nil {
+			return fmt.Errorf("error scoring PR #%d: %w", pr.Number, err)
+		}
+		processedAt = pr.UpdatedAt
+		if processedAt.IsZero() {
+			processedAt = time.Now()
+		}
+	}
+
+	update := storage.PRUpdate{
+		PRNumber:    pr.Number,
+		Events:      storedEvents(events),
+		Entries:     ledgerEntries(awards),
+		ProcessedAt: processedAt,
+	}
+	if legacy {
+		update = legacyUpdate(karmaData, update, awards)
+	}
+	if err := tx.Apply(update); err != nil {
+		return fmt.Errorf("error updating karma for PR #%d: %w", pr.Number, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error saving karma data: %w", err)
+	}
+
+	fmt.Printf("✅ Processed PR #%d\n", pr.Number)
+	summary.print()
+
+	return writeLeaderboard(cfg, karmaData, sc.Engine())
+}
+
+// writeLeaderboard writes the leaderboard for the totals in data, with a
+// table per leaderboard window
+func writeLeaderboard(cfg config.Config, data *storage.KarmaData, engine *karma.Engine) error {
+	leaderboards := dataLeaderboards(cfg, data, time.Now())
+	if err := karma.WriteLeaderboards(cfg.LeaderboardFile, leaderboards, engine); err != nil {
+		return fmt.Errorf("error writing leaderboard file: %w", err)
+	}
+	return nil
+}
@@ -2,14 +2,21 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/google/go-github/v62/github"
 	"github.com/master-wayne7/reviewer-karma-action/internal/config"
 	"github.com/master-wayne7/reviewer-karma-action/internal/githubapi"
 	"github.com/master-wayne7/reviewer-karma-action/internal/karma"
+	"github.com/master-wayne7/reviewer-karma-action/internal/scorer"
 	"github.com/master-wayne7/reviewer-karma-action/internal/storage"
 	"golang.org/x/oauth2"
 )
@@ -26,13 +33,64 @@ func main() {
 		fmt.Println("  REVIEW_POINT          - Points for reviews (default: 1)")
 		fmt.Println("  POSITIVE_EMOJI_POINT  - Points for emojis (default: 2)")
 		fmt.Println("  CONSTRUCTIVE_COMMENT_POINT - Points for comments (default: 1)")
+		fmt.Println("  ISSUE_COMMENT_POINT   - Points for conversation comments on PRs (default: 0)")
+		fmt.Println("  REACTION_POINTS       - Points per reaction type, e.g. \"+1=1,rocket=2\" (default: none)")
+		fmt.Println("  REACTION_CAP          - Maximum reaction points per user per PR (default: 5)")
+		fmt.Println("  EXCLUDE_SELF_ACTIVITY - Ignore activity by authors on their own PRs (default: true)")
+		fmt.Println("  SELF_ACTIVITY_PERCENT - Percentage of points for own-PR activity when not excluded (default: 100)")
+		fmt.Println("  APPROVED_REVIEW_POINT - Points for approving reviews (default: REVIEW_POINT)")
+		fmt.Println("  CHANGES_REQUESTED_REVIEW_POINT - Points for reviews requesting changes (default: REVIEW_POINT)")
+		fmt.Println("  COMMENTED_REVIEW_POINT - Points for comment-only reviews (default: REVIEW_POINT)")
+		fmt.Println("  DISMISSED_REVIEW_POINT - Points for dismissed reviews, may be negative (default: 0)")
+		fmt.Println("  REVOKE_DISMISSED_REVIEWS - Dismissed reviews earn no points at all (default: false)")
 		fmt.Println("  INCREMENTAL_UPDATE    - Use incremental updates (default: false)")
+		fmt.Println("  CONCURRENCY           - Pull requests whose activity is fetched at the same time (default: 4, max: 16)")
+		fmt.Println("  SINCE                 - Full recreation only scores activity from this date or RFC 3339 time on (default: none)")
+		fmt.Println("  UNTIL                 - Full recreation only scores activity up to the end of this date, or before this time (default: none)")
+		fmt.Println("  FETCHER               - \"rest\" or \"graphql\" API for fetching pull request activity (default: rest)")
+		fmt.Println("  EVENT_MODE            - Incremental updates only score the PR of the triggering event (default: true)")
+		fmt.Println("  LEADERBOARD_FILE      - Leaderboard output path (default: REVIEWERS.md)")
+		fmt.Println("  LEADERBOARD_WINDOWS   - Ranking tables, e.g. \"This Week=7,This Month=30,All Time=all\" (default: one all-time table)")
+		fmt.Println("  DATA_FILE             - Karma data path for incremental updates (default: .karma-data.json, .karma-data.db with sqlite)")
+		fmt.Println("  STORAGE_BACKEND       - \"json\", \"sqlite\" or \"git\" storage for DATA_FILE (default: json)")
+		fmt.Println("  DATA_BRANCH           - Branch the git storage backend commits DATA_FILE to (default: karma-data)")
+		fmt.Println("  DATA_REMOTE           - Remote the git storage backend fetches and pushes (default: origin)")
+		fmt.Println("  CACHE_DIR             - Directory caching GitHub API responses between runs (default: none)")
+		fmt.Println("  CHECKPOINT_FILE       - Progress of an interrupted full recreation, resumed by the next run (default: .karma-checkpoint.json)")
+		fmt.Println("  ON_SCORING_CHANGE     - \"rescore\" or \"fail\" when the scoring configuration changed (default: rescore)")
+		fmt.Println("  CONFIG_FILE           - Configuration file (default: .github/reviewer-karma.yml)")
 		fmt.Println("")
 		fmt.Println("Usage:")
 		fmt.Println("  ./reviewer-karma [--help]")
+		fmt.Println("  ./reviewer-karma [--since DATE] [--until DATE]")
+		fmt.Println("  ./reviewer-karma validate-config [config-file]")
+		fmt.Println("  ./reviewer-karma rescore")
+		fmt.Println("  ./reviewer-karma migrate [--dry-run]")
 		os.Exit(0)
 	}
 
+	// Validate configuration without running
+	if len(os.Args) > 1 && os.Args[1] == "validate-config" {
+		os.Exit(runValidateConfig(os.Args[2:]))
+	}
+
+	// Re-score stored events with the current configuration
+	if len(os.Args) > 1 && os.Args[1] == "rescore" {
+		os.Exit(runRescore(os.Args[2:]))
+	}
+
+	// Upgrade the karma data file to the current schema
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(os.Args[2:]))
+	}
+
+	// Command line options override their environment variables
+	if err := applyRunFlags(os.Args[1:]); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		fmt.Println("Usage: reviewer-karma [--since DATE] [--until DATE]")
+		os.Exit(2)
+	}
+
 	// Get GitHub token from environment
 	token := os.Getenv("GITHUB_TOKEN")
 	if token == "" {
@@ -57,256 +115,550 @@ func main() {
 	repoName := parts[1]
 
 	// Load configuration
-	cfg := config.Load()
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("❌ Invalid configuration: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Stop fetching on cancellation, so the progress so far can be saved
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	// Create GitHub client
-	ctx := context.Background()
+	// Create GitHub client that waits out rate limits and retries server errors
 	ts := oauth2.StaticTokenSource(
 		&oauth2.Token{AccessToken: token},
 	)
 	tc := oauth2.NewClient(ctx, ts)
+	var cache *githubapi.CacheTransport
+	if cfg.CacheDir != "" {
+		// Unchanged responses are revalidated without using up the rate limit
+		cache = githubapi.NewCacheTransport(tc.Transport, cfg.CacheDir)
+		tc.Transport = cache
+	}
+	retryOpts := githubapi.DefaultRetryOptions()
+	retryOpts.OnWait = func(reason string, wait time.Duration) {
+		fmt.Printf("⏳ %s, waiting %s before retrying\n", reason, wait.Round(time.Second))
+	}
+	tc.Transport = githubapi.NewRetryTransport(tc.Transport, retryOpts)
 	client := github.NewClient(tc)
 
 	fmt.Printf("🔍 Analyzing repository: %s/%s\n", repoOwner, repoName)
-	fmt.Printf("📊 Karma configuration: Review=%d, Emoji=%d, Constructive=%d\n",
-		cfg.ReviewPoint, cfg.PositiveEmojiPoint, cfg.ConstructiveCommentPoint)
+	if cfg.ConfigFile != "" {
+		fmt.Printf("⚙️ Using configuration file: %s\n", cfg.ConfigFile)
+	}
+	fmt.Printf("📊 Karma configuration: Approved=%d, ChangesRequested=%d, Commented=%d, Dismissed=%d, Emoji=%d, Constructive=%d, IssueComment=%d\n",
+		cfg.ApprovedReviewPoint, cfg.ChangesRequestedReviewPoint, cfg.CommentedReviewPoint, cfg.DismissedReviewPoint,
+		cfg.PositiveEmojiPoint, cfg.ConstructiveCommentPoint, cfg.IssueCommentPoint)
 	fmt.Printf("🔄 Update mode: %s\n", getUpdateModeString(cfg.IncrementalUpdate))
 
-	if cfg.IncrementalUpdate {
-		runIncrementalUpdate(ctx, client, repoOwner, repoName, cfg)
-	} else {
-		runFullRecreation(ctx, client, repoOwner, repoName, cfg)
+	var source githubapi.Source = githubapi.NewGitHubSource(client, repoOwner, repoName)
+	if cfg.Fetcher == config.FetcherGraphQL {
+		// PRs with more activity than a GraphQL query returns use the REST source
+		source = githubapi.NewGraphQLSource(tc, repoOwner, repoName, source, githubapi.GraphQLOptions{
+			Reactions: len(cfg.ReactionPoints) > 0,
+		})
+		fmt.Println("🧬 Fetching pull request activity with the GraphQL API")
+	}
+
+	// Pull request events only need their own PR re-scored
+	var trigger *githubapi.Trigger
+	if cfg.IncrementalUpdate && cfg.EventMode {
+		trigger, err = githubapi.ReadTrigger(os.Getenv("GITHUB_EVENT_NAME"), os.Getenv("GITHUB_EVENT_PATH"))
+		if errors.Is(err, githubapi.ErrNotPullRequest) {
+			fmt.Println("ℹ️ The triggering comment is not on a pull request, nothing to score")
+			return
+		}
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	switch {
+	case trigger != nil:
+		err = runEventUpdate(ctx, source, cfg, trigger)
+	case cfg.IncrementalUpdate:
+		err = runIncrementalUpdate(ctx, source, cfg)
+	default:
+		err = runFullRecreation(ctx, source, cfg)
+	}
+	if cache != nil {
+		reportCache(cache)
+	}
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(1)
 	}
 
 	fmt.Println("✅ Reviewer karma leaderboard generated successfully!")
 }
 
-func runFullRecreation(ctx context.Context, client *github.Client, owner, repo string, cfg config.Config) {
+// applyRunFlags sets the environment variables of the options given on the
+// command line, such as --since 2024-01-01 or --until=2024-03-31, so they
+// are parsed and validated with the rest of the configuration
+func applyRunFlags(args []string) error {
+	flags := map[string]string{"--since": "SINCE", "--until": "UNTIL"}
+
+	for i := 0; i < len(args); i++ {
+		name, value, hasValue := strings.Cut(args[i], "=")
+		env, ok := flags[name]
+		if !ok {
+			return fmt.Errorf("unknown option %q", args[i])
+		}
+		if !hasValue {
+			if i+1 == len(args) {
+				return fmt.Errorf("option %s needs a date", name)
+			}
+			i++
+			value = args[i]
+		}
+		os.Setenv(env, value)
+	}
+
+	return nil
+}
+
+// maxCacheAge is how long a cached API response is kept without being used
+const maxCacheAge = 30 * 24 * time.Hour
+
+// reportCache prints how many API responses the cache served and removes
+// the responses no run has used for a while
+func reportCache(cache *githubapi.CacheTransport) {
+	requests, notModified := cache.Stats()
+	fmt.Printf("💾 %d of %d API responses were unchanged and served from the cache\n", notModified, requests)
+
+	removed, err := cache.Prune(maxCacheAge)
+	if err != nil {
+		fmt.Printf("⚠️ Failed to prune the cache: %v\n", err)
+		return
+	}
+	if removed > 0 {
+		fmt.Printf("🧹 Removed %d cached responses unused for %d days\n", removed, int(maxCacheAge.Hours()/24))
+	}
+}
+
+func runFullRecreation(ctx context.Context, source githubapi.Source, cfg config.Config) error {
 	fmt.Println("🔄 Running in full recreation mode...")
 
-	// Fetch all pull requests
-	prs, err := githubapi.FetchAllPullRequests(ctx, client, owner, repo)
+	sc := newScorer(source, cfg)
+	window := karma.Window{Since: cfg.Since, Until: cfg.Until}
+	if !window.IsZero() {
+		sc.SetWindow(window)
+		fmt.Printf("🗓️ Scoring activity %s\n", window)
+	}
+
+	// Fetch the pull requests that can have activity in the window
+	prs, err := sc.PullRequests(ctx)
 	if err != nil {
-		fmt.Printf("❌ Error fetching pull requests: %v\n", err)
-		os.Exit(1)
+		return fmt.Errorf("error fetching pull requests: %w", err)
 	}
 
 	fmt.Printf("📋 Found %d pull requests\n", len(prs))
 
-	// Calculate karma for all reviewers
-	reviewerKarma := make(map[string]int)
-
+	// Continue an interrupted run where it stopped
+	cp, err := loadCheckpoint(cfg.CheckpointFile, runFingerprint(cfg))
+	if err != nil {
+		return err
+	}
+	done := cp.processed()
+	var pending []githubapi.PullRequest
 	for _, pr := range prs {
-		fmt.Printf("🔍 Processing PR #%d: %s\n", pr.GetNumber(), pr.GetTitle())
-
-		// Get reviews for this PR
-		reviews, err := githubapi.FetchPullRequestReviews(ctx, client, owner, repo, pr.GetNumber())
-		if err != nil {
-			fmt.Printf("⚠️ Error fetching reviews for PR #%d: %v\n", pr.GetNumber(), err)
-			continue
+		if !done[pr.Number] {
+			pending = append(pending, pr)
 		}
+	}
+	if len(done) > 0 {
+		fmt.Printf("⏯️ Resuming from %s, %d PRs were already scored\n", cfg.CheckpointFile, len(prs)-len(pending))
+	}
 
-		// Process reviews
-		for _, review := range reviews {
-			username := review.GetUser().GetLogin()
-			if karma.IsBot(username) {
-				continue
-			}
+	// Rank each leaderboard window as of the start of the run, so a resumed
+	// run adds to the same windows
+	windows := rollingWindows(cfg, cp.StartedAt)
+	for i := range windows {
+		if i == len(cp.WindowReviewers) {
+			cp.WindowReviewers = append(cp.WindowReviewers, nil)
+		}
+		if cp.WindowReviewers[i] == nil {
+			cp.WindowReviewers[i] = make(map[string]int)
+		}
+	}
 
-			// Award points for giving a review
-			reviewerKarma[username] += cfg.ReviewPoint
+	// Calculate karma for all reviewers
+	summary := newRunSummary()
+	reviewerKarma := cp.Reviewers
+	var failed []int
 
-			// Check for positive emojis in review body
-			if karma.HasPositiveEmoji(review.GetBody()) {
-				reviewerKarma[username] += cfg.PositiveEmojiPoint
-				fmt.Printf("  🎉 @%s gets +%d points for positive emoji\n", username, cfg.PositiveEmojiPoint)
-			}
+	err = sc.FetchEvents(ctx, pending, cfg.Concurrency, func(outcome scorer.PREvents) error {
+		fmt.Printf("🔍 Processing PR #%d: %s\n", outcome.PR.Number, outcome.PR.Title)
 
-			// Check for constructive comments
-			if karma.IsConstructiveComment(review.GetBody()) {
-				reviewerKarma[username] += cfg.ConstructiveCommentPoint
-				fmt.Printf("  💬 @%s gets +%d points for constructive comment\n", username, cfg.ConstructiveCommentPoint)
-			}
+		if stopsRun(ctx, outcome.Err) {
+			return fmt.Errorf("error fetching activity for PR #%d: %w", outcome.PR.Number, outcome.Err)
 		}
-
-		// Get comments for this PR
-		comments, err := githubapi.FetchPullRequestComments(ctx, client, owner, repo, pr.GetNumber())
-		if err != nil {
-			fmt.Printf("⚠️ Error fetching comments for PR #%d: %v\n", pr.GetNumber(), err)
-			continue
+		if outcome.Err != nil {
+			// Keep the PR out of the checkpoint, so the next run fetches it
+			// again instead of ranking part of its activity
+			fmt.Printf("⚠️ Error fetching activity for PR #%d: %v\n", outcome.PR.Number, outcome.Err)
+			failed = append(failed, outcome.PR.Number)
+			return nil
 		}
 
-		// Process comments
-		for _, comment := range comments {
-			username := comment.GetUser().GetLogin()
-			if karma.IsBot(username) {
-				continue
-			}
-
-			// Check for positive emojis in comment body
-			if karma.HasPositiveEmoji(comment.GetBody()) {
-				reviewerKarma[username] += cfg.PositiveEmojiPoint
-				fmt.Printf("  🎉 @%s gets +%d points for positive emoji in comment\n", username, cfg.PositiveEmojiPoint)
-			}
-
-			// Check for constructive comments
-			if karma.IsConstructiveComment(comment.GetBody()) {
-				reviewerKarma[username] += cfg.ConstructiveCommentPoint
-				fmt.Printf("  💬 @%s gets +%d points for constructive comment\n", username, cfg.ConstructiveCommentPoint)
-			}
+		awards := scorePREvents(sc, outcome, summary)
+		scorer.AddAwards(reviewerKarma, awards)
+		addWindowAwards(cp.WindowReviewers, windows, awards)
+		cp.ProcessedPRs = append(cp.ProcessedPRs, outcome.PR.Number)
+		if len(cp.ProcessedPRs)%checkpointInterval == 0 {
+			return cp.save(cfg.CheckpointFile)
+		}
+		return nil
+	})
+	if err != nil {
+		if saveErr := cp.save(cfg.CheckpointFile); saveErr != nil {
+			return fmt.Errorf("%w; %v", err, saveErr)
+		}
+		return fmt.Errorf("stopped after scoring %d of %d PRs, the next run resumes from %s: %w", len(cp.ProcessedPRs), len(prs), cfg.CheckpointFile, err)
+	}
+	if len(failed) > 0 {
+		if err := cp.save(cfg.CheckpointFile); err != nil {
+			return err
 		}
+		sort.Ints(failed)
+		return fmt.Errorf("failed to fetch the activity of %d PR(s) %v, the next run retries them and resumes from %s", len(failed), failed, cfg.CheckpointFile)
 	}
 
+	summary.print()
+
 	// Generate leaderboard
-	leaderboard := karma.GenerateLeaderboard(reviewerKarma)
+	leaderboards := newLeaderboards(cfg, windows, reviewerKarma, func(i int) map[string]int {
+		return cp.WindowReviewers[i]
+	})
+	if len(windows) == 0 {
+		leaderboards[0].Window = window
+	}
 
 	// Write leaderboard to file with custom scoring display
-	err = karma.WriteLeaderboardFileWithConfig(leaderboard, cfg.ReviewPoint, cfg.PositiveEmojiPoint, cfg.ConstructiveCommentPoint)
-	if err != nil {
-		fmt.Printf("❌ Error writing leaderboard file: %v\n", err)
-		os.Exit(1)
+	if err := karma.WriteLeaderboards(cfg.LeaderboardFile, leaderboards, sc.Engine()); err != nil {
+		return fmt.Errorf("error writing leaderboard file: %w", err)
 	}
+
+	return removeCheckpoint(cfg.CheckpointFile)
 }
 
-func runIncrementalUpdate(ctx context.Context, client *github.Client, owner, repo string, cfg config.Config) {
+func runIncrementalUpdate(ctx context.Context, source githubapi.Source, cfg config.Config) error {
 	fmt.Println("🔄 Running in incremental update mode...")
 
-	// Initialize storage
-	storage := storage.NewStorage(".karma-data.json")
+	// Load existing karma data once; all updates are written together at the end
+	store, err := openStorage(cfg)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
 
-	// Load existing karma data
-	karmaData, err := storage.GetKarmaData()
+	tx, err := store.Begin()
 	if err != nil {
-		fmt.Printf("⚠️ Error loading karma data: %v\n", err)
-		fmt.Println("🔄 Starting fresh...")
-		// Create empty data using the storage instance
-		karmaData, err = storage.Load()
-		if err != nil {
-			fmt.Printf("⚠️ Error creating empty karma data: %v\n", err)
-			os.Exit(1)
-		}
+		// Never start over silently, that would lose all history
+		return fmt.Errorf("error loading karma data: %w; fix or remove %s to start fresh", err, cfg.DataFile)
+	}
+	karmaData := tx.Data()
+	tx.SetRescorer(rescorer(newEngine(cfg)))
+
+	// Stored points must match the current scoring configuration
+	if err := checkScoringFingerprint(karmaData, cfg); err != nil {
+		return err
 	}
 
 	// Fetch all pull requests
-	prs, err := githubapi.FetchAllPullRequests(ctx, client, owner, repo)
+	prs, err := source.ListPullRequests(ctx)
 	if err != nil {
-		fmt.Printf("❌ Error fetching pull requests: %v\n", err)
-		os.Exit(1)
+		return fmt.Errorf("error fetching pull requests: %w", err)
 	}
 
 	fmt.Printf("📋 Found %d pull requests\n", len(prs))
 
-	// Get processed PRs
-	processedPRs, err := storage.GetProcessedPRs()
-	if err != nil {
-		fmt.Printf("⚠️ Error getting processed PRs: %v\n", err)
-		processedPRs = make(map[int]bool)
+	// Select new PRs and PRs with activity since they were last processed
+	var pending []githubapi.PullRequest
+	newPRsCount, updatedPRsCount, legacyPRsCount := 0, 0, 0
+	legacy := make(map[int]bool)
+	for _, pr := range prs {
+		processedAt, processed := karmaData.ProcessedPRs[pr.Number]
+		_, hasEvents := karmaData.Events[pr.Number]
+		legacy[pr.Number] = processed && !karmaData.HasPRRecord(pr.Number)
+
+		// PRs whose events aren't stored are fetched again, so they can be
+		// rescored later. PRs processed before per-PR points were stored
+		// have none to replace, so they wait for new activity.
+		if processed && !pr.UpdatedAt.After(processedAt) && (hasEvents || legacy[pr.Number]) {
+			continue // Skip PRs without new activity
+		}
+		if legacy[pr.Number] {
+			legacyPRsCount++
+		}
+
+		pending = append(pending, pr)
 	}
 
-	// Process only new PRs
-	newPRsCount := 0
-	for _, pr := range prs {
-		if processedPRs[pr.GetNumber()] {
-			continue // Skip already processed PRs
+	sc := newScorer(source, cfg)
+	summary := newRunSummary()
+	applied := 0
+	err = sc.FetchEvents(ctx, pending, cfg.Concurrency, func(outcome scorer.PREvents) error {
+		pr := outcome.PR
+		_, processed := karmaData.ProcessedPRs[pr.Number]
+		if processed {
+			fmt.Printf("♻️ Re-processing updated PR #%d: %s\n", pr.Number, pr.Title)
+		} else {
+			fmt.Printf("🆕 Processing new PR #%d: %s\n", pr.Number, pr.Title)
 		}
 
-		newPRsCount++
-		fmt.Printf("🆕 Processing new PR #%d: %s\n", pr.GetNumber(), pr.GetTitle())
+		if stopsRun(ctx, outcome.Err) {
+			return fmt.Errorf("error fetching activity for PR #%d: %w", pr.Number, outcome.Err)
+		}
+		if outcome.Err != nil {
+			// Leave the PR unprocessed so the next run retries it, without
+			// scoring the activity fetched so far
+			fmt.Printf("⚠️ Error fetching activity for PR #%d, retrying it next run: %v\n", pr.Number, outcome.Err)
+			return nil
+		}
+		if processed {
+			updatedPRsCount++
+		} else {
+			newPRsCount++
+		}
 
 		// Calculate karma for this PR
-		prKarma := calculatePRKarma(ctx, client, owner, repo, pr.GetNumber(), cfg)
+		awards := scorePREvents(sc, outcome, summary)
 
-		// Update storage
-		err = storage.UpdateKarma(pr.GetNumber(), prKarma)
+		// Record when the PR was last updated, so later activity triggers a re-score
+		processedAt := pr.UpdatedAt
+		if processedAt.IsZero() {
+			processedAt = time.Now()
+		}
+
+		// Append the awards to the ledger, reversing the PR's changed ones,
+		// and keep the raw events for rescoring
+		update := storage.PRUpdate{
+			PRNumber:    pr.Number,
+			Events:      storedEvents(outcome.Events),
+			Entries:     ledgerEntries(awards),
+			ProcessedAt: processedAt,
+		}
+		if legacy[pr.Number] {
+			update = legacyUpdate(karmaData, update, awards)
+		}
+		err := tx.Apply(update)
 		if err != nil {
-			fmt.Printf("⚠️ Error updating karma for PR #%d: %v\n", pr.GetNumber(), err)
-			continue
+			return fmt.Errorf("error updating karma for PR #%d: %w", pr.Number, err)
 		}
 
-		// Update in-memory data
-		for username, points := range prKarma {
-			karmaData.Reviewers[username] += points
+		// Save progress regularly, so an interrupted run keeps it
+		applied++
+		if applied%checkpointInterval == 0 {
+			if err := tx.Checkpoint(); err != nil {
+				return fmt.Errorf("error saving karma data: %w", err)
+			}
 		}
+		return nil
+	})
+	if err != nil {
+		// The PRs scored so far are kept, the next run continues with the rest
+		if commitErr := tx.Commit(); commitErr != nil {
+			return fmt.Errorf("%w; error saving karma data: %v", err, commitErr)
+		}
+		return fmt.Errorf("stopped after scoring %d of %d PRs, the next run continues from there: %w", applied, len(pending), err)
 	}
 
-	if newPRsCount == 0 {
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error saving karma data: %w", err)
+	}
+
+	if newPRsCount == 0 && updatedPRsCount == 0 {
 		fmt.Println("✅ No new PRs to process")
 	} else {
-		fmt.Printf("✅ Processed %d new PRs\n", newPRsCount)
+		fmt.Printf("✅ Processed %d new and %d updated PRs\n", newPRsCount, updatedPRsCount)
+	}
+	if legacyPRsCount > 0 {
+		fmt.Printf("ℹ️ Scored only the new activity of %d PR(s) processed by an older version, whose earlier points are in the baseline\n", legacyPRsCount)
 	}
+	summary.print()
 
 	// Generate leaderboard from updated data
-	leaderboard := karma.GenerateLeaderboard(karmaData.Reviewers)
+	return writeLeaderboard(cfg, karmaData, sc.Engine())
+}
 
-	// Write leaderboard to file with custom scoring display
-	err = karma.WriteLeaderboardFileWithConfig(leaderboard, cfg.ReviewPoint, cfg.PositiveEmojiPoint, cfg.ConstructiveCommentPoint)
-	if err != nil {
-		fmt.Printf("❌ Error writing leaderboard file: %v\n", err)
-		os.Exit(1)
+// newScorer builds a scorer for the source using the configured rules
+func newScorer(source githubapi.Source, cfg config.Config) *scorer.Scorer {
+	sc := scorer.NewScorer(source, newEngine(cfg))
+	if len(cfg.ReactionPoints) > 0 {
+		sc.EnableReactions()
 	}
+	return sc
 }
 
-func calculatePRKarma(ctx context.Context, client *github.Client, owner, repo string, prNumber int, cfg config.Config) map[string]int {
-	reviewerKarma := make(map[string]int)
+// newEngine builds the scoring engine from the configured point values
+func newEngine(cfg config.Config) *karma.Engine {
+	engine := karma.NewEngine(
+		karma.ReviewStateRule{
+			Approved:         cfg.ApprovedReviewPoint,
+			ChangesRequested: cfg.ChangesRequestedReviewPoint,
+			Commented:        cfg.CommentedReviewPoint,
+			Dismissed:        cfg.DismissedReviewPoint,
+		},
+		karma.PositiveEmojiRule{Points: cfg.PositiveEmojiPoint, Emojis: cfg.PositiveEmojis},
+		karma.ConstructiveCommentRule{Points: cfg.ConstructiveCommentPoint},
+		karma.IssueCommentRule{Points: cfg.IssueCommentPoint},
+		karma.ReactionRule{Points: cfg.ReactionPoints, MaxPerPR: cfg.ReactionCap},
+	)
 
-	// Get reviews for this PR
-	reviews, err := githubapi.FetchPullRequestReviews(ctx, client, owner, repo, prNumber)
-	if err != nil {
-		fmt.Printf("⚠️ Error fetching reviews for PR #%d: %v\n", prNumber, err)
-		return reviewerKarma
+	if len(cfg.BotPatterns) > 0 {
+		engine.SetBotPatterns(cfg.BotPatterns)
 	}
 
-	// Process reviews
-	for _, review := range reviews {
-		username := review.GetUser().GetLogin()
-		if karma.IsBot(username) {
-			continue
-		}
+	if len(cfg.ExcludedUsers) > 0 {
+		engine.AddFilters(karma.ExcludedUserFilter{Users: cfg.ExcludedUsers})
+	}
 
-		// Award points for giving a review
-		reviewerKarma[username] += cfg.ReviewPoint
+	if cfg.ExcludeSelfActivity {
+		engine.AddFilters(karma.SelfActivityFilter{Percent: 0})
+	} else if cfg.SelfActivityPercent != 100 {
+		engine.AddFilters(karma.SelfActivityFilter{Percent: cfg.SelfActivityPercent})
+	}
 
-		// Check for positive emojis in review body
-		if karma.HasPositiveEmoji(review.GetBody()) {
-			reviewerKarma[username] += cfg.PositiveEmojiPoint
-			fmt.Printf("  🎉 @%s gets +%d points for positive emoji\n", username, cfg.PositiveEmojiPoint)
-		}
+	if cfg.RevokeDismissedReviews {
+		engine.AddFilters(karma.DismissedReviewFilter{})
+	}
 
-		// Check for constructive comments
-		if karma.IsConstructiveComment(review.GetBody()) {
-			reviewerKarma[username] += cfg.ConstructiveCommentPoint
-			fmt.Printf("  💬 @%s gets +%d points for constructive comment\n", username, cfg.ConstructiveCommentPoint)
-		}
+	return engine
+}
+
+// runSummary counts events excluded from scoring during a run
+type runSummary struct {
+	excluded map[string]int
+}
+
+func newRunSummary() *runSummary {
+	return &runSummary{excluded: make(map[string]int)}
+}
+
+// print reports the excluded events, if any
+func (s *runSummary) print() {
+	total := 0
+	var parts []string
+	for _, name := range sortedNames(s.excluded) {
+		total += s.excluded[name]
+		parts = append(parts, fmt.Sprintf("%s=%d", name, s.excluded[name]))
 	}
+	if total > 0 {
+		fmt.Printf("🚫 Excluded %d event(s): %s\n", total, strings.Join(parts, ", "))
+	}
+}
 
-	// Get comments for this PR
-	comments, err := githubapi.FetchPullRequestComments(ctx, client, owner, repo, prNumber)
-	if err != nil {
-		fmt.Printf("⚠️ Error fetching comments for PR #%d: %v\n", prNumber, err)
-		return reviewerKarma
+// sortedNames returns the keys of a map in sorted order
+func sortedNames(m map[string]int) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// calculatePRKarma scores a single pull request and logs the bonus awards.
+// On a fetch error the events and awards for the activity fetched so far are
+// returned along with the error.
+func calculatePRKarma(ctx context.Context, sc *scorer.Scorer, pr githubapi.PullRequest, summary *runSummary) ([]karma.Event, []karma.Award, error) {
+	events, err := sc.Events(ctx, pr)
+	awards := scorePREvents(sc, scorer.PREvents{PR: pr, Events: events, Err: err}, summary)
+	return events, awards, err
+}
+
+// scorePREvents scores the events collected for a pull request and logs the
+// bonus awards and any fetch error
+func scorePREvents(sc *scorer.Scorer, outcome scorer.PREvents, summary *runSummary) []karma.Award {
+	if outcome.Err != nil {
+		fmt.Printf("⚠️ Error fetching activity for PR #%d: %v\n", outcome.PR.Number, outcome.Err)
 	}
 
-	// Process comments
-	for _, comment := range comments {
-		username := comment.GetUser().GetLogin()
-		if karma.IsBot(username) {
-			continue
+	result := sc.Engine().EvaluateAll(outcome.Events)
+
+	for _, award := range result.Awards {
+		if award.Rule != "review" {
+			fmt.Printf("  %s @%s gets +%d points for %s\n", awardIcon(award.Rule), award.User, award.Points, award.Reason)
 		}
+	}
+
+	for name, count := range result.Excluded {
+		summary.excluded[name] += count
+	}
+
+	return result.Awards
+}
 
-		// Check for positive emojis in comment body
-		if karma.HasPositiveEmoji(comment.GetBody()) {
-			reviewerKarma[username] += cfg.PositiveEmojiPoint
-			fmt.Printf("  🎉 @%s gets +%d points for positive emoji in comment\n", username, cfg.PositiveEmojiPoint)
+// openStorage opens the karma data with the configured storage backend
+func openStorage(cfg config.Config) (*storage.Storage, error) {
+	switch cfg.StorageBackend {
+	case config.StorageSQLite:
+		backend, err := storage.NewSQLiteBackend(cfg.DataFile)
+		if err != nil {
+			return nil, fmt.Errorf("error opening karma database %s: %w", cfg.DataFile, err)
 		}
+		return storage.NewStorageWithBackend(backend), nil
+	case config.StorageGit:
+		return storage.NewStorageWithBackend(storage.NewGitBackend(storage.GitOptions{
+			RepoDir: ".",
+			Remote:  cfg.DataRemote,
+			Branch:  cfg.DataBranch,
+			Path:    filepath.ToSlash(filepath.Clean(cfg.DataFile)),
+		})), nil
+	}
+	return storage.NewStorage(cfg.DataFile), nil
+}
 
-		// Check for constructive comments
-		if karma.IsConstructiveComment(comment.GetBody()) {
-			reviewerKarma[username] += cfg.ConstructiveCommentPoint
-			fmt.Printf("  💬 @%s gets +%d points for constructive comment\n", username, cfg.ConstructiveCommentPoint)
+// ledgerEntries converts awards into storage ledger entries
+func ledgerEntries(awards []karma.Award) []storage.LedgerEntry {
+	entries := make([]storage.LedgerEntry, 0, len(awards))
+	for _, award := range awards {
+		entries = append(entries, storage.LedgerEntry{
+			PRNumber:  award.PRNumber,
+			EventKind: string(award.EventKind),
+			EventID:   award.EventID,
+			User:      award.User,
+			Rule:      award.Rule,
+			Points:    award.Points,
+			Timestamp: award.CreatedAt,
+		})
+	}
+	return entries
+}
+
+// legacyUpdate limits the update of a PR processed before per-PR points were
+// stored to the awards for activity since it was last processed. Its earlier
+// points are only counted in the baseline, so scoring that activity again
+// would count it twice. Awards recorded since are kept, and the events are
+// not stored, since the PR can't be rescored as a whole.
+func legacyUpdate(data *storage.KarmaData, update storage.PRUpdate, awards []karma.Award) storage.PRUpdate {
+	processedAt := data.ProcessedPRs[update.PRNumber]
+	entries := data.PREntries(update.PRNumber)
+	for _, award := range awards {
+		if award.CreatedAt.After(processedAt) {
+			entries = append(entries, ledgerEntries([]karma.Award{award})...)
 		}
 	}
+	update.Events = nil
+	update.Entries = entries
+	return update
+}
 
-	return reviewerKarma
+// awardIcon returns the log icon used for a rule's awards
+func awardIcon(rule string) string {
+	switch rule {
+	case "positive_emoji":
+		return "🎉"
+	case "constructive_comment", "issue_comment":
+		return "💬"
+	case "reaction":
+		return "👍"
+	default:
+		return "⭐"
+	}
 }
 
 func getUpdateModeString(incremental bool) string {
@@ -0,0 +1,648 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v62/github"
+	"github.com/master-wayne7/reviewer-karma-action/internal/atomicfile"
+	"github.com/master-wayne7/reviewer-karma-action/internal/config"
+	"github.com/master-wayne7/reviewer-karma-action/internal/githubapi"
+	"github.com/master-wayne7/reviewer-karma-action/internal/storage"
+)
+
+func testConfig(t *testing.T) config.Config {
+	t.Helper()
+	dir := t.TempDir()
+	cfg, err := config.LoadFile(filepath.Join("testdata", "config.yml"))
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	cfg.LeaderboardFile = filepath.Join(dir, "REVIEWERS.md")
+	cfg.DataFile = filepath.Join(dir, ".karma-data.json")
+	cfg.CheckpointFile = filepath.Join(dir, ".karma-checkpoint.json")
+	return cfg
+}
+
+func loadFixture(t *testing.T) *githubapi.FakeSource {
+	t.Helper()
+	source, err := githubapi.LoadFixture(filepath.Join("testdata", "repo.json"))
+	if err != nil {
+		t.Fatalf("Failed to load fixture: %v", err)
+	}
+	return source
+}
+
+func readLeaderboard(t *testing.T, cfg config.Config) string {
+	t.Helper()
+	content, err := os.ReadFile(cfg.LeaderboardFile)
+	if err != nil {
+		t.Fatalf("Failed to read leaderboard: %v", err)
+	}
+	return string(content)
+}
+
+func TestRunFullRecreation(t *testing.T) {
+	cfg := testConfig(t)
+
+	if err := runFullRecreation(context.Background(), loadFixture(t), cfg); err != nil {
+		t.Fatalf("Full recreation failed: %v", err)
+	}
+
+	content := readLeaderboard(t, cfg)
+
+	// alice: changes requested (2) + constructive (1)
+	// bob: approved (3) + emoji (2)
+	// carol: commented (1) + emoji in comment (2)
+	// dave: conversation comment (1) + emoji in comment (2)
+	// alice's comment on her own PR #1 is excluded
+	for _, row := range []string{"| 1 | 🥇 @bob | 5 |", "@alice | 3 |", "@carol | 3 |", "@dave | 3 |"} {
+		if !strings.Contains(content, row) {
+			t.Errorf("Expected leaderboard to contain %q, got:\n%s", row, content)
+		}
+	}
+
+	if strings.Contains(content, "dependabot") {
+		t.Error("Bots should not appear on the leaderboard")
+	}
+}
+
+func TestRunFullRecreationWindow(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Since = time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)
+	cfg.Until = time.Date(2024, 1, 5, 13, 0, 0, 0, time.UTC)
+
+	// PR #1 was last updated before the window, so it isn't fetched
+	source := loadFixture(t)
+	source.Errors[1] = errors.New("unexpected API call")
+	if err := runFullRecreation(context.Background(), source, cfg); err != nil {
+		t.Fatalf("Full recreation failed: %v", err)
+	}
+
+	// Only alice's review of PR #2 falls in the window
+	content := readLeaderboard(t, cfg)
+	if !strings.Contains(content, "| 1 | 🥇 @alice | 3 |") {
+		t.Errorf("Expected alice to lead with 3 points, got:\n%s", content)
+	}
+	for _, user := range []string{"@bob", "@carol", "@dave"} {
+		if strings.Contains(content, user) {
+			t.Errorf("Expected %s to have no activity in the window, got:\n%s", user, content)
+		}
+	}
+	if !strings.Contains(content, "Activity since 2024-01-05 and before 2024-01-05 13:00 UTC.") {
+		t.Errorf("Expected the leaderboard to describe the window, got:\n%s", content)
+	}
+}
+
+func TestRunLeaderboardWindows(t *testing.T) {
+	cfg := testConfig(t)
+
+	// A window reaching back to the day PR #2 was opened
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	days := int(today.Sub(time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)).Hours()/24) + 1
+	cfg.LeaderboardWindows = []config.LeaderboardWindow{{Title: "Lexer Fix", Days: days}, {Days: 1}, {Title: "All Time"}}
+
+	expected := []string{
+		"## Lexer Fix\n\nActivity since 2024-01-05.\n\n",
+		"| 1 | 🥇 @alice | 3 |\n| 2 | 🥈 @carol | 3 |\n\n",
+		"## Today\n\nActivity since " + today.Format(time.DateOnly) + ".\n\nNo activity in this period.\n\n",
+		"## All Time\n\n",
+		"| 1 | 🥇 @bob | 5 |\n| 2 | 🥈 @alice | 3 |\n| 3 | 🥉 @carol | 3 |\n| 4 | @dave | 3 |\n\n",
+	}
+
+	// Full recreation totals the windows while scoring, incremental updates
+	// from the ledger
+	runs := []struct {
+		name string
+		run  func(context.Context, githubapi.Source, config.Config) error
+	}{
+		{"full recreation", runFullRecreation},
+		{"incremental update", runIncrementalUpdate},
+	}
+
+	for _, run := range runs {
+		if err := run.run(context.Background(), loadFixture(t), cfg); err != nil {
+			t.Fatalf("%s failed: %v", run.name, err)
+		}
+
+		content := readLeaderboard(t, cfg)
+		last := -1
+		for _, section := range expected {
+			index := strings.Index(content, section)
+			if index <= last {
+				t.Errorf("Expected the %s leaderboard to contain %q after the previous section, got:\n%s", run.name, section, content)
+				break
+			}
+			last = index
+		}
+	}
+}
+
+func TestRunLeaderboardWindowsDatesReactions(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.ReactionPoints = map[string]int{"rocket": 2}
+	cfg.LeaderboardWindows = []config.LeaderboardWindow{{Days: 1}}
+
+	// A reaction added today to PR #1, opened in 2024
+	source := loadFixture(t)
+	source.PullRequestReactions[1] = []githubapi.Reaction{{ID: 9, User: "erin", Content: "rocket", CreatedAt: time.Now()}}
+
+	if err := runFullRecreation(context.Background(), source, cfg); err != nil {
+		t.Fatalf("Full recreation failed: %v", err)
+	}
+	if content := readLeaderboard(t, cfg); !strings.Contains(content, "| 1 | 🥇 @erin | 2 |") {
+		t.Errorf("Expected today's reaction to rank erin today, got:\n%s", content)
+	}
+}
+
+func TestApplyRunFlags(t *testing.T) {
+	tests := []struct {
+		args  []string
+		since string
+		until string
+		valid bool
+	}{
+		{[]string{"--since", "2024-01-01", "--until=2024-03-31"}, "2024-01-01", "2024-03-31", true},
+		{[]string{"--since=2024-01-01"}, "2024-01-01", "", true},
+		{[]string{"--since"}, "", "", false},
+		{[]string{"--verbose"}, "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Setenv("SINCE", "")
+		t.Setenv("UNTIL", "")
+		err := applyRunFlags(tt.args)
+		if (err == nil) != tt.valid {
+			t.Errorf("Expected %v valid=%v, got: %v", tt.args, tt.valid, err)
+			continue
+		}
+		if os.Getenv("SINCE") != tt.since || os.Getenv("UNTIL") != tt.until {
+			t.Errorf("Expected %v to set SINCE=%q and UNTIL=%q, got %q and %q", tt.args, tt.since, tt.until, os.Getenv("SINCE"), os.Getenv("UNTIL"))
+		}
+	}
+}
+
+func TestRunIncrementalUpdate(t *testing.T) {
+	cfg := testConfig(t)
+	source := loadFixture(t)
+	ctx := context.Background()
+
+	if err := runIncrementalUpdate(ctx, source, cfg); err != nil {
+		t.Fatalf("Incremental update failed: %v", err)
+	}
+
+	// A second run over the same data must not double count
+	if err := runIncrementalUpdate(ctx, source, cfg); err != nil {
+		t.Fatalf("Second incremental update failed: %v", err)
+	}
+
+	content := readLeaderboard(t, cfg)
+	if !strings.Contains(content, "| 1 | 🥇 @bob | 5 |") {
+		t.Errorf("Expected bob to lead with 5 points, got:\n%s", content)
+	}
+}
+
+func TestRunIncrementalUpdateReprocessesUpdatedPRs(t *testing.T) {
+	cfg := testConfig(t)
+	source := loadFixture(t)
+	ctx := context.Background()
+
+	if err := runIncrementalUpdate(ctx, source, cfg); err != nil {
+		t.Fatalf("Incremental update failed: %v", err)
+	}
+
+	// New activity on the already processed PR #1
+	source.Reviews[1] = append(source.Reviews[1], githubapi.Review{
+		ID: 103, User: "erin", State: "APPROVED", SubmittedAt: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+	})
+	source.PullRequests[0].UpdatedAt = time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	if err := runIncrementalUpdate(ctx, source, cfg); err != nil {
+		t.Fatalf("Second incremental update failed: %v", err)
+	}
+
+	content := readLeaderboard(t, cfg)
+
+	// bob's points from PR #1 must not be counted twice
+	for _, row := range []string{"| 1 | 🥇 @bob | 5 |", "@erin | 3 |"} {
+		if !strings.Contains(content, row) {
+			t.Errorf("Expected leaderboard to contain %q, got:\n%s", row, content)
+		}
+	}
+}
+
+// issueCommentFailingSource fails to list the conversation comments of one
+// pull request, after its reviews and review comments were fetched
+type issueCommentFailingSource struct {
+	*githubapi.FakeSource
+	prNumber int
+}
+
+func (s issueCommentFailingSource) ListIssueComments(ctx context.Context, prNumber int) ([]githubapi.Comment, error) {
+	if prNumber == s.prNumber {
+		return nil, errors.New("boom")
+	}
+	return s.FakeSource.ListIssueComments(ctx, prNumber)
+}
+
+func TestRunIncrementalUpdateSkipsPartiallyFetchedPRs(t *testing.T) {
+	cfg := testConfig(t)
+	ctx := context.Background()
+	source := loadFixture(t)
+
+	if err := runIncrementalUpdate(ctx, issueCommentFailingSource{source, 2}, cfg); err != nil {
+		t.Fatalf("Incremental update failed: %v", err)
+	}
+
+	data, err := storage.NewStorage(cfg.DataFile).Load()
+	if err != nil {
+		t.Fatalf("Failed to load karma data: %v", err)
+	}
+	if _, ok := data.ProcessedPRs[2]; ok {
+		t.Error("Expected PR #2 to be left for the next run")
+	}
+	if entries := data.PREntries(2); len(entries) != 0 {
+		t.Errorf("Expected no points for the partially fetched PR #2, got %+v", entries)
+	}
+	if data.Reviewers["carol"] != 0 {
+		t.Errorf("Expected carol's comment on PR #2 to be unscored, got %d points", data.Reviewers["carol"])
+	}
+
+	// The next run scores PR #2 exactly once
+	if err := runIncrementalUpdate(ctx, source, cfg); err != nil {
+		t.Fatalf("Second incremental update failed: %v", err)
+	}
+	if content := readLeaderboard(t, cfg); !strings.Contains(content, "@carol | 3 |") {
+		t.Errorf("Expected PR #2 to be scored by the next run, got:\n%s", content)
+	}
+}
+
+func TestRunIncrementalUpdateLegacyData(t *testing.T) {
+	cfg := testConfig(t)
+	source := loadFixture(t)
+	ctx := context.Background()
+
+	// Written before the ledger: the points of both PRs are only in the
+	// totals, which become the baseline
+	legacy := `{
+  "reviewers": {"bob": 5, "dave": 3, "alice": 3, "carol": 3},
+  "last_updated": "2024-01-06T10:00:00Z",
+  "processed_prs": {"1": "2024-01-03T10:00:00Z", "2": "2024-01-06T10:00:00Z"}
+}`
+	if err := os.WriteFile(cfg.DataFile, []byte(legacy), 0644); err != nil {
+		t.Fatalf("Failed to write legacy data: %v", err)
+	}
+
+	// Neither PR has new activity, so neither is fetched
+	source.Errors[1] = errors.New("unexpected API call")
+	source.Errors[2] = errors.New("unexpected API call")
+	if err := runIncrementalUpdate(ctx, source, cfg); err != nil {
+		t.Fatalf("Incremental update failed: %v", err)
+	}
+	data, err := storage.NewStorage(cfg.DataFile).Load()
+	if err != nil {
+		t.Fatalf("Failed to load karma data: %v", err)
+	}
+	if !data.LegacyPRs[1] || !data.LegacyPRs[2] {
+		t.Errorf("Expected both PRs to be legacy, got %v", data.LegacyPRs)
+	}
+	if data.Reviewers["alice"] != 3 || data.Reviewers["carol"] != 3 || data.Reviewers["bob"] != 5 {
+		t.Errorf("Expected the totals to be unchanged, got %v", data.Reviewers)
+	}
+
+	// New activity on PR #1 is scored without counting the old activity twice
+	delete(source.Errors, 1)
+	delete(source.Errors, 2)
+	source.Reviews[1] = append(source.Reviews[1], githubapi.Review{
+		ID: 103, User: "erin", State: "APPROVED", SubmittedAt: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+	})
+	source.PullRequests[0].UpdatedAt = time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	if err := runIncrementalUpdate(ctx, source, cfg); err != nil {
+		t.Fatalf("Second incremental update failed: %v", err)
+	}
+
+	content := readLeaderboard(t, cfg)
+	for _, row := range []string{"| 1 | 🥇 @bob | 5 |", "@erin | 3 |", "@dave | 3 |"} {
+		if !strings.Contains(content, row) {
+			t.Errorf("Expected leaderboard to contain %q, got:\n%s", row, content)
+		}
+	}
+}
+
+func TestRunIncrementalUpdateWritesLedger(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.IncrementalUpdate = true
+	source := loadFixture(t)
+
+	if err := runIncrementalUpdate(context.Background(), source, cfg); err != nil {
+		t.Fatalf("Incremental update failed: %v", err)
+	}
+
+	// All PRs are written at once, so there is no earlier generation to back up
+	if _, err := os.Stat(atomicfile.BackupPath(cfg.DataFile)); !os.IsNotExist(err) {
+		t.Errorf("Expected karma data to be written once, but a backup exists: %v", err)
+	}
+
+	data, err := storage.NewStorage(cfg.DataFile).Load()
+	if err != nil {
+		t.Fatalf("Failed to load karma data: %v", err)
+	}
+
+	for _, entry := range data.Ledger {
+		if entry.EventID == 0 || entry.Rule == "" || entry.EventKind == "" {
+			t.Errorf("Expected ledger entry to reference its event, got %+v", entry)
+		}
+	}
+
+	totals := data.Totals()
+	for username, points := range data.Reviewers {
+		if totals[username] != points {
+			t.Errorf("Expected %s's stored total %d to match the ledger, got %d", username, points, totals[username])
+		}
+	}
+	if data.Reviewers["bob"] != 5 {
+		t.Errorf("Expected bob to have 5 points, got %d", data.Reviewers["bob"])
+	}
+}
+
+func TestRunIncrementalUpdateSQLite(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.StorageBackend = config.StorageSQLite
+	cfg.DataFile = filepath.Join(t.TempDir(), ".karma-data.db")
+	source := loadFixture(t)
+	ctx := context.Background()
+
+	if err := runIncrementalUpdate(ctx, source, cfg); err != nil {
+		t.Fatalf("Incremental update failed: %v", err)
+	}
+
+	// A second run over the same data must not double count
+	if err := runIncrementalUpdate(ctx, source, cfg); err != nil {
+		t.Fatalf("Second incremental update failed: %v", err)
+	}
+
+	content := readLeaderboard(t, cfg)
+	if !strings.Contains(content, "| 1 | 🥇 @bob | 5 |") {
+		t.Errorf("Expected bob to lead with 5 points, got:\n%s", content)
+	}
+}
+
+func TestRunIncrementalUpdateGitBranch(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	cfg := testConfig(t)
+	cfg.StorageBackend = config.StorageGit
+	cfg.DataFile = ".karma-data.json"
+	cfg.DataRemote = ""
+	source := loadFixture(t)
+	ctx := context.Background()
+
+	repo := t.TempDir()
+	if out, err := exec.Command("git", "init", "--quiet", repo).CombinedOutput(); err != nil {
+		t.Fatalf("git init failed: %v\n%s", err, out)
+	}
+	t.Chdir(repo)
+
+	if err := runIncrementalUpdate(ctx, source, cfg); err != nil {
+		t.Fatalf("Incremental update failed: %v", err)
+	}
+	if err := runIncrementalUpdate(ctx, source, cfg); err != nil {
+		t.Fatalf("Second incremental update failed: %v", err)
+	}
+
+	content := readLeaderboard(t, cfg)
+	if !strings.Contains(content, "| 1 | 🥇 @bob | 5 |") {
+		t.Errorf("Expected bob to lead with 5 points, got:\n%s", content)
+	}
+
+	// The data is only on the branch, not in the working tree
+	if _, err := os.Stat(cfg.DataFile); !os.IsNotExist(err) {
+		t.Errorf("Expected no data file in the working tree, got %v", err)
+	}
+	if out, err := exec.Command("git", "cat-file", "-e", "karma-data:.karma-data.json").CombinedOutput(); err != nil {
+		t.Errorf("Expected the data file on the karma-data branch: %v\n%s", err, out)
+	}
+}
+
+func TestRunIncrementalUpdateKeepsCorruptData(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.IncrementalUpdate = true
+	corrupt := []byte(`{"reviewers": {"alice": 4`)
+	if err := os.WriteFile(cfg.DataFile, corrupt, 0644); err != nil {
+		t.Fatalf("Failed to write data: %v", err)
+	}
+
+	if err := runIncrementalUpdate(context.Background(), loadFixture(t), cfg); err == nil {
+		t.Fatal("Expected an error for corrupt karma data")
+	}
+
+	// The corrupt file is left for inspection instead of being reset
+	content, err := os.ReadFile(cfg.DataFile)
+	if err != nil {
+		t.Fatalf("Failed to read data: %v", err)
+	}
+	if string(content) != string(corrupt) {
+		t.Errorf("Expected karma data to be left untouched, got:\n%s", content)
+	}
+}
+
+func TestRunEventUpdatePatchesStoredEvents(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.IncrementalUpdate = true
+	ctx := context.Background()
+
+	source := loadFixture(t)
+	if err := runIncrementalUpdate(ctx, source, cfg); err != nil {
+		t.Fatalf("Incremental update failed: %v", err)
+	}
+
+	// The new comment is applied from the payload, without fetching PR #1
+	source.Errors[1] = errors.New("unexpected API call")
+	trigger := &githubapi.Trigger{
+		Event:        "issue_comment",
+		Action:       "created",
+		PullRequest:  source.PullRequests[0],
+		IssueComment: &githubapi.Comment{ID: 3003, User: "erin", Body: "Looks good 🎉", CreatedAt: time.Date(2024, 1, 4, 10, 0, 0, 0, time.UTC)},
+	}
+	if err := runEventUpdate(ctx, source, cfg, trigger); err != nil {
+		t.Fatalf("Event update failed: %v", err)
+	}
+
+	content := readLeaderboard(t, cfg)
+	for _, row := range []string{"| 1 | 🥇 @bob | 5 |", "@erin | 3 |", "@dave | 3 |"} {
+		if !strings.Contains(content, row) {
+			t.Errorf("Expected leaderboard to contain %q, got:\n%s", row, content)
+		}
+	}
+}
+
+func TestRunEventUpdateFetchesOnlyTriggeringPR(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.IncrementalUpdate = true
+
+	source := loadFixture(t)
+	trigger := &githubapi.Trigger{Event: "pull_request", Action: "opened", PullRequest: source.PullRequests[1]}
+	if err := runEventUpdate(context.Background(), source, cfg, trigger); err != nil {
+		t.Fatalf("Event update failed: %v", err)
+	}
+
+	data, err := storage.NewStorage(cfg.DataFile).Load()
+	if err != nil {
+		t.Fatalf("Failed to load karma data: %v", err)
+	}
+	if _, ok := data.ProcessedPRs[1]; ok {
+		t.Error("Expected PR #1 not to be processed")
+	}
+	if data.Reviewers["alice"] != 3 || data.Reviewers["carol"] != 3 {
+		t.Errorf("Expected alice and carol to have 3 points from PR #2, got %v", data.Reviewers)
+	}
+}
+
+func TestRunFullRecreationConcurrency(t *testing.T) {
+	var leaderboards []string
+	for _, concurrency := range []int{1, 8} {
+		cfg := testConfig(t)
+		cfg.Concurrency = concurrency
+		if err := runFullRecreation(context.Background(), loadFixture(t), cfg); err != nil {
+			t.Fatalf("Full recreation with concurrency %d failed: %v", concurrency, err)
+		}
+		// The generation time may differ between runs
+		content, _, _ := strings.Cut(readLeaderboard(t, cfg), "*Last updated")
+		leaderboards = append(leaderboards, content)
+	}
+
+	if leaderboards[0] != leaderboards[1] {
+		t.Errorf("Expected the same leaderboard regardless of concurrency, got:\n%s\nand:\n%s", leaderboards[0], leaderboards[1])
+	}
+}
+
+func TestRunFullRecreationResumesAfterRateLimit(t *testing.T) {
+	cfg := testConfig(t)
+	ctx := context.Background()
+
+	source := loadFixture(t)
+	source.Errors[2] = &github.RateLimitError{Message: "API rate limit exceeded"}
+	if err := runFullRecreation(ctx, source, cfg); err == nil {
+		t.Fatal("Expected the rate limit to stop the run")
+	}
+	if _, err := os.Stat(cfg.LeaderboardFile); !os.IsNotExist(err) {
+		t.Errorf("Expected no leaderboard from a stopped run, got: %v", err)
+	}
+
+	cp, err := loadCheckpoint(cfg.CheckpointFile, cfg.ScoringFingerprint())
+	if err != nil {
+		t.Fatalf("Failed to load checkpoint: %v", err)
+	}
+	if len(cp.ProcessedPRs) != 1 || cp.ProcessedPRs[0] != 1 || cp.Reviewers["bob"] != 5 {
+		t.Errorf("Expected the checkpoint to hold PR #1, got %+v", cp)
+	}
+
+	// The next run only fetches PR #2
+	source.Errors[2] = nil
+	source.Errors[1] = errors.New("unexpected API call")
+	if err := runFullRecreation(ctx, source, cfg); err != nil {
+		t.Fatalf("Resumed full recreation failed: %v", err)
+	}
+
+	content := readLeaderboard(t, cfg)
+	for _, row := range []string{"| 1 | 🥇 @bob | 5 |", "@alice | 3 |", "@carol | 3 |", "@dave | 3 |"} {
+		if !strings.Contains(content, row) {
+			t.Errorf("Expected leaderboard to contain %q, got:\n%s", row, content)
+		}
+	}
+	if _, err := os.Stat(cfg.CheckpointFile); !os.IsNotExist(err) {
+		t.Errorf("Expected the checkpoint to be removed, got: %v", err)
+	}
+}
+
+func TestRunFullRecreationRetriesFailedPRs(t *testing.T) {
+	cfg := testConfig(t)
+	ctx := context.Background()
+
+	source := loadFixture(t)
+	source.Errors[2] = errors.New("502 Bad Gateway")
+	if err := runFullRecreation(ctx, source, cfg); err == nil {
+		t.Fatal("Expected the failed PR to fail the run")
+	}
+	if _, err := os.Stat(cfg.LeaderboardFile); !os.IsNotExist(err) {
+		t.Errorf("Expected no leaderboard missing PR #2, got: %v", err)
+	}
+
+	cp, err := loadCheckpoint(cfg.CheckpointFile, runFingerprint(cfg))
+	if err != nil {
+		t.Fatalf("Failed to load checkpoint: %v", err)
+	}
+	if len(cp.ProcessedPRs) != 1 || cp.ProcessedPRs[0] != 1 {
+		t.Errorf("Expected the checkpoint to hold only PR #1, got %v", cp.ProcessedPRs)
+	}
+
+	// The next run only fetches the failed PR
+	source.Errors[2] = nil
+	source.Errors[1] = errors.New("unexpected API call")
+	if err := runFullRecreation(ctx, source, cfg); err != nil {
+		t.Fatalf("Retried full recreation failed: %v", err)
+	}
+	if content := readLeaderboard(t, cfg); !strings.Contains(content, "@carol | 3 |") {
+		t.Errorf("Expected PR #2 to be scored by the next run, got:\n%s", content)
+	}
+}
+
+func TestRunFullRecreationIgnoresStaleCheckpoint(t *testing.T) {
+	cfg := testConfig(t)
+
+	stale := &checkpoint{
+		StartedAt:          time.Now().Add(-2 * maxCheckpointAge),
+		ScoringFingerprint: cfg.ScoringFingerprint(),
+		Reviewers:          map[string]int{"mallory": 100},
+		ProcessedPRs:       []int{1, 2},
+	}
+	if err := stale.save(cfg.CheckpointFile); err != nil {
+		t.Fatalf("Failed to save checkpoint: %v", err)
+	}
+
+	if err := runFullRecreation(context.Background(), loadFixture(t), cfg); err != nil {
+		t.Fatalf("Full recreation failed: %v", err)
+	}
+	if content := readLeaderboard(t, cfg); strings.Contains(content, "mallory") {
+		t.Errorf("Expected the stale checkpoint to be ignored, got:\n%s", content)
+	}
+}
+
+func TestRunIncrementalUpdateKeepsProgressOnRateLimit(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.IncrementalUpdate = true
+	ctx := context.Background()
+
+	source := loadFixture(t)
+	source.Errors[2] = &github.AbuseRateLimitError{Message: "You have exceeded a secondary rate limit"}
+	if err := runIncrementalUpdate(ctx, source, cfg); err == nil {
+		t.Fatal("Expected the rate limit to stop the run")
+	}
+
+	data, err := storage.NewStorage(cfg.DataFile).Load()
+	if err != nil {
+		t.Fatalf("Failed to load karma data: %v", err)
+	}
+	if _, ok := data.ProcessedPRs[1]; !ok {
+		t.Error("Expected PR #1 to be saved")
+	}
+	if _, ok := data.ProcessedPRs[2]; ok {
+		t.Error("Expected PR #2 to be left for the next run")
+	}
+
+	source.Errors[2] = nil
+	if err := runIncrementalUpdate(ctx, source, cfg); err != nil {
+		t.Fatalf("Incremental update failed: %v", err)
+	}
+	if content := readLeaderboard(t, cfg); !strings.Contains(content, "@carol | 3 |") {
+		t.Errorf("Expected PR #2 to be scored by the next run, got:\n%s", content)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/master-wayne7/reviewer-karma-action/internal/config"
+)
+
+// runMigrate upgrades the karma data file to the current schema version, or
+// with --dry-run only shows what would change. It returns the process exit code.
+func runMigrate(args []string) int {
+	dryRun := false
+	for _, arg := range args {
+		if arg != "--dry-run" {
+			fmt.Println("❌ Usage: reviewer-karma migrate [--dry-run]")
+			return 2
+		}
+		dryRun = true
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("❌ Invalid configuration: %v\n", err)
+		return 1
+	}
+
+	if err := migrate(cfg, dryRun); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// migrate upgrades the data file and reports the migrations and changes
+func migrate(cfg config.Config, dryRun bool) error {
+	store, err := openStorage(cfg)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	result, err := store.Migrate(dryRun)
+	if err != nil {
+		return fmt.Errorf("error migrating %s: %w", cfg.DataFile, err)
+	}
+
+	if len(result.Applied) == 0 {
+		fmt.Printf("✅ %s is already at schema version %d\n", cfg.DataFile, result.ToVersion)
+		return nil
+	}
+
+	fmt.Printf("📦 %s: schema version %d -> %d\n", cfg.DataFile, result.FromVersion, result.ToVersion)
+	for _, migration := range result.Applied {
+		fmt.Printf("  - %d -> %d: %s\n", migration.From, migration.From+1, migration.Description)
+	}
+	for _, change := range result.Changes {
+		fmt.Printf("  • %s\n", change)
+	}
+
+	if dryRun {
+		fmt.Println("🔍 Dry run, nothing was written")
+	} else {
+		fmt.Printf("✅ Migrated %s\n", cfg.DataFile)
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/master-wayne7/reviewer-karma-action/internal/config"
+	"github.com/master-wayne7/reviewer-karma-action/internal/karma"
+	"github.com/master-wayne7/reviewer-karma-action/internal/storage"
+)
+
+// runRescore replays the stored events through the current configuration and
+// rewrites the totals and leaderboard without contacting GitHub. It returns
+// the process exit code.
+func runRescore(args []string) int {
+	if len(args) > 0 {
+		fmt.Println("❌ Usage: reviewer-karma rescore")
+		return 2
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("❌ Invalid configuration: %v\n", err)
+		return 1
+	}
+
+	if err := rescore(cfg); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return 1
+	}
+
+	fmt.Println("✅ Reviewer karma leaderboard rescored successfully!")
+	return 0
+}
+
+// rescore re-scores every PR in the data file whose events are stored
+func rescore(cfg config.Config) error {
+	fmt.Printf("♻️ Rescoring stored events in %s...\n", cfg.DataFile)
+
+	store, err := openStorage(cfg)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	tx, err := store.Begin()
+	if err != nil {
+		return fmt.Errorf("error loading karma data: %w", err)
+	}
+	data := tx.Data()
+
+	if len(data.ProcessedPRs) == 0 {
+		return fmt.Errorf("no karma data found in %s; run an incremental update first", cfg.DataFile)
+	}
+
+	engine := newEngine(cfg)
+	tx.SetRescorer(rescorer(engine))
+	summary := newRunSummary()
+	rescored, changed, skipped := rescoreData(data, engine, summary)
+	data.ScoringFingerprint = cfg.ScoringFingerprint()
+
+	fmt.Printf("✅ Rescored %d PRs, %d with changed points\n", rescored, changed)
+	if skipped > 0 {
+		fmt.Printf("⚠️ Skipped %d PR(s) without stored events; the next incremental update that isn't triggered by a PR event fetches and re-scores them\n", skipped)
+	}
+	summary.print()
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error saving karma data: %w", err)
+	}
+
+	return writeLeaderboard(cfg, data, engine)
+}
+
+// rescoreData re-scores every PR whose events are stored in data and returns
+// the number of PRs rescored, those whose points changed, and those skipped
+// because their events are not stored. PRs processed before per-PR points
+// were stored are left alone, their points are only in the baseline.
+func rescoreData(data *storage.KarmaData, engine *karma.Engine, summary *runSummary) (rescored, changed, skipped int) {
+	prNumbers := make([]int, 0, len(data.ProcessedPRs))
+	for prNumber := range data.ProcessedPRs {
+		prNumbers = append(prNumbers, prNumber)
+	}
+	sort.Ints(prNumbers)
+
+	for _, prNumber := range prNumbers {
+		if !data.HasPRRecord(prNumber) {
+			continue
+		}
+		events, ok := data.Events[prNumber]
+		if !ok {
+			skipped++
+			continue
+		}
+
+		result := engine.EvaluateAll(replayEvents(prNumber, events))
+		for name, count := range result.Excluded {
+			summary.excluded[name] += count
+		}
+
+		ledgerSize := len(data.Ledger)
+		data.RecordPR(prNumber, events, ledgerEntries(result.Awards), data.ProcessedPRs[prNumber])
+		rescored++
+		if len(data.Ledger) != ledgerSize {
+			changed++
+		}
+	}
+
+	return rescored, changed, skipped
+}
+
+// rescorer scores stored events with engine, for merging the events of a PR
+// that a concurrent run changed as well
+func rescorer(engine *karma.Engine) storage.Rescorer {
+	return func(prNumber int, events []storage.Event) []storage.LedgerEntry {
+		return ledgerEntries(engine.EvaluateAll(replayEvents(prNumber, events)).Awards)
+	}
+}
+
+// checkScoringFingerprint compares the scoring configuration the stored data
+// was scored with against the current one. On a change the stored events are
+// rescored or an error is returned, depending on cfg.OnScoringChange. The
+// current fingerprint is then recorded in data.
+func checkScoringFingerprint(data *storage.KarmaData, cfg config.Config) error {
+	fingerprint := cfg.ScoringFingerprint()
+	if data.ScoringFingerprint == fingerprint {
+		return nil
+	}
+
+	// Data written before fingerprints were stored is assumed to match
+	if data.ScoringFingerprint != "" && len(data.ProcessedPRs) > 0 {
+		if cfg.OnScoringChange == config.ScoringChangeFail {
+			return fmt.Errorf("scoring configuration changed since %s was written (%s -> %s); run `reviewer-karma rescore` or set on-scoring-change to %q",
+				cfg.DataFile, data.ScoringFingerprint, fingerprint, config.ScoringChangeRescore)
+		}
+
+		fmt.Printf("⚙️ Scoring configuration changed (%s -> %s), rescoring stored events...\n", data.ScoringFingerprint, fingerprint)
+		rescored, changed, skipped := rescoreData(data, newEngine(cfg), newRunSummary())
+		fmt.Printf("♻️ Rescored %d PRs, %d with changed points\n", rescored, changed)
+		if skipped > 0 {
+			fmt.Printf("⚠️ %d PR(s) without stored events keep their old points until the next incremental update that isn't triggered by a PR event fetches them\n", skipped)
+		}
+	}
+
+	data.ScoringFingerprint = fingerprint
+	return nil
+}
+
+// storedEvents converts events into the raw form kept in storage
+func storedEvents(events []karma.Event) []storage.Event {
+	stored := make([]storage.Event, 0, len(events))
+	for _, event := range events {
+		stored = append(stored, storage.Event{
+			Kind:      string(event.Kind),
+			ID:        event.ID,
+			PRAuthor:  event.PRAuthor,
+			User:      event.User,
+			Body:      event.Body,
+			State:     event.State,
+			Reaction:  event.Reaction,
+			CreatedAt: event.CreatedAt,
+		})
+	}
+	return stored
+}
+
+// replayEvents converts stored events of a PR back into scoring events
+func replayEvents(prNumber int, stored []storage.Event) []karma.Event {
+	events := make([]karma.Event, 0, len(stored))
+	for _, event := range stored {
+		events = append(events, karma.Event{
+			Kind:      karma.EventKind(event.Kind),
+			ID:        event.ID,
+			PRNumber:  prNumber,
+			PRAuthor:  event.PRAuthor,
+			User:      event.User,
+			Body:      event.Body,
+			State:     event.State,
+			Reaction:  event.Reaction,
+			CreatedAt: event.CreatedAt,
+		})
+	}
+	return events
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/master-wayne7/reviewer-karma-action/internal/config"
+	"github.com/master-wayne7/reviewer-karma-action/internal/storage"
+)
+
+func TestRescoreAppliesNewWeights(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.IncrementalUpdate = true
+
+	if err := runIncrementalUpdate(context.Background(), loadFixture(t), cfg); err != nil {
+		t.Fatalf("Incremental update failed: %v", err)
+	}
+
+	before, err := storage.NewStorage(cfg.DataFile).Load()
+	if err != nil {
+		t.Fatalf("Failed to load karma data: %v", err)
+	}
+
+	// Approvals are now worth more; no source is needed to apply that
+	cfg.ApprovedReviewPoint = 10
+	if err := rescore(cfg); err != nil {
+		t.Fatalf("Rescore failed: %v", err)
+	}
+
+	content := readLeaderboard(t, cfg)
+
+	// bob: approved (10) + emoji (2)
+	for _, row := range []string{"| 1 | 🥇 @bob | 12 |", "@alice | 3 |"} {
+		if !strings.Contains(content, row) {
+			t.Errorf("Expected leaderboard to contain %q, got:\n%s", row, content)
+		}
+	}
+
+	after, err := storage.NewStorage(cfg.DataFile).Load()
+	if err != nil {
+		t.Fatalf("Failed to load karma data: %v", err)
+	}
+
+	if after.Reviewers["bob"] != 12 {
+		t.Errorf("Expected bob to have 12 points, got %d", after.Reviewers["bob"])
+	}
+
+	// Only bob's approval changed: one reversal and one new entry
+	if added := len(after.Ledger) - len(before.Ledger); added != 2 {
+		t.Errorf("Expected 2 new ledger entries, got %d", added)
+	}
+}
+
+func TestRescoreRequiresData(t *testing.T) {
+	cfg := testConfig(t)
+
+	if err := rescore(cfg); err == nil {
+		t.Error("Expected an error without karma data")
+	}
+}
+
+func TestRunIncrementalUpdateRescoresOnScoringChange(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.IncrementalUpdate = true
+	ctx := context.Background()
+
+	if err := runIncrementalUpdate(ctx, loadFixture(t), cfg); err != nil {
+		t.Fatalf("Incremental update failed: %v", err)
+	}
+
+	// Nothing new to fetch, but the stored points must follow the new weights
+	cfg.ApprovedReviewPoint = 10
+	if err := runIncrementalUpdate(ctx, loadFixture(t), cfg); err != nil {
+		t.Fatalf("Second incremental update failed: %v", err)
+	}
+
+	data, err := storage.NewStorage(cfg.DataFile).Load()
+	if err != nil {
+		t.Fatalf("Failed to load karma data: %v", err)
+	}
+
+	if data.Reviewers["bob"] != 12 {
+		t.Errorf("Expected bob to have 12 points, got %d", data.Reviewers["bob"])
+	}
+
+	if data.ScoringFingerprint != cfg.ScoringFingerprint() {
+		t.Errorf("Expected fingerprint %s, got %s", cfg.ScoringFingerprint(), data.ScoringFingerprint)
+	}
+}
+
+func TestRunIncrementalUpdateFailsOnScoringChange(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.IncrementalUpdate = true
+	cfg.OnScoringChange = config.ScoringChangeFail
+	ctx := context.Background()
+
+	if err := runIncrementalUpdate(ctx, loadFixture(t), cfg); err != nil {
+		t.Fatalf("Incremental update failed: %v", err)
+	}
+
+	cfg.ApprovedReviewPoint = 10
+	err := runIncrementalUpdate(ctx, loadFixture(t), cfg)
+	if err == nil || !strings.Contains(err.Error(), "scoring configuration changed") {
+		t.Fatalf("Expected a scoring change error, got: %v", err)
+	}
+
+	// An explicit rescore adopts the new configuration
+	if err := rescore(cfg); err != nil {
+		t.Fatalf("Rescore failed: %v", err)
+	}
+	if err := runIncrementalUpdate(ctx, loadFixture(t), cfg); err != nil {
+		t.Errorf("Expected update to succeed after rescoring, got: %v", err)
+	}
+}
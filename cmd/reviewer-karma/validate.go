@@ -0,0 +1,47 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/master-wayne7/reviewer-karma-action/internal/config"
+)
+
+// runValidateConfig loads and validates the configuration without contacting
+// GitHub, printing every problem found. It returns the process exit code.
+func runValidateConfig(args []string) int {
+	if len(args) > 1 {
+		fmt.Println("❌ Usage: reviewer-karma validate-config [config-file]")
+		return 2
+	}
+	if len(args) == 1 {
+		os.Setenv("CONFIG_FILE", args[0])
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		var validationErr *config.ValidationError
+		if errors.As(err, &validationErr) {
+			fmt.Printf("❌ Found %d configuration problem(s):\n", len(validationErr.Problems))
+			for _, problem := range validationErr.Problems {
+				fmt.Printf("  - %s\n", problem)
+			}
+		} else {
+			fmt.Printf("❌ %v\n", err)
+		}
+		return 1
+	}
+
+	if cfg.ConfigFile != "" {
+		fmt.Printf("⚙️ Configuration file: %s\n", cfg.ConfigFile)
+	} else {
+		fmt.Println("⚙️ No configuration file found, using defaults and environment variables")
+	}
+	fmt.Printf("📊 Reviews: Approved=%d, ChangesRequested=%d, Commented=%d, Dismissed=%d\n",
+		cfg.ApprovedReviewPoint, cfg.ChangesRequestedReviewPoint, cfg.CommentedReviewPoint, cfg.DismissedReviewPoint)
+	fmt.Printf("📊 Comments: Emoji=%d, Constructive=%d, Conversation=%d\n", cfg.PositiveEmojiPoint, cfg.ConstructiveCommentPoint, cfg.IssueCommentPoint)
+	fmt.Printf("📝 Output: %s (data: %s, %s)\n", cfg.LeaderboardFile, cfg.DataFile, cfg.StorageBackend)
+	fmt.Println("✅ Configuration is valid")
+	return 0
+}
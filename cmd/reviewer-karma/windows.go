@@ -0,0 +1,62 @@
+package main
+
+import (
+	"time"
+
+	"github.com/master-wayne7/reviewer-karma-action/internal/config"
+	"github.com/master-wayne7/reviewer-karma-action/internal/karma"
+	"github.com/master-wayne7/reviewer-karma-action/internal/storage"
+)
+
+// rollingWindows returns the time range of every configured leaderboard
+// window. Windows count whole days in UTC, up to and including the day of now.
+func rollingWindows(cfg config.Config, now time.Time) []karma.Window {
+	today := now.UTC().Truncate(24 * time.Hour)
+	windows := make([]karma.Window, len(cfg.LeaderboardWindows))
+	for i, window := range cfg.LeaderboardWindows {
+		if window.Days > 0 {
+			windows[i] = karma.Window{Since: today.AddDate(0, 0, 1-window.Days)}
+		}
+	}
+	return windows
+}
+
+// addWindowAwards adds the points of every award to the totals of the
+// windows it falls within
+func addWindowAwards(totals []map[string]int, windows []karma.Window, awards []karma.Award) {
+	for _, award := range awards {
+		for i, window := range windows {
+			if window.Contains(award.CreatedAt) {
+				totals[i][award.User] += award.Points
+			}
+		}
+	}
+}
+
+// newLeaderboards ranks totals, or with leaderboard windows configured, the
+// totals of every window as returned by windowTotals
+func newLeaderboards(cfg config.Config, windows []karma.Window, totals map[string]int, windowTotals func(i int) map[string]int) []karma.Leaderboard {
+	if len(windows) == 0 {
+		return []karma.Leaderboard{karma.GenerateLeaderboard(totals)}
+	}
+
+	leaderboards := make([]karma.Leaderboard, len(windows))
+	for i, window := range windows {
+		leaderboards[i] = karma.GenerateLeaderboard(windowTotals(i))
+		leaderboards[i].Title = cfg.LeaderboardWindows[i].Name()
+		leaderboards[i].Window = window
+	}
+	return leaderboards
+}
+
+// dataLeaderboards ranks the totals in data and, for every leaderboard
+// window, the activity its ledger records within the window
+func dataLeaderboards(cfg config.Config, data *storage.KarmaData, now time.Time) []karma.Leaderboard {
+	windows := rollingWindows(cfg, now)
+	return newLeaderboards(cfg, windows, data.Reviewers, func(i int) map[string]int {
+		if windows[i].IsZero() {
+			return data.Reviewers
+		}
+		return data.TotalsSince(windows[i].Since)
+	})
+}
@@ -0,0 +1,87 @@
+package atomicfile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// BackupSuffix is appended to a file's path to name its backup
+const BackupSuffix = ".bak"
+
+// BackupPath returns the path of the backup kept by WriteWithBackup
+func BackupPath(path string) string {
+	return path + BackupSuffix
+}
+
+// Write replaces the file at path with data. The data is written to a
+// temporary file in the same directory and renamed over path, so readers
+// see either the old or the new content, never a truncated file.
+func Write(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	tmpPath := tmp.Name()
+
+	// Remove the temporary file unless it was renamed into place
+	renamed := false
+	defer func() {
+		if !renamed {
+			os.Remove(tmpPath)
+		}
+	}()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temporary file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync temporary file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temporary file: %w", err)
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		return fmt.Errorf("failed to set file permissions: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	renamed = true
+
+	syncDir(dir)
+	return nil
+}
+
+// WriteWithBackup is like Write, but first keeps the current content of path
+// at BackupPath(path). Both replacements are atomic, so a crash at any point
+// leaves a readable file at path or at its backup. If valid is not nil, the
+// current content is only backed up when valid reports it usable, so a
+// corrupt file never replaces a good backup.
+func WriteWithBackup(path string, data []byte, perm os.FileMode, valid func([]byte) bool) error {
+	previous, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s for backup: %w", path, err)
+	}
+
+	// An empty file has nothing worth keeping
+	if len(previous) > 0 && (valid == nil || valid(previous)) {
+		if err := Write(BackupPath(path), previous, perm); err != nil {
+			return fmt.Errorf("failed to back up %s: %w", path, err)
+		}
+	}
+
+	return Write(path, data, perm)
+}
+
+// syncDir flushes a directory entry change to disk where supported
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+}
@@ -0,0 +1,65 @@
+package atomicfile
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "REVIEWERS.md")
+
+	for _, content := range []string{"first", "second"} {
+		if err := Write(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %q: %v", content, err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if string(data) != "second" {
+		t.Errorf("Expected %q, got %q", "second", data)
+	}
+
+	// No temporary files are left behind
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to read dir: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Expected only the written file, got %d entries", len(entries))
+	}
+}
+
+func TestWriteWithBackup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.json")
+
+	tests := []struct {
+		name           string
+		content        string
+		expectedBackup string
+	}{
+		{"first generation", `{"n": 1}`, ""},
+		{"second generation", `{"n": 2}`, `{"n": 1}`},
+		{"corrupt generation", `{"n": `, `{"n": 2}`},
+		{"after corrupt generation", `{"n": 3}`, `{"n": 2}`}, // The corrupt file is not backed up
+	}
+
+	for _, test := range tests {
+		if err := WriteWithBackup(path, []byte(test.content), 0644, json.Valid); err != nil {
+			t.Fatalf("%s: failed to write: %v", test.name, err)
+		}
+
+		backup, err := os.ReadFile(BackupPath(path))
+		if err != nil && !os.IsNotExist(err) {
+			t.Fatalf("%s: failed to read backup: %v", test.name, err)
+		}
+		if string(backup) != test.expectedBackup {
+			t.Errorf("%s: expected backup %q, got %q", test.name, test.expectedBackup, backup)
+		}
+	}
+}
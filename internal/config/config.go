@@ -1,9 +1,11 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Config holds the karma point configuration
@@ -11,42 +13,463 @@ type Config struct {
 	ReviewPoint              int
 	PositiveEmojiPoint       int
 	ConstructiveCommentPoint int
+	IssueCommentPoint        int
 	IncrementalUpdate        bool
+	EventMode                bool // Score only the triggering pull request during incremental updates
+	Concurrency              int  // Pull requests whose activity is fetched at the same time
+
+	// How pull request activity is fetched: FetcherREST or FetcherGraphQL
+	Fetcher string
+
+	// Time range a full recreation scores, from Since up to but excluding
+	// Until. A zero time leaves that side of the range open.
+	Since time.Time
+	Until time.Time
+
+	// Per-state review points, defaulting to ReviewPoint when unset
+	ApprovedReviewPoint         int
+	ChangesRequestedReviewPoint int
+	CommentedReviewPoint        int
+	DismissedReviewPoint        int
+	RevokeDismissedReviews      bool
+
+	// Activity by the author on their own pull request
+	ExcludeSelfActivity bool
+	SelfActivityPercent int // Percentage of points kept when self activity is not excluded
+
+	// Reaction points by reaction type, empty to not score reactions
+	ReactionPoints map[string]int
+	ReactionCap    int // Maximum reaction points per user per pull request, 0 for no cap
+
+	// Detection lists, empty to use the built-in defaults
+	PositiveEmojis []string
+	BotPatterns    []string
+	ExcludedUsers  []string
+
+	// What an incremental update does when the stored points were scored
+	// with a different configuration: ScoringChangeRescore or ScoringChangeFail
+	OnScoringChange string
+
+	// Output targets
+	LeaderboardFile string
+	DataFile        string
+	StorageBackend  string // How DataFile is stored: StorageJSON, StorageSQLite or StorageGit
+	CheckpointFile  string // Progress of an interrupted full recreation
+	CacheDir        string // Cached GitHub API responses, empty to not cache
+
+	// Rankings written to the leaderboard, one table per window. Empty
+	// writes a single all-time table.
+	LeaderboardWindows []LeaderboardWindow
+
+	// Where the git storage backend keeps DataFile
+	DataBranch string
+	DataRemote string
+
+	// ConfigFile is the configuration file that was loaded, if any
+	ConfigFile string
+
+	// Where ConfigFile set each setting, by the name problems describe it with
+	sources map[string]string
+}
+
+// LeaderboardWindow is a ranking of the activity in the last Days days,
+// counting today, or of all activity when Days is 0
+type LeaderboardWindow struct {
+	Title string
+	Days  int
 }
 
+// Name returns the title of the window, or one describing its length
+func (w LeaderboardWindow) Name() string {
+	switch {
+	case w.Title != "":
+		return w.Title
+	case w.Days == 0:
+		return "All Time"
+	case w.Days == 1:
+		return "Today"
+	}
+	return fmt.Sprintf("Last %d Days", w.Days)
+}
+
+// Storage backends for the karma data
+const (
+	StorageJSON   = "json"
+	StorageSQLite = "sqlite"
+	StorageGit    = "git" // DataFile is committed to DataBranch instead of the working tree
+)
+
+// Fetchers of pull request activity
+const (
+	FetcherREST    = "rest"
+	FetcherGraphQL = "graphql" // Activity of many pull requests per request, falling back to REST for busy ones
+)
+
 // Default configuration
 var defaultConfig = Config{
 	ReviewPoint:              1,
 	PositiveEmojiPoint:       2,
 	ConstructiveCommentPoint: 1,
+	IssueCommentPoint:        0,
 	IncrementalUpdate:        false, // Default to full recreation
+	EventMode:                true,
+	Concurrency:              4,
+	Fetcher:                  FetcherREST,
+
+	ApprovedReviewPoint:         1,
+	ChangesRequestedReviewPoint: 1,
+	CommentedReviewPoint:        1,
+	DismissedReviewPoint:        0,
+	RevokeDismissedReviews:      false,
+
+	ExcludeSelfActivity: true,
+	SelfActivityPercent: 100,
+
+	ReactionCap: 5,
+
+	OnScoringChange: ScoringChangeRescore,
+
+	LeaderboardFile: "REVIEWERS.md",
+	DataFile:        ".karma-data.json",
+	StorageBackend:  StorageJSON,
+	CheckpointFile:  ".karma-checkpoint.json",
+
+	DataBranch: "karma-data",
+	DataRemote: "origin",
 }
 
-// Load loads configuration from environment variables
-func Load() Config {
+// Default data file of the sqlite backend, which keeps a database rather than
+// the JSON of defaultConfig.DataFile
+const defaultSQLiteDataFile = ".karma-data.db"
+
+// Default locations searched for a configuration file
+var defaultConfigFiles = []string{
+	".github/reviewer-karma.yml",
+	".github/reviewer-karma.yaml",
+	".github/reviewer-karma.json",
+}
+
+// Load loads configuration with the following precedence, highest first:
+// environment variables (including action inputs), the configuration file
+// named by CONFIG_FILE or found in .github/, and the built-in defaults
+func Load() (Config, error) {
 	config := defaultConfig
 
+	path := os.Getenv("CONFIG_FILE")
+	if path == "" {
+		path = findConfigFile()
+	}
+
+	var problems []string
+	if path != "" {
+		fileProblems, err := applyFile(&config, path)
+		if err != nil {
+			return config, err
+		}
+		problems = fileProblems
+		config.ConfigFile = path
+	}
+
+	problems = append(problems, applyEnv(&config)...)
+	_, fileDataFile := config.sources["data file"]
+	config.applyBackendDefaults(fileDataFile || os.Getenv("DATA_FILE") != "")
+	problems = append(problems, config.problems()...)
+	if len(problems) > 0 {
+		return config, &ValidationError{Problems: problems}
+	}
+
+	return config, nil
+}
+
+// applyBackendDefaults replaces the defaults that depend on the storage
+// backend, unless the data file was set explicitly
+func (c *Config) applyBackendDefaults(dataFileSet bool) {
+	if !dataFileSet && c.StorageBackend == StorageSQLite {
+		c.DataFile = defaultSQLiteDataFile
+	}
+}
+
+// findConfigFile returns the first default configuration file that exists
+func findConfigFile() string {
+	for _, path := range defaultConfigFiles {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// Settings overridden by each environment variable, by the name problems
+// describe them with
+var envSettings = map[string][]string{
+	"REVIEW_POINT":                   {"review point"},
+	"APPROVED_REVIEW_POINT":          {"approved review point"},
+	"CHANGES_REQUESTED_REVIEW_POINT": {"changes requested review point"},
+	"COMMENTED_REVIEW_POINT":         {"commented review point"},
+	"DISMISSED_REVIEW_POINT":         {"dismissed review point"},
+	"POSITIVE_EMOJI_POINT":           {"positive emoji point"},
+	"CONSTRUCTIVE_COMMENT_POINT":     {"constructive comment point"},
+	"ISSUE_COMMENT_POINT":            {"issue comment point"},
+	"REACTION_CAP":                   {"reaction cap"},
+	"REACTION_POINTS":                {"reactions"},
+	"SELF_ACTIVITY_PERCENT":          {"self activity percent"},
+	"CONCURRENCY":                    {"concurrency"},
+	"REVOKE_DISMISSED_REVIEWS":       {"revoke dismissed reviews"},
+	"EXCLUDE_SELF_ACTIVITY":          {"exclude self activity"},
+	"INCREMENTAL_UPDATE":             {"incremental update"},
+	"EVENT_MODE":                     {"event mode"},
+	"SINCE":                          {"since"},
+	"UNTIL":                          {"until"},
+	"FETCHER":                        {"fetcher"},
+	"ON_SCORING_CHANGE":              {"on scoring change"},
+	"LEADERBOARD_FILE":               {"leaderboard file"},
+	"DATA_FILE":                      {"data file"},
+	"CHECKPOINT_FILE":                {"checkpoint file"},
+	"CACHE_DIR":                      {"cache dir"},
+	"LEADERBOARD_WINDOWS":            {"leaderboard windows"},
+	"STORAGE_BACKEND":                {"storage backend"},
+	"DATA_BRANCH":                    {"data branch"},
+	"DATA_REMOTE":                    {"data remote"},
+}
+
+// applyEnv overrides config with values from environment variables and
+// returns a problem for every value that could not be parsed
+func applyEnv(config *Config) []string {
+	var problems []string
+
+	for name, settings := range envSettings {
+		if os.Getenv(name) != "" {
+			config.forget(settings)
+		}
+	}
+
 	if val := os.Getenv("REVIEW_POINT"); val != "" {
-		if points, err := strconv.Atoi(val); err == nil {
+		if points, err := parseInt("REVIEW_POINT", val); err != nil {
+			problems = append(problems, err.Error())
+		} else {
 			config.ReviewPoint = points
+			// States the file or their own variable set keep their points
+			for _, state := range []struct {
+				env    string
+				name   string
+				key    string
+				target *int
+			}{
+				{"APPROVED_REVIEW_POINT", "approved review point", "scoring.reviews.approved", &config.ApprovedReviewPoint},
+				{"CHANGES_REQUESTED_REVIEW_POINT", "changes requested review point", "scoring.reviews.changes_requested", &config.ChangesRequestedReviewPoint},
+				{"COMMENTED_REVIEW_POINT", "commented review point", "scoring.reviews.commented", &config.CommentedReviewPoint},
+			} {
+				if os.Getenv(state.env) == "" && !config.setBy(state.name, state.key) {
+					*state.target = points
+					config.forget([]string{state.name})
+				}
+			}
+		}
+	}
+
+	envInts := []struct {
+		name   string
+		target *int
+	}{
+		{"APPROVED_REVIEW_POINT", &config.ApprovedReviewPoint},
+		{"CHANGES_REQUESTED_REVIEW_POINT", &config.ChangesRequestedReviewPoint},
+		{"COMMENTED_REVIEW_POINT", &config.CommentedReviewPoint},
+		{"DISMISSED_REVIEW_POINT", &config.DismissedReviewPoint},
+		{"POSITIVE_EMOJI_POINT", &config.PositiveEmojiPoint},
+		{"CONSTRUCTIVE_COMMENT_POINT", &config.ConstructiveCommentPoint},
+		{"ISSUE_COMMENT_POINT", &config.IssueCommentPoint},
+		{"REACTION_CAP", &config.ReactionCap},
+		{"SELF_ACTIVITY_PERCENT", &config.SelfActivityPercent},
+		{"CONCURRENCY", &config.Concurrency},
+	}
+
+	for _, env := range envInts {
+		if val := os.Getenv(env.name); val != "" {
+			if points, err := parseInt(env.name, val); err != nil {
+				problems = append(problems, err.Error())
+			} else {
+				*env.target = points
+			}
+		}
+	}
+
+	if val := os.Getenv("REACTION_POINTS"); val != "" {
+		if points, err := parseReactionPoints(val); err != nil {
+			problems = append(problems, err.Error())
+		} else {
+			config.ReactionPoints = points
 		}
 	}
 
-	if val := os.Getenv("POSITIVE_EMOJI_POINT"); val != "" {
-		if points, err := strconv.Atoi(val); err == nil {
-			config.PositiveEmojiPoint = points
+	envBools := []struct {
+		name   string
+		target *bool
+	}{
+		{"REVOKE_DISMISSED_REVIEWS", &config.RevokeDismissedReviews},
+		{"INCREMENTAL_UPDATE", &config.IncrementalUpdate},
+		{"EVENT_MODE", &config.EventMode},
+		{"EXCLUDE_SELF_ACTIVITY", &config.ExcludeSelfActivity},
+	}
+
+	for _, env := range envBools {
+		if val := os.Getenv(env.name); val != "" {
+			if enabled, err := parseBool(env.name, val); err != nil {
+				problems = append(problems, err.Error())
+			} else {
+				*env.target = enabled
+			}
 		}
 	}
 
-	if val := os.Getenv("CONSTRUCTIVE_COMMENT_POINT"); val != "" {
-		if points, err := strconv.Atoi(val); err == nil {
-			config.ConstructiveCommentPoint = points
+	envDates := []struct {
+		name     string
+		target   *time.Time
+		endOfDay bool
+	}{
+		{"SINCE", &config.Since, false},
+		{"UNTIL", &config.Until, true},
+	}
+
+	for _, env := range envDates {
+		if val := os.Getenv(env.name); val != "" {
+			if date, err := parseDate(val, env.endOfDay); err != nil {
+				problems = append(problems, env.name+": "+err.Error())
+			} else {
+				*env.target = date
+			}
 		}
 	}
 
-	if val := os.Getenv("INCREMENTAL_UPDATE"); val != "" {
-		config.IncrementalUpdate = strings.ToLower(val) == "true"
+	if val := os.Getenv("FETCHER"); val != "" {
+		config.Fetcher = strings.ToLower(strings.TrimSpace(val))
 	}
 
-	return config
+	if val := os.Getenv("ON_SCORING_CHANGE"); val != "" {
+		config.OnScoringChange = strings.ToLower(strings.TrimSpace(val))
+	}
+
+	if val := os.Getenv("LEADERBOARD_FILE"); val != "" {
+		config.LeaderboardFile = val
+	}
+
+	if val := os.Getenv("DATA_FILE"); val != "" {
+		config.DataFile = val
+	}
+
+	if val := os.Getenv("CHECKPOINT_FILE"); val != "" {
+		config.CheckpointFile = val
+	}
+
+	if val := os.Getenv("CACHE_DIR"); val != "" {
+		config.CacheDir = val
+	}
+
+	if val := os.Getenv("LEADERBOARD_WINDOWS"); val != "" {
+		if windows, err := parseLeaderboardWindows(val); err != nil {
+			problems = append(problems, err.Error())
+		} else {
+			config.LeaderboardWindows = windows
+		}
+	}
+
+	if val := os.Getenv("STORAGE_BACKEND"); val != "" {
+		config.StorageBackend = strings.ToLower(strings.TrimSpace(val))
+	}
+
+	if val := os.Getenv("DATA_BRANCH"); val != "" {
+		config.DataBranch = strings.TrimSpace(val)
+	}
+
+	if val := os.Getenv("DATA_REMOTE"); val != "" {
+		config.DataRemote = strings.TrimSpace(val)
+	}
+
+	return problems
+}
+
+// parseInt parses an integer environment variable value
+func parseInt(name, val string) (int, error) {
+	points, err := strconv.Atoi(strings.TrimSpace(val))
+	if err != nil {
+		return 0, fmt.Errorf("%s: %q is not a valid integer", name, val)
+	}
+	return points, nil
+}
+
+// parseDate parses a YYYY-MM-DD date, in UTC, or an RFC 3339 time. A date
+// is the start of that day, or its end if endOfDay is set, so a range ending
+// on a date includes the whole day.
+func parseDate(val string, endOfDay bool) (time.Time, error) {
+	val = strings.TrimSpace(val)
+	if t, err := time.Parse(time.RFC3339, val); err == nil {
+		return t, nil
+	}
+	date, err := time.Parse(time.DateOnly, val)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is not a valid date, expected YYYY-MM-DD or an RFC 3339 time", val)
+	}
+	if endOfDay {
+		date = date.AddDate(0, 0, 1)
+	}
+	return date, nil
+}
+
+// parseReactionPoints parses a comma-separated list of reaction=points pairs,
+// such as "+1=1,rocket=2"
+func parseReactionPoints(val string) (map[string]int, error) {
+	points := make(map[string]int)
+	for _, pair := range strings.Split(val, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		reaction, value, found := strings.Cut(pair, "=")
+		if !found {
+			return nil, fmt.Errorf("REACTION_POINTS: %q must be in the form reaction=points", pair)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("REACTION_POINTS: %q is not a valid integer for %s", value, reaction)
+		}
+		points[strings.TrimSpace(reaction)] = n
+	}
+	return points, nil
+}
+
+// parseLeaderboardWindows parses a comma-separated list of windows, each a
+// number of days or "all", optionally titled, such as "This Week=7,All Time=all"
+func parseLeaderboardWindows(val string) ([]LeaderboardWindow, error) {
+	var windows []LeaderboardWindow
+	for _, entry := range strings.Split(val, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		var window LeaderboardWindow
+		days := entry
+		if title, value, found := strings.Cut(entry, "="); found {
+			window.Title = strings.TrimSpace(title)
+			days = strings.TrimSpace(value)
+		}
+		if !strings.EqualFold(days, "all") {
+			n, err := strconv.Atoi(days)
+			if err != nil {
+				return nil, fmt.Errorf("LEADERBOARD_WINDOWS: %q must be a number of days or \"all\"", days)
+			}
+			window.Days = n
+		}
+		windows = append(windows, window)
+	}
+	return windows, nil
+}
+
+// parseBool parses a true/false environment variable value
+func parseBool(name, val string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(val)) {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	return false, fmt.Errorf("%s: %q must be \"true\" or \"false\"", name, val)
 }
@@ -1,13 +1,21 @@
 package config
 
 import (
+	"go/ast"
+	"go/parser"
+	"go/token"
 	"os"
+	"regexp"
+	"strconv"
 	"testing"
 )
 
 func TestLoadConfig(t *testing.T) {
 	// Test default config
-	config := Load()
+	config, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
 
 	if config.ReviewPoint != 1 {
 		t.Errorf("Expected ReviewPoint to be 1, got %d", config.ReviewPoint)
@@ -29,7 +37,10 @@ func TestLoadConfigWithEnvironment(t *testing.T) {
 	os.Setenv("CONSTRUCTIVE_COMMENT_POINT", "2")
 
 	// Test config with environment variables
-	config := Load()
+	config, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
 
 	if config.ReviewPoint != 3 {
 		t.Errorf("Expected ReviewPoint to be 3, got %d", config.ReviewPoint)
@@ -48,3 +59,105 @@ func TestLoadConfigWithEnvironment(t *testing.T) {
 	os.Unsetenv("POSITIVE_EMOJI_POINT")
 	os.Unsetenv("CONSTRUCTIVE_COMMENT_POINT")
 }
+
+func TestLoadConfigReviewStatePoints(t *testing.T) {
+	os.Setenv("REVIEW_POINT", "2")
+	os.Setenv("APPROVED_REVIEW_POINT", "4")
+	os.Setenv("DISMISSED_REVIEW_POINT", "-1")
+	os.Setenv("REVOKE_DISMISSED_REVIEWS", "true")
+	defer func() {
+		os.Unsetenv("REVIEW_POINT")
+		os.Unsetenv("APPROVED_REVIEW_POINT")
+		os.Unsetenv("DISMISSED_REVIEW_POINT")
+		os.Unsetenv("REVOKE_DISMISSED_REVIEWS")
+	}()
+
+	config, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if config.ApprovedReviewPoint != 4 {
+		t.Errorf("Expected ApprovedReviewPoint to be 4, got %d", config.ApprovedReviewPoint)
+	}
+
+	// Unset states inherit REVIEW_POINT
+	if config.ChangesRequestedReviewPoint != 2 {
+		t.Errorf("Expected ChangesRequestedReviewPoint to be 2, got %d", config.ChangesRequestedReviewPoint)
+	}
+
+	if config.CommentedReviewPoint != 2 {
+		t.Errorf("Expected CommentedReviewPoint to be 2, got %d", config.CommentedReviewPoint)
+	}
+
+	if config.DismissedReviewPoint != -1 {
+		t.Errorf("Expected DismissedReviewPoint to be -1, got %d", config.DismissedReviewPoint)
+	}
+
+	if !config.RevokeDismissedReviews {
+		t.Error("Expected RevokeDismissedReviews to be true")
+	}
+}
+
+// File settings without an environment variable
+var fileOnlySettings = map[string]bool{"emojis": true, "bot patterns": true, "excluded users": true}
+
+func TestSettingTablesAgree(t *testing.T) {
+	// Every environment variable applyEnv reads is named by a string literal
+	file, err := parser.ParseFile(token.NewFileSet(), "config.go", nil, 0)
+	if err != nil {
+		t.Fatalf("Failed to parse config.go: %v", err)
+	}
+	envName := regexp.MustCompile(`^[A-Z][A-Z_]+$`)
+	read := make(map[string]bool)
+	for _, decl := range file.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Name.Name == "applyEnv" {
+			ast.Inspect(fn, func(node ast.Node) bool {
+				if lit, ok := node.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+					if name, _ := strconv.Unquote(lit.Value); envName.MatchString(name) {
+						read[name] = true
+					}
+				}
+				return true
+			})
+		}
+	}
+	if len(read) == 0 {
+		t.Fatal("Expected applyEnv to read environment variables")
+	}
+
+	for name := range read {
+		if _, ok := envSettings[name]; !ok {
+			t.Errorf("Expected envSettings to list %s, which applyEnv reads", name)
+		}
+	}
+
+	fromEnv := make(map[string]bool)
+	for name, settings := range envSettings {
+		if !read[name] {
+			t.Errorf("Expected applyEnv to read %s, which envSettings lists", name)
+		}
+		for _, setting := range settings {
+			fromEnv[setting] = true
+		}
+	}
+
+	fromFile := map[string]bool{"reactions": true}
+	for _, setting := range fileSettings {
+		fromFile[setting.name] = true
+	}
+	for _, list := range fileLists {
+		fromFile[list.name] = true
+	}
+
+	for setting := range fromEnv {
+		if !fromFile[setting] {
+			t.Errorf("Expected fileSettings to list %q, which envSettings lists", setting)
+		}
+	}
+	for setting := range fromFile {
+		if !fromEnv[setting] && !fileOnlySettings[setting] {
+			t.Errorf("Expected envSettings to list %q, which fileSettings lists", setting)
+		}
+	}
+}
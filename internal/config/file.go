@@ -0,0 +1,542 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// fileConfig mirrors the structure of .github/reviewer-karma.yml. JSON files
+// use the same keys, since JSON is parsed as YAML.
+type fileConfig struct {
+	Scoring struct {
+		Review  *int `yaml:"review"`
+		Reviews struct {
+			Approved         *int  `yaml:"approved"`
+			ChangesRequested *int  `yaml:"changes_requested"`
+			Commented        *int  `yaml:"commented"`
+			Dismissed        *int  `yaml:"dismissed"`
+			RevokeDismissed  *bool `yaml:"revoke_dismissed"`
+		} `yaml:"reviews"`
+		PositiveEmoji       *int           `yaml:"positive_emoji"`
+		ConstructiveComment *int           `yaml:"constructive_comment"`
+		IssueComment        *int           `yaml:"issue_comment"`
+		Reactions           map[string]int `yaml:"reactions"`
+		ReactionCap         *int           `yaml:"reaction_cap"`
+	} `yaml:"scoring"`
+
+	Emojis []string `yaml:"emojis"`
+
+	Bots struct {
+		Patterns []string `yaml:"patterns"`
+	} `yaml:"bots"`
+
+	Exclude struct {
+		Users               []string `yaml:"users"`
+		SelfActivity        *bool    `yaml:"self_activity"`
+		SelfActivityPercent *int     `yaml:"self_activity_percent"`
+	} `yaml:"exclude"`
+
+	Output struct {
+		Leaderboard string `yaml:"leaderboard"`
+		DataFile    string `yaml:"data_file"`
+		Storage     string `yaml:"storage"`
+		Checkpoint  string `yaml:"checkpoint"`
+		CacheDir    string `yaml:"cache_dir"`
+		Branch      string `yaml:"branch"`
+		Remote      string `yaml:"remote"`
+		Windows     []struct {
+			Title string `yaml:"title"`
+			Days  int    `yaml:"days"`
+		} `yaml:"windows"`
+	} `yaml:"output"`
+
+	IncrementalUpdate *bool    `yaml:"incremental_update"`
+	EventMode         *bool    `yaml:"event_mode"`
+	Concurrency       *int     `yaml:"concurrency"`
+	Fetcher           string   `yaml:"fetcher"`
+	Since             fileDate `yaml:"since"`
+	Until             fileDate `yaml:"until"`
+	OnScoringChange   string   `yaml:"on_scoring_change"`
+}
+
+// fileDate is a date or time in a configuration file, kept as written since
+// a whole date means the start or the end of the day depending on the setting
+type fileDate string
+
+// UnmarshalYAML checks that the value is a date or an RFC 3339 time. The
+// problem is reported as a type error, so decoding carries on.
+func (d *fileDate) UnmarshalYAML(node *yaml.Node) error {
+	if _, err := parseDate(node.Value, false); err != nil || node.Kind != yaml.ScalarNode {
+		return &yaml.TypeError{Errors: []string{
+			fmt.Sprintf("line %d: %q is not a valid date, expected YYYY-MM-DD or an RFC 3339 time", node.Line, node.Value),
+		}}
+	}
+	*d = fileDate(node.Value)
+	return nil
+}
+
+// LoadFile loads and validates configuration from a YAML or JSON file on top
+// of the defaults, without applying environment variables
+func LoadFile(path string) (Config, error) {
+	config := defaultConfig
+	problems, err := applyFile(&config, path)
+	if err != nil {
+		return config, err
+	}
+	config.ConfigFile = path
+	_, dataFileSet := config.sources["data file"]
+	config.applyBackendDefaults(dataFileSet)
+
+	problems = append(problems, config.problems()...)
+	if len(problems) > 0 {
+		return config, &ValidationError{Problems: problems}
+	}
+	return config, nil
+}
+
+// applyFile overrides config with the values set in the file at path and
+// returns a problem for every value that could not be decoded. Values that
+// decode are applied regardless, so they are validated too.
+func applyFile(config *Config, path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var problems []string
+	fc, err := parseFile(data)
+	lines := indexLines(path, data)
+	if err != nil {
+		var typeErr *yaml.TypeError
+		if !errors.As(err, &typeErr) {
+			return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+		}
+		failed := make(map[string]bool)
+		for _, msg := range typeErr.Errors {
+			problem, key := lines.describe(msg)
+			problems = append(problems, problem)
+			if key != "" {
+				failed[key] = true
+			}
+		}
+
+		// yaml leaves zero values behind for the values it failed to
+		// decode, which would be applied and validated too
+		fc = lines.decodeWithout(failed)
+	}
+
+	fc.apply(config)
+	config.sources = lines.sources()
+	return problems, nil
+}
+
+// parseFile decodes a YAML or JSON configuration document. Decoding errors
+// reported by yaml include the offending line number.
+func parseFile(data []byte) (*fileConfig, error) {
+	var fc fileConfig
+	if len(bytes.TrimSpace(data)) == 0 {
+		return &fc, nil
+	}
+
+	// Reject unknown keys so typos don't silently fall back to defaults
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&fc); err != nil {
+		var typeErr *yaml.TypeError
+		if errors.As(err, &typeErr) {
+			// Every other value was still decoded
+			return &fc, err
+		}
+		return nil, err
+	}
+
+	return &fc, nil
+}
+
+// apply copies every value set in the file onto config
+func (fc *fileConfig) apply(config *Config) {
+	scoring := fc.Scoring
+
+	if scoring.Review != nil {
+		config.ReviewPoint = *scoring.Review
+		config.ApprovedReviewPoint = *scoring.Review
+		config.ChangesRequestedReviewPoint = *scoring.Review
+		config.CommentedReviewPoint = *scoring.Review
+	}
+	setInt(&config.ApprovedReviewPoint, scoring.Reviews.Approved)
+	setInt(&config.ChangesRequestedReviewPoint, scoring.Reviews.ChangesRequested)
+	setInt(&config.CommentedReviewPoint, scoring.Reviews.Commented)
+	setInt(&config.DismissedReviewPoint, scoring.Reviews.Dismissed)
+	setBool(&config.RevokeDismissedReviews, scoring.Reviews.RevokeDismissed)
+	setInt(&config.PositiveEmojiPoint, scoring.PositiveEmoji)
+	setInt(&config.ConstructiveCommentPoint, scoring.ConstructiveComment)
+	setInt(&config.IssueCommentPoint, scoring.IssueComment)
+	if len(scoring.Reactions) > 0 {
+		config.ReactionPoints = scoring.Reactions
+	}
+	setInt(&config.ReactionCap, scoring.ReactionCap)
+
+	if len(fc.Emojis) > 0 {
+		config.PositiveEmojis = fc.Emojis
+	}
+	if len(fc.Bots.Patterns) > 0 {
+		config.BotPatterns = fc.Bots.Patterns
+	}
+	if len(fc.Exclude.Users) > 0 {
+		config.ExcludedUsers = fc.Exclude.Users
+	}
+	setBool(&config.ExcludeSelfActivity, fc.Exclude.SelfActivity)
+	setInt(&config.SelfActivityPercent, fc.Exclude.SelfActivityPercent)
+
+	if fc.Output.Leaderboard != "" {
+		config.LeaderboardFile = fc.Output.Leaderboard
+	}
+	if fc.Output.DataFile != "" {
+		config.DataFile = fc.Output.DataFile
+	}
+	if fc.Output.Checkpoint != "" {
+		config.CheckpointFile = fc.Output.Checkpoint
+	}
+	if fc.Output.CacheDir != "" {
+		config.CacheDir = fc.Output.CacheDir
+	}
+	if len(fc.Output.Windows) > 0 {
+		config.LeaderboardWindows = make([]LeaderboardWindow, len(fc.Output.Windows))
+		for i, window := range fc.Output.Windows {
+			config.LeaderboardWindows[i] = LeaderboardWindow{Title: strings.TrimSpace(window.Title), Days: window.Days}
+		}
+	}
+	if fc.Output.Storage != "" {
+		config.StorageBackend = strings.ToLower(strings.TrimSpace(fc.Output.Storage))
+	}
+	if fc.Output.Branch != "" {
+		config.DataBranch = strings.TrimSpace(fc.Output.Branch)
+	}
+	if fc.Output.Remote != "" {
+		config.DataRemote = strings.TrimSpace(fc.Output.Remote)
+	}
+
+	setBool(&config.IncrementalUpdate, fc.IncrementalUpdate)
+	setBool(&config.EventMode, fc.EventMode)
+	setInt(&config.Concurrency, fc.Concurrency)
+	if fc.Since != "" {
+		config.Since, _ = parseDate(string(fc.Since), false)
+	}
+	if fc.Until != "" {
+		config.Until, _ = parseDate(string(fc.Until), true)
+	}
+	if fc.Fetcher != "" {
+		config.Fetcher = strings.ToLower(strings.TrimSpace(fc.Fetcher))
+	}
+	if fc.OnScoringChange != "" {
+		config.OnScoringChange = strings.ToLower(strings.TrimSpace(fc.OnScoringChange))
+	}
+}
+
+// fileLines locates the keys and values of a configuration file, so
+// problems with them can point at the line to fix
+type fileLines struct {
+	path  string
+	doc   *yaml.Node
+	keys  map[string]int     // Line of every key path, such as "scoring.reviews.approved" or "emojis[1]"
+	nodes map[int][]lineNode // Keys and values starting on every line, outermost first
+}
+
+// lineNode is a key or value node of a configuration file and its key path
+type lineNode struct {
+	path string
+	key  bool
+	node *yaml.Node
+}
+
+// indexLines records the line of every key and value in a configuration
+// document. A document that doesn't parse has none.
+func indexLines(path string, data []byte) fileLines {
+	lines := fileLines{path: path, doc: &yaml.Node{}, keys: make(map[string]int), nodes: make(map[int][]lineNode)}
+	if err := yaml.Unmarshal(data, lines.doc); err == nil && len(lines.doc.Content) > 0 {
+		lines.walk("", lines.doc.Content[0])
+	}
+	return lines
+}
+
+// walk records the children of node, whose key path is path
+func (l fileLines) walk(path string, node *yaml.Node) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			child := joinKey(path, key.Value)
+			l.keys[child] = key.Line
+			l.nodes[key.Line] = append(l.nodes[key.Line], lineNode{path: child, key: true, node: key})
+			l.nodes[value.Line] = append(l.nodes[value.Line], lineNode{path: child, node: value})
+			l.walk(child, value)
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			child := fmt.Sprintf("%s[%d]", path, i)
+			l.keys[child] = item.Line
+			l.nodes[item.Line] = append(l.nodes[item.Line], lineNode{path: child, node: item})
+			l.walk(child, item)
+		}
+	}
+}
+
+var typeErrorLine = regexp.MustCompile(`^line (\d+): (.*)$`)
+
+// describe rewrites a yaml decoding error as "file:line: key: problem". It
+// also returns the key path of the value that failed to decode, if any.
+func (l fileLines) describe(msg string) (string, string) {
+	groups := typeErrorLine.FindStringSubmatch(msg)
+	if groups == nil {
+		return l.path + ": " + cleanTypeError(msg), ""
+	}
+	line, _ := strconv.Atoi(groups[1])
+	location := fmt.Sprintf("%s:%d: ", l.path, line)
+
+	// An unknown key is reported at the mapping holding it
+	if field := unknownFieldPattern.FindStringSubmatch(groups[2]); field != nil {
+		for _, candidate := range l.nodes[line] {
+			if candidate.key && candidate.node.Value == field[1] {
+				if mapping := strings.TrimSuffix(strings.TrimSuffix(candidate.path, field[1]), "."); mapping != "" {
+					location += mapping + ": "
+				}
+				break
+			}
+		}
+		return location + cleanTypeError(groups[2]), ""
+	}
+
+	key := l.valueAt(line, groups[2])
+	if key != "" {
+		location += key + ": "
+	}
+	return location + cleanTypeError(groups[2]), key
+}
+
+// valueAt returns the key path of the value on line a decoding error refers
+// to: the innermost one of the type and value it names, or else the innermost
+func (l fileLines) valueAt(line int, msg string) string {
+	var values []lineNode
+	for _, candidate := range l.nodes[line] {
+		if !candidate.key {
+			values = append(values, candidate)
+		}
+	}
+	if len(values) == 0 {
+		return ""
+	}
+
+	if groups := cannotUnmarshal.FindStringSubmatch(msg); groups != nil {
+		// yaml shortens long values to their first 7 characters
+		value := strings.TrimSuffix(groups[2], "...")
+		for i := len(values) - 1; i >= 0; i-- {
+			node := values[i].node
+			if node.ShortTag() == "!!"+groups[1] && strings.HasPrefix(node.Value, value) {
+				return values[i].path
+			}
+		}
+	}
+	return values[len(values)-1].path
+}
+
+// decodeWithout decodes the file again, leaving out the values at the
+// failed key paths
+func (l fileLines) decodeWithout(failed map[string]bool) *fileConfig {
+	var fc fileConfig
+	if len(l.doc.Content) > 0 {
+		prune("", l.doc.Content[0], failed)
+		// Every remaining value decodes, and unknown keys were already reported
+		_ = l.doc.Decode(&fc)
+	}
+	return &fc
+}
+
+// prune removes the children of node at the failed key paths
+func prune(path string, node *yaml.Node, failed map[string]bool) {
+	var content []*yaml.Node
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			child := joinKey(path, node.Content[i].Value)
+			if !failed[child] {
+				prune(child, node.Content[i+1], failed)
+				content = append(content, node.Content[i], node.Content[i+1])
+			}
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			child := fmt.Sprintf("%s[%d]", path, i)
+			if !failed[child] {
+				prune(child, item, failed)
+				content = append(content, item)
+			}
+		}
+	default:
+		return
+	}
+	node.Content = content
+}
+
+// joinKey returns the key path of key within the mapping at path
+func joinKey(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// Configuration file keys of every setting, by the name problems describe it
+// with, in the order they are applied. The review key sets every per-state
+// review point.
+var fileSettings = []struct {
+	name string
+	key  string
+}{
+	{"review point", "scoring.review"},
+	{"approved review point", "scoring.review"},
+	{"changes requested review point", "scoring.review"},
+	{"commented review point", "scoring.review"},
+	{"approved review point", "scoring.reviews.approved"},
+	{"changes requested review point", "scoring.reviews.changes_requested"},
+	{"commented review point", "scoring.reviews.commented"},
+	{"dismissed review point", "scoring.reviews.dismissed"},
+	{"revoke dismissed reviews", "scoring.reviews.revoke_dismissed"},
+	{"positive emoji point", "scoring.positive_emoji"},
+	{"constructive comment point", "scoring.constructive_comment"},
+	{"issue comment point", "scoring.issue_comment"},
+	{"reaction cap", "scoring.reaction_cap"},
+	{"exclude self activity", "exclude.self_activity"},
+	{"self activity percent", "exclude.self_activity_percent"},
+	{"concurrency", "concurrency"},
+	{"fetcher", "fetcher"},
+	{"on scoring change", "on_scoring_change"},
+	{"incremental update", "incremental_update"},
+	{"event mode", "event_mode"},
+	{"since", "since"},
+	{"until", "until"},
+	{"leaderboard file", "output.leaderboard"},
+	{"data file", "output.data_file"},
+	{"storage backend", "output.storage"},
+	{"checkpoint file", "output.checkpoint"},
+	{"cache dir", "output.cache_dir"},
+	{"data branch", "output.branch"},
+	{"data remote", "output.remote"},
+}
+
+// Configuration file keys of the list settings validated by name and index
+var fileLists = []struct {
+	name string
+	key  string
+}{
+	{"emojis", "emojis"},
+	{"bot patterns", "bots.patterns"},
+	{"excluded users", "exclude.users"},
+	{"leaderboard windows", "output.windows"},
+}
+
+// sources maps the name of every setting the file sets, as used in problem
+// descriptions, to the file, line and key that set it
+func (l fileLines) sources() map[string]string {
+	sources := make(map[string]string)
+	source := func(key string) string {
+		return fmt.Sprintf("%s:%d: %s", l.path, l.keys[key], key)
+	}
+
+	for _, setting := range fileSettings {
+		if _, ok := l.keys[setting.key]; ok {
+			sources[setting.name] = source(setting.key)
+		}
+	}
+
+	for key := range l.keys {
+		if reaction, ok := strings.CutPrefix(key, "scoring.reactions."); ok {
+			sources["reactions["+reaction+"]"] = source(key)
+		}
+		for _, list := range fileLists {
+			index, ok := strings.CutPrefix(key, list.key+"[")
+			if ok && !strings.Contains(index, ".") {
+				sources[list.name+"["+index] = source(key)
+			}
+		}
+	}
+
+	return sources
+}
+
+// field returns where a setting was set in the configuration file, or its
+// name when it wasn't
+func (c Config) field(name string) string {
+	if source, ok := c.sources[name]; ok {
+		return source
+	}
+	return name
+}
+
+// setBy reports whether a setting was set by the given configuration file key
+func (c Config) setBy(name, key string) bool {
+	return strings.HasSuffix(c.sources[name], ": "+key)
+}
+
+// forget drops the configuration file locations of settings, and of every
+// entry of list settings, overridden by environment variables
+func (c *Config) forget(names []string) {
+	for source := range c.sources {
+		for _, name := range names {
+			if source == name || strings.HasPrefix(source, name+"[") {
+				delete(c.sources, source)
+			}
+		}
+	}
+}
+
+var (
+	unknownFieldPattern = regexp.MustCompile(`field (\S+) not found in type .*`)
+	cannotUnmarshal     = regexp.MustCompile("cannot unmarshal !!(\\w+)(?: `(.*)`)? into (\\S+)")
+)
+
+// Friendly names for the Go types a configuration value can decode into
+var typeNames = map[string]string{
+	"int":            "integer",
+	"bool":           "boolean",
+	"[]string":       "list",
+	"map[string]int": "mapping of integers",
+	"string":         "string",
+}
+
+// cleanTypeError rewrites a yaml decoding error without Go type details
+func cleanTypeError(msg string) string {
+	msg = unknownFieldPattern.ReplaceAllString(msg, "unknown key \"$1\"")
+	return cannotUnmarshal.ReplaceAllStringFunc(msg, func(m string) string {
+		groups := cannotUnmarshal.FindStringSubmatch(m)
+		value := groups[2]
+		if value == "" {
+			value = "a " + strings.NewReplacer("seq", "list", "map", "mapping").Replace(groups[1])
+		} else {
+			value = fmt.Sprintf("%q", value)
+		}
+		expected := strings.TrimPrefix(groups[3], "*")
+		if name, ok := typeNames[expected]; ok {
+			expected = name
+		}
+		return fmt.Sprintf("%s is not a valid %s", value, expected)
+	})
+}
+
+func setInt(target *int, val *int) {
+	if val != nil {
+		*target = *val
+	}
+}
+
+func setBool(target *bool, val *bool) {
+	if val != nil {
+		*target = *val
+	}
+}
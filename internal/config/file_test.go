@@ -0,0 +1,358 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	return path
+}
+
+func TestLoadFileYAML(t *testing.T) {
+	path := writeConfigFile(t, "reviewer-karma.yml", `
+scoring:
+  review: 2
+  reviews:
+    approved: 5
+    revoke_dismissed: true
+  positive_emoji: 3
+emojis: ["👍", "🦄"]
+bots:
+  patterns: ["-ci"]
+exclude:
+  users: [alice]
+output:
+  leaderboard: docs/LEADERBOARD.md
+  data_file: .github/karma.json
+incremental_update: true
+event_mode: false
+fetcher: GraphQL
+`)
+
+	config, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to load config file: %v", err)
+	}
+
+	if config.ApprovedReviewPoint != 5 {
+		t.Errorf("Expected ApprovedReviewPoint to be 5, got %d", config.ApprovedReviewPoint)
+	}
+
+	if config.CommentedReviewPoint != 2 {
+		t.Errorf("Expected CommentedReviewPoint to inherit 2, got %d", config.CommentedReviewPoint)
+	}
+
+	if config.PositiveEmojiPoint != 3 {
+		t.Errorf("Expected PositiveEmojiPoint to be 3, got %d", config.PositiveEmojiPoint)
+	}
+
+	if config.ConstructiveCommentPoint != 1 {
+		t.Errorf("Expected ConstructiveCommentPoint to keep default 1, got %d", config.ConstructiveCommentPoint)
+	}
+
+	if !config.RevokeDismissedReviews || !config.IncrementalUpdate {
+		t.Error("Expected RevokeDismissedReviews and IncrementalUpdate to be true")
+	}
+
+	if config.EventMode {
+		t.Error("Expected EventMode to be false")
+	}
+
+	if config.Fetcher != FetcherGraphQL {
+		t.Errorf("Expected Fetcher to be %q, got %q", FetcherGraphQL, config.Fetcher)
+	}
+
+	if len(config.PositiveEmojis) != 2 || config.PositiveEmojis[1] != "🦄" {
+		t.Errorf("Unexpected PositiveEmojis: %v", config.PositiveEmojis)
+	}
+
+	if len(config.BotPatterns) != 1 || len(config.ExcludedUsers) != 1 {
+		t.Errorf("Unexpected BotPatterns %v or ExcludedUsers %v", config.BotPatterns, config.ExcludedUsers)
+	}
+
+	if config.LeaderboardFile != "docs/LEADERBOARD.md" || config.DataFile != ".github/karma.json" {
+		t.Errorf("Unexpected output targets: %s, %s", config.LeaderboardFile, config.DataFile)
+	}
+}
+
+func TestLoadFileJSON(t *testing.T) {
+	path := writeConfigFile(t, "reviewer-karma.json", `{
+  "scoring": {"review": 4, "constructive_comment": 2},
+  "exclude": {"users": ["bob"]}
+}`)
+
+	config, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to load config file: %v", err)
+	}
+
+	if config.ReviewPoint != 4 || config.ConstructiveCommentPoint != 2 {
+		t.Errorf("Expected review 4 and constructive 2, got %d and %d", config.ReviewPoint, config.ConstructiveCommentPoint)
+	}
+
+	if len(config.ExcludedUsers) != 1 || config.ExcludedUsers[0] != "bob" {
+		t.Errorf("Unexpected ExcludedUsers: %v", config.ExcludedUsers)
+	}
+}
+
+func TestLoadFileReportsLineNumbers(t *testing.T) {
+	path := writeConfigFile(t, "reviewer-karma.yml", `scoring:
+  review: 1
+  positive_emoji: lots
+`)
+
+	_, err := LoadFile(path)
+	if err == nil {
+		t.Fatal("Expected an error for a non-integer value")
+	}
+
+	expected := path + `:3: scoring.positive_emoji: "lots" is not a valid integer`
+	if !strings.Contains(err.Error(), expected) {
+		t.Errorf("Expected error to contain %q, got: %v", expected, err)
+	}
+}
+
+func TestLoadFileWindow(t *testing.T) {
+	path := writeConfigFile(t, "reviewer-karma.yml", `since: 2024-01-01
+until: "2024-03-31"
+`)
+
+	config, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to load config file: %v", err)
+	}
+
+	if !config.Since.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected Since to be the start of 2024-01-01, got %v", config.Since)
+	}
+	if !config.Until.Equal(time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected Until to be the end of 2024-03-31, got %v", config.Until)
+	}
+
+	path = writeConfigFile(t, "invalid.yml", `incremental_update: false
+since: last tuesday
+`)
+	if _, err := LoadFile(path); err == nil || !strings.Contains(err.Error(), path+`:2: since: "last tuesday" is not a valid date`) {
+		t.Errorf("Expected an invalid date error at line 2, got: %v", err)
+	}
+}
+
+func TestLoadFileLeaderboardWindows(t *testing.T) {
+	path := writeConfigFile(t, "reviewer-karma.yml", `output:
+  windows:
+    - title: This Week
+      days: 7
+    - days: 90
+    - title: All Time
+`)
+
+	config, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to load config file: %v", err)
+	}
+
+	names := make([]string, len(config.LeaderboardWindows))
+	for i, window := range config.LeaderboardWindows {
+		names[i] = window.Name()
+	}
+	if strings.Join(names, ", ") != "This Week, Last 90 Days, All Time" {
+		t.Errorf("Unexpected windows: %v", names)
+	}
+	if config.LeaderboardWindows[0].Days != 7 || config.LeaderboardWindows[2].Days != 0 {
+		t.Errorf("Unexpected window days: %+v", config.LeaderboardWindows)
+	}
+}
+
+func TestLoadFileReportsEveryProblem(t *testing.T) {
+	path := writeConfigFile(t, "reviewer-karma.yml", `scoring:
+  reviews:
+    approved: 5000
+  positive_emoji: lots
+  reactions:
+    heart: 2
+    tada: 1
+emojis: ["👍", " "]
+concurrency: 99
+`)
+
+	_, err := LoadFile(path)
+
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Expected a ValidationError, got: %v", err)
+	}
+
+	expected := []string{
+		path + `:4: scoring.positive_emoji: "lots" is not a valid integer`,
+		path + `:3: scoring.reviews.approved: 5000 must be between 0 and 1000`,
+		path + `:7: scoring.reactions.tada: unknown reaction "tada", expected one of +1, -1, laugh, confused, heart, hooray, rocket, eyes`,
+		path + `:9: concurrency: 99 must be between 1 and 16`,
+		path + `:8: emojis[1]: must not be empty`,
+	}
+	if !reflect.DeepEqual(validationErr.Problems, expected) {
+		t.Errorf("Expected problems:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(validationErr.Problems, "\n"))
+	}
+}
+
+func TestLoadFileLocatesTypeErrors(t *testing.T) {
+	path := writeConfigFile(t, "reviewer-karma.yml", `output:
+  windows:
+    - title: [a, b]
+      days: seven
+concurrency:
+  max: 4
+bots: {patterns: [x], ignore: true}
+`)
+
+	_, err := LoadFile(path)
+
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Expected a ValidationError, got: %v", err)
+	}
+
+	expected := []string{
+		path + `:3: output.windows[0].title: a list is not a valid string`,
+		path + `:4: output.windows[0].days: "seven" is not a valid integer`,
+		path + `:6: concurrency: a mapping is not a valid integer`,
+		path + `:7: bots: unknown key "ignore"`,
+	}
+	if !reflect.DeepEqual(validationErr.Problems, expected) {
+		t.Errorf("Expected problems:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(validationErr.Problems, "\n"))
+	}
+}
+
+func TestLoadReportsFileAndEnvironmentProblems(t *testing.T) {
+	path := writeConfigFile(t, "reviewer-karma.yml", `scoring:
+  positive_emoji: lots
+  constructive_comment: -1
+fetcher: soap
+`)
+
+	os.Setenv("CONFIG_FILE", path)
+	os.Setenv("INCREMENTAL_UPDATE", "yes")
+	os.Setenv("FETCHER", "carrier-pigeon")
+	defer os.Unsetenv("CONFIG_FILE")
+	defer os.Unsetenv("INCREMENTAL_UPDATE")
+	defer os.Unsetenv("FETCHER")
+
+	_, err := Load()
+
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Expected a ValidationError, got: %v", err)
+	}
+
+	// The fetcher set by the environment is no longer the file's problem
+	expected := []string{
+		path + `:2: scoring.positive_emoji: "lots" is not a valid integer`,
+		`INCREMENTAL_UPDATE: "yes" must be "true" or "false"`,
+		path + `:3: scoring.constructive_comment: -1 must be between 0 and 1000`,
+		`fetcher: "carrier-pigeon" must be "rest" or "graphql"`,
+	}
+	if !reflect.DeepEqual(validationErr.Problems, expected) {
+		t.Errorf("Expected problems:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(validationErr.Problems, "\n"))
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeConfigFile(t, "reviewer-karma.yml", `scoring:
+  review: 3
+  positive_emoji: 4
+`)
+
+	os.Setenv("CONFIG_FILE", path)
+	os.Setenv("POSITIVE_EMOJI_POINT", "7")
+	defer os.Unsetenv("CONFIG_FILE")
+	defer os.Unsetenv("POSITIVE_EMOJI_POINT")
+
+	config, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if config.ReviewPoint != 3 {
+		t.Errorf("Expected ReviewPoint from file to be 3, got %d", config.ReviewPoint)
+	}
+
+	if config.PositiveEmojiPoint != 7 {
+		t.Errorf("Expected environment to override PositiveEmojiPoint to 7, got %d", config.PositiveEmojiPoint)
+	}
+
+	if config.ConfigFile != path {
+		t.Errorf("Expected ConfigFile to be %s, got %s", path, config.ConfigFile)
+	}
+}
+
+func TestLoadReviewPointPrecedence(t *testing.T) {
+	path := writeConfigFile(t, "reviewer-karma.yml", `scoring:
+  review: 3
+  reviews:
+    approved: 5
+    commented: -2
+`)
+
+	os.Setenv("CONFIG_FILE", path)
+	os.Setenv("REVIEW_POINT", "7")
+	os.Setenv("CHANGES_REQUESTED_REVIEW_POINT", "9")
+	defer os.Unsetenv("CONFIG_FILE")
+	defer os.Unsetenv("REVIEW_POINT")
+	defer os.Unsetenv("CHANGES_REQUESTED_REVIEW_POINT")
+
+	config, err := Load()
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Expected a validation error for the negative commented points, got %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		got      int
+		expected int
+	}{
+		{"ReviewPoint", config.ReviewPoint, 7},
+		{"ApprovedReviewPoint", config.ApprovedReviewPoint, 5},
+		{"ChangesRequestedReviewPoint", config.ChangesRequestedReviewPoint, 9},
+		{"CommentedReviewPoint", config.CommentedReviewPoint, -2},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.expected {
+			t.Errorf("Expected %s to be %d, got %d", tt.name, tt.expected, tt.got)
+		}
+	}
+
+	// The file still sets the commented points, so the problem points at it
+	expected := path + ":5: scoring.reviews.commented"
+	if len(validationErr.Problems) != 1 || !strings.HasPrefix(validationErr.Problems[0], expected) {
+		t.Errorf("Expected one problem at %s, got %v", expected, validationErr.Problems)
+	}
+}
+
+func TestLoadInvalidEnvironment(t *testing.T) {
+	os.Setenv("REVIEW_POINT", "abc")
+	defer os.Unsetenv("REVIEW_POINT")
+
+	if _, err := Load(); err == nil {
+		t.Error("Expected an error for a non-integer REVIEW_POINT")
+	}
+}
+
+func TestLoadMissingExplicitFile(t *testing.T) {
+	os.Setenv("CONFIG_FILE", filepath.Join(t.TempDir(), "missing.yml"))
+	defer os.Unsetenv("CONFIG_FILE")
+
+	if _, err := Load(); err == nil {
+		t.Error("Expected an error for a missing CONFIG_FILE")
+	}
+}
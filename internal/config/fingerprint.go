@@ -0,0 +1,74 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"slices"
+)
+
+// Policies for an incremental update whose stored data was scored with a
+// different configuration
+const (
+	ScoringChangeRescore = "rescore" // Re-score the stored events before updating
+	ScoringChangeFail    = "fail"    // Stop and ask for an explicit rescore
+)
+
+// scoringSettings holds the settings that affect the points awarded.
+// Output paths and update modes are left out.
+type scoringSettings struct {
+	ApprovedReviewPoint         int            `json:"approved_review_point"`
+	ChangesRequestedReviewPoint int            `json:"changes_requested_review_point"`
+	CommentedReviewPoint        int            `json:"commented_review_point"`
+	DismissedReviewPoint        int            `json:"dismissed_review_point"`
+	RevokeDismissedReviews      bool           `json:"revoke_dismissed_reviews"`
+	PositiveEmojiPoint          int            `json:"positive_emoji_point"`
+	ConstructiveCommentPoint    int            `json:"constructive_comment_point"`
+	IssueCommentPoint           int            `json:"issue_comment_point"`
+	ExcludeSelfActivity         bool           `json:"exclude_self_activity"`
+	SelfActivityPercent         int            `json:"self_activity_percent"`
+	ReactionPoints              map[string]int `json:"reaction_points"`
+	ReactionCap                 int            `json:"reaction_cap"`
+	PositiveEmojis              []string       `json:"positive_emojis"`
+	BotPatterns                 []string       `json:"bot_patterns"`
+	ExcludedUsers               []string       `json:"excluded_users"`
+}
+
+// ScoringFingerprint returns a hash of the settings that affect scoring, so
+// stored points can be checked against the configuration they were scored with
+func (c Config) ScoringFingerprint() string {
+	settings := scoringSettings{
+		ApprovedReviewPoint:         c.ApprovedReviewPoint,
+		ChangesRequestedReviewPoint: c.ChangesRequestedReviewPoint,
+		CommentedReviewPoint:        c.CommentedReviewPoint,
+		DismissedReviewPoint:        c.DismissedReviewPoint,
+		RevokeDismissedReviews:      c.RevokeDismissedReviews,
+		PositiveEmojiPoint:          c.PositiveEmojiPoint,
+		ConstructiveCommentPoint:    c.ConstructiveCommentPoint,
+		IssueCommentPoint:           c.IssueCommentPoint,
+		ExcludeSelfActivity:         c.ExcludeSelfActivity,
+		SelfActivityPercent:         c.SelfActivityPercent,
+		ReactionPoints:              c.ReactionPoints,
+		ReactionCap:                 c.ReactionCap,
+		PositiveEmojis:              sortedCopy(c.PositiveEmojis),
+		BotPatterns:                 sortedCopy(c.BotPatterns),
+		ExcludedUsers:               sortedCopy(c.ExcludedUsers),
+	}
+
+	// The percentage only applies when self activity is kept
+	if settings.ExcludeSelfActivity {
+		settings.SelfActivityPercent = 0
+	}
+
+	// Map keys are marshaled in sorted order, so the encoding is stable
+	data, _ := json.Marshal(settings)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// sortedCopy returns a sorted copy of a list whose order doesn't matter
+func sortedCopy(values []string) []string {
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	return sorted
+}
@@ -0,0 +1,35 @@
+package config
+
+import "testing"
+
+func TestScoringFingerprint(t *testing.T) {
+	base := defaultConfig
+	base.BotPatterns = []string{"[bot]", "-ci"}
+
+	reordered := base
+	reordered.BotPatterns = []string{"-ci", "[bot]"}
+
+	moved := base
+	moved.LeaderboardFile = "docs/REVIEWERS.md"
+	moved.IncrementalUpdate = true
+
+	reweighted := base
+	reweighted.ApprovedReviewPoint = 5
+
+	tests := []struct {
+		name     string
+		config   Config
+		expected bool // Whether the fingerprint matches base
+	}{
+		{"same config", base, true},
+		{"reordered list", reordered, true},
+		{"output settings", moved, true},
+		{"changed points", reweighted, false},
+	}
+
+	for _, test := range tests {
+		if got := test.config.ScoringFingerprint() == base.ScoringFingerprint(); got != test.expected {
+			t.Errorf("%s: expected match to be %v, got %v", test.name, test.expected, got)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MaxPoints is the largest number of points a single rule may award
+const MaxPoints = 1000
+
+// MaxConcurrency is the largest number of pull requests fetched at the same
+// time. GitHub's secondary rate limits punish many concurrent requests.
+const MaxConcurrency = 16
+
+// Reaction types supported by GitHub
+var validReactions = []string{"+1", "-1", "laugh", "confused", "heart", "hooray", "rocket", "eyes"}
+
+// ValidationError lists every invalid configuration value
+type ValidationError struct {
+	Problems []string
+}
+
+// Error returns all problems, one per line
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// Validate checks every field and reports all invalid values at once
+func (c Config) Validate() error {
+	if problems := c.problems(); len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// problems returns a description of every invalid field
+func (c Config) problems() []string {
+	var problems []string
+
+	points := []struct {
+		name  string
+		value int
+	}{
+		{"review point", c.ReviewPoint},
+		{"approved review point", c.ApprovedReviewPoint},
+		{"changes requested review point", c.ChangesRequestedReviewPoint},
+		{"commented review point", c.CommentedReviewPoint},
+		{"positive emoji point", c.PositiveEmojiPoint},
+		{"constructive comment point", c.ConstructiveCommentPoint},
+		{"issue comment point", c.IssueCommentPoint},
+	}
+
+	for _, p := range points {
+		if p.value < 0 || p.value > MaxPoints {
+			problems = append(problems, fmt.Sprintf("%s: %d must be between 0 and %d", c.field(p.name), p.value, MaxPoints))
+		}
+	}
+
+	// Dismissed reviews may be penalized
+	if c.DismissedReviewPoint < -MaxPoints || c.DismissedReviewPoint > MaxPoints {
+		problems = append(problems, fmt.Sprintf("%s: %d must be between %d and %d", c.field("dismissed review point"), c.DismissedReviewPoint, -MaxPoints, MaxPoints))
+	}
+
+	for _, reaction := range sortedKeys(c.ReactionPoints) {
+		field := "reactions"
+		if source, ok := c.sources["reactions["+reaction+"]"]; ok {
+			field = source
+		}
+		if !slices.Contains(validReactions, reaction) {
+			problems = append(problems, fmt.Sprintf("%s: unknown reaction %q, expected one of %s", field, reaction, strings.Join(validReactions, ", ")))
+		}
+		if value := c.ReactionPoints[reaction]; value < 0 || value > MaxPoints {
+			problems = append(problems, fmt.Sprintf("%s: %d must be between 0 and %d", c.field("reactions["+reaction+"]"), value, MaxPoints))
+		}
+	}
+
+	if c.SelfActivityPercent < 0 || c.SelfActivityPercent > 100 {
+		problems = append(problems, fmt.Sprintf("%s: %d must be between 0 and 100", c.field("self activity percent"), c.SelfActivityPercent))
+	}
+
+	if c.Concurrency < 1 || c.Concurrency > MaxConcurrency {
+		problems = append(problems, fmt.Sprintf("%s: %d must be between 1 and %d", c.field("concurrency"), c.Concurrency, MaxConcurrency))
+	}
+
+	if c.Fetcher != FetcherREST && c.Fetcher != FetcherGraphQL {
+		problems = append(problems, fmt.Sprintf("%s: %q must be %q or %q", c.field("fetcher"), c.Fetcher, FetcherREST, FetcherGraphQL))
+	}
+
+	if c.ReactionCap < 0 {
+		problems = append(problems, fmt.Sprintf("%s: %d must not be negative", c.field("reaction cap"), c.ReactionCap))
+	}
+
+	problems = append(problems, c.blankEntries("emojis", c.PositiveEmojis)...)
+	problems = append(problems, c.blankEntries("bot patterns", c.BotPatterns)...)
+	problems = append(problems, c.blankEntries("excluded users", c.ExcludedUsers)...)
+
+	if c.OnScoringChange != ScoringChangeRescore && c.OnScoringChange != ScoringChangeFail {
+		problems = append(problems, fmt.Sprintf("%s: %q must be %q or %q", c.field("on scoring change"), c.OnScoringChange, ScoringChangeRescore, ScoringChangeFail))
+	}
+
+	if strings.TrimSpace(c.LeaderboardFile) == "" {
+		problems = append(problems, c.field("leaderboard file")+": must not be empty")
+	}
+
+	if strings.TrimSpace(c.DataFile) == "" {
+		problems = append(problems, c.field("data file")+": must not be empty")
+	}
+
+	switch c.StorageBackend {
+	case StorageJSON:
+	case StorageSQLite:
+		if strings.EqualFold(filepath.Ext(c.DataFile), ".json") {
+			problems = append(problems, fmt.Sprintf("%s: %q is a JSON path, but the sqlite backend writes a database there", c.field("data file"), c.DataFile))
+		}
+	case StorageGit:
+		if strings.TrimSpace(c.DataBranch) == "" {
+			problems = append(problems, c.field("data branch")+": must not be empty")
+		}
+		if filepath.IsAbs(c.DataFile) || strings.HasPrefix(filepath.Clean(c.DataFile), "..") {
+			problems = append(problems, fmt.Sprintf("%s: %q must be a relative path within the data branch", c.field("data file"), c.DataFile))
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("%s: %q must be %q, %q or %q", c.field("storage backend"), c.StorageBackend, StorageJSON, StorageSQLite, StorageGit))
+	}
+
+	if c.LeaderboardFile != "" && c.LeaderboardFile == c.DataFile {
+		problems = append(problems, fmt.Sprintf("%s: %q must differ from the leaderboard file", c.field("data file"), c.DataFile))
+	}
+
+	if strings.TrimSpace(c.CheckpointFile) == "" {
+		problems = append(problems, c.field("checkpoint file")+": must not be empty")
+	} else if c.CheckpointFile == c.LeaderboardFile || c.CheckpointFile == c.DataFile {
+		problems = append(problems, fmt.Sprintf("%s: %q must differ from the leaderboard and data files", c.field("checkpoint file"), c.CheckpointFile))
+	}
+
+	if !c.Since.IsZero() && !c.Until.IsZero() && !c.Since.Before(c.Until) {
+		problems = append(problems, fmt.Sprintf("%s: %s must be after since (%s)", c.field("until"), c.Until.Format(time.RFC3339), c.Since.Format(time.RFC3339)))
+	}
+	if c.IncrementalUpdate && (!c.Since.IsZero() || !c.Until.IsZero()) {
+		problems = append(problems, "since/until: only apply to full recreation, incremental updates keep all-time totals")
+	}
+
+	names := make(map[string]bool)
+	for i, window := range c.LeaderboardWindows {
+		if window.Days < 0 {
+			problems = append(problems, fmt.Sprintf("%s: %d days must not be negative", c.field(fmt.Sprintf("leaderboard windows[%d]", i)), window.Days))
+		}
+		if names[window.Name()] {
+			problems = append(problems, fmt.Sprintf("%s: %q is used by more than one window", c.field(fmt.Sprintf("leaderboard windows[%d]", i)), window.Name()))
+		}
+		names[window.Name()] = true
+	}
+	if len(c.LeaderboardWindows) > 0 && (!c.Since.IsZero() || !c.Until.IsZero()) {
+		problems = append(problems, "leaderboard windows: can't be combined with since/until, which limit the points every window counts")
+	}
+
+	if c.CacheDir != "" && (c.CacheDir == c.LeaderboardFile || c.CacheDir == c.DataFile || c.CacheDir == c.CheckpointFile) {
+		problems = append(problems, fmt.Sprintf("%s: %q must differ from the leaderboard, data and checkpoint files", c.field("cache dir"), c.CacheDir))
+	}
+
+	return problems
+}
+
+// sortedKeys returns the keys of a map in sorted order
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// blankEntries reports empty entries in a list setting
+func (c Config) blankEntries(name string, values []string) []string {
+	var problems []string
+	for i, value := range values {
+		if strings.TrimSpace(value) == "" {
+			problems = append(problems, c.field(fmt.Sprintf("%s[%d]", name, i))+": must not be empty")
+		}
+	}
+	return problems
+}
@@ -0,0 +1,346 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateDefaults(t *testing.T) {
+	if err := defaultConfig.Validate(); err != nil {
+		t.Errorf("Expected default config to be valid, got: %v", err)
+	}
+}
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+	config := defaultConfig
+	config.ReviewPoint = -1
+	config.PositiveEmojiPoint = MaxPoints + 1
+	config.DismissedReviewPoint = -5
+	config.BotPatterns = []string{"[bot]", " "}
+	config.LeaderboardFile = ""
+
+	err := config.Validate()
+
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Expected a ValidationError, got: %v", err)
+	}
+
+	if len(validationErr.Problems) != 4 {
+		t.Errorf("Expected 4 problems, got %d: %v", len(validationErr.Problems), validationErr.Problems)
+	}
+}
+
+func TestLoadRejectsGarbageEnvironment(t *testing.T) {
+	os.Setenv("REVIEW_POINT", "abc")
+	os.Setenv("CONSTRUCTIVE_COMMENT_POINT", "-2")
+	os.Setenv("INCREMENTAL_UPDATE", "yes")
+	defer func() {
+		os.Unsetenv("REVIEW_POINT")
+		os.Unsetenv("CONSTRUCTIVE_COMMENT_POINT")
+		os.Unsetenv("INCREMENTAL_UPDATE")
+	}()
+
+	_, err := Load()
+
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Expected a ValidationError, got: %v", err)
+	}
+
+	if len(validationErr.Problems) != 3 {
+		t.Errorf("Expected 3 problems, got %d: %v", len(validationErr.Problems), validationErr.Problems)
+	}
+}
+
+func TestLoadFileRejectsUnknownKeys(t *testing.T) {
+	path := writeConfigFile(t, "reviewer-karma.yml", `scoring:
+  reveiw: 2
+outputs:
+  leaderboard: LEADERS.md
+`)
+
+	_, err := LoadFile(path)
+
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Expected a ValidationError, got: %v", err)
+	}
+
+	if len(validationErr.Problems) != 2 {
+		t.Fatalf("Expected 2 problems, got %d: %v", len(validationErr.Problems), validationErr.Problems)
+	}
+
+	if validationErr.Problems[0] != path+`:2: scoring: unknown key "reveiw"` {
+		t.Errorf("Unexpected first problem: %s", validationErr.Problems[0])
+	}
+	if validationErr.Problems[1] != path+`:3: unknown key "outputs"` {
+		t.Errorf("Unexpected second problem: %s", validationErr.Problems[1])
+	}
+}
+
+func TestLoadReactionPoints(t *testing.T) {
+	os.Setenv("REACTION_POINTS", "+1=1, rocket=2")
+	os.Setenv("REACTION_CAP", "3")
+	defer os.Unsetenv("REACTION_POINTS")
+	defer os.Unsetenv("REACTION_CAP")
+
+	config, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if config.ReactionPoints["+1"] != 1 || config.ReactionPoints["rocket"] != 2 {
+		t.Errorf("Unexpected ReactionPoints: %v", config.ReactionPoints)
+	}
+
+	if config.ReactionCap != 3 {
+		t.Errorf("Expected ReactionCap to be 3, got %d", config.ReactionCap)
+	}
+}
+
+func TestValidateReactionPoints(t *testing.T) {
+	config := defaultConfig
+	config.ReactionPoints = map[string]int{"thumbsup": 1, "rocket": -1}
+	config.ReactionCap = -1
+
+	var validationErr *ValidationError
+	if !errors.As(config.Validate(), &validationErr) {
+		t.Fatal("Expected a ValidationError")
+	}
+
+	if len(validationErr.Problems) != 3 {
+		t.Errorf("Expected 3 problems, got %d: %v", len(validationErr.Problems), validationErr.Problems)
+	}
+}
+
+func TestValidateOnScoringChange(t *testing.T) {
+	os.Setenv("ON_SCORING_CHANGE", "Fail")
+	defer os.Unsetenv("ON_SCORING_CHANGE")
+
+	config, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if config.OnScoringChange != ScoringChangeFail {
+		t.Errorf("Expected OnScoringChange to be %q, got %q", ScoringChangeFail, config.OnScoringChange)
+	}
+
+	config.OnScoringChange = "ignore"
+	if err := config.Validate(); err == nil {
+		t.Error("Expected an unknown policy to be rejected")
+	}
+}
+
+func TestValidateStorageBackend(t *testing.T) {
+	os.Setenv("STORAGE_BACKEND", "SQLite")
+	defer os.Unsetenv("STORAGE_BACKEND")
+
+	config, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if config.StorageBackend != StorageSQLite {
+		t.Errorf("Expected StorageBackend to be %q, got %q", StorageSQLite, config.StorageBackend)
+	}
+	if config.DataFile != ".karma-data.db" {
+		t.Errorf("Expected the sqlite backend to default DataFile to .karma-data.db, got %q", config.DataFile)
+	}
+
+	config.DataFile = "karma.JSON"
+	if err := config.Validate(); err == nil || !strings.Contains(err.Error(), "data file") {
+		t.Errorf("Expected a sqlite backend writing to a .json path to be rejected, got %v", err)
+	}
+
+	config.StorageBackend = "postgres"
+	if err := config.Validate(); err == nil {
+		t.Error("Expected an unknown storage backend to be rejected")
+	}
+}
+
+func TestLoadSQLiteDataFile(t *testing.T) {
+	path := writeConfigFile(t, "reviewer-karma.yml", `output:
+  storage: sqlite
+`)
+	config, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to load config file: %v", err)
+	}
+	if config.DataFile != ".karma-data.db" {
+		t.Errorf("Expected DataFile to default to .karma-data.db, got %q", config.DataFile)
+	}
+
+	os.Setenv("CONFIG_FILE", path)
+	os.Setenv("DATA_FILE", "karma.sqlite")
+	defer os.Unsetenv("CONFIG_FILE")
+	defer os.Unsetenv("DATA_FILE")
+
+	config, err = Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if config.DataFile != "karma.sqlite" {
+		t.Errorf("Expected an explicit DataFile to be kept, got %q", config.DataFile)
+	}
+}
+
+func TestValidateFetcher(t *testing.T) {
+	os.Setenv("FETCHER", " GraphQL")
+	defer os.Unsetenv("FETCHER")
+
+	config, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if config.Fetcher != FetcherGraphQL {
+		t.Errorf("Expected Fetcher to be %q, got %q", FetcherGraphQL, config.Fetcher)
+	}
+
+	config.Fetcher = "soap"
+	if err := config.Validate(); err == nil {
+		t.Error("Expected an unknown fetcher to be rejected")
+	}
+}
+
+func TestValidateCacheDir(t *testing.T) {
+	os.Setenv("CACHE_DIR", ".karma-cache")
+	defer os.Unsetenv("CACHE_DIR")
+
+	config, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if config.CacheDir != ".karma-cache" {
+		t.Errorf("Expected CacheDir to be .karma-cache, got %q", config.CacheDir)
+	}
+
+	config.CacheDir = config.DataFile
+	if err := config.Validate(); err == nil {
+		t.Error("Expected a cache dir at the data file to be rejected")
+	}
+}
+
+func TestValidateWindow(t *testing.T) {
+	os.Setenv("SINCE", "2024-01-01")
+	os.Setenv("UNTIL", "2024-03-31T12:00:00+02:00")
+	defer os.Unsetenv("SINCE")
+	defer os.Unsetenv("UNTIL")
+
+	config, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if !config.Since.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected Since to be 2024-01-01, got %v", config.Since)
+	}
+	if !config.Until.Equal(time.Date(2024, 3, 31, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected Until to be the given time, got %v", config.Until)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*Config)
+	}{
+		{"until before since", func(c *Config) { c.Until = c.Since.AddDate(0, 0, -1) }},
+		{"empty window", func(c *Config) { c.Until = c.Since }},
+		{"incremental update", func(c *Config) { c.IncrementalUpdate = true }},
+	}
+
+	for _, tt := range tests {
+		invalid := config
+		tt.modify(&invalid)
+		if err := invalid.Validate(); err == nil {
+			t.Errorf("Expected %s to be rejected", tt.name)
+		}
+	}
+
+	os.Setenv("SINCE", "yesterday")
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "SINCE") {
+		t.Errorf("Expected an invalid SINCE to be rejected, got: %v", err)
+	}
+}
+
+func TestValidateLeaderboardWindows(t *testing.T) {
+	os.Setenv("LEADERBOARD_WINDOWS", "This Week=7, 30,All Time=all")
+	defer os.Unsetenv("LEADERBOARD_WINDOWS")
+
+	config, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	expected := []LeaderboardWindow{{Title: "This Week", Days: 7}, {Days: 30}, {Title: "All Time"}}
+	if !reflect.DeepEqual(config.LeaderboardWindows, expected) {
+		t.Errorf("Expected windows %+v, got %+v", expected, config.LeaderboardWindows)
+	}
+	if name := config.LeaderboardWindows[1].Name(); name != "Last 30 Days" {
+		t.Errorf("Expected an untitled window to be named Last 30 Days, got %q", name)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*Config)
+	}{
+		{"negative days", func(c *Config) { c.LeaderboardWindows[1].Days = -1 }},
+		{"duplicate name", func(c *Config) { c.LeaderboardWindows[2].Title = "This Week" }},
+		{"untitled duplicate", func(c *Config) { c.LeaderboardWindows = append(c.LeaderboardWindows, LeaderboardWindow{Days: 30}) }},
+		{"since", func(c *Config) { c.Since = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC) }},
+	}
+
+	for _, tt := range tests {
+		invalid := config
+		invalid.LeaderboardWindows = slices.Clone(config.LeaderboardWindows)
+		tt.modify(&invalid)
+		if err := invalid.Validate(); err == nil {
+			t.Errorf("Expected %s to be rejected", tt.name)
+		}
+	}
+
+	os.Setenv("LEADERBOARD_WINDOWS", "week=seven")
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "LEADERBOARD_WINDOWS") {
+		t.Errorf("Expected invalid LEADERBOARD_WINDOWS to be rejected, got: %v", err)
+	}
+}
+
+func TestValidateGitStorage(t *testing.T) {
+	config := defaultConfig
+	config.StorageBackend = StorageGit
+	if err := config.Validate(); err != nil {
+		t.Errorf("Expected git storage with the default data file to be valid, got: %v", err)
+	}
+
+	config.DataFile = "/tmp/karma.json"
+	config.DataBranch = ""
+	err := config.Validate()
+	validationErr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("Expected *ValidationError, got %T: %v", err, err)
+	}
+	if len(validationErr.Problems) != 2 {
+		t.Errorf("Expected 2 problems, got %d: %v", len(validationErr.Problems), validationErr.Problems)
+	}
+}
+
+func TestValidateConcurrency(t *testing.T) {
+	tests := []struct {
+		concurrency int
+		valid       bool
+	}{
+		{1, true},
+		{MaxConcurrency, true},
+		{0, false},
+		{MaxConcurrency + 1, false},
+	}
+
+	for _, tt := range tests {
+		config := defaultConfig
+		config.Concurrency = tt.concurrency
+		if err := config.Validate(); (err == nil) != tt.valid {
+			t.Errorf("Expected concurrency %d valid=%v, got: %v", tt.concurrency, tt.valid, err)
+		}
+	}
+}
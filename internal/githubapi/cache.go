@@ -0,0 +1,194 @@
+package githubapi
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// cacheSuffix names the files of cached responses
+const cacheSuffix = ".http"
+
+// CacheTransport keeps GitHub API responses in a directory and revalidates
+// them with conditional requests. GitHub answers a request whose ETag or
+// Last-Modified still matches with 304 Not Modified, which doesn't count
+// against the rate limit, and the cached response is returned instead.
+//
+// Only GET responses with a validator are cached. Entries are keyed by URL
+// and Accept header but not by credentials, so the transport must wrap the
+// one that authenticates requests, and the directory must only be shared by
+// clients that may read the same data. Failing to read or write the cache
+// never fails a request.
+type CacheTransport struct {
+	base http.RoundTripper
+	dir  string
+
+	requests    atomic.Int64
+	notModified atomic.Int64
+}
+
+// NewCacheTransport wraps base, or http.DefaultTransport if nil, with a
+// response cache in dir, which is created when the first response is stored
+func NewCacheTransport(base http.RoundTripper, dir string) *CacheTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &CacheTransport{base: base, dir: dir}
+}
+
+// RoundTrip sends the request, conditionally if a response to it is cached
+func (t *CacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Requests that are already conditional expect to see the 304 themselves
+	if req.Method != http.MethodGet || req.Header.Get("If-None-Match") != "" || req.Header.Get("If-Modified-Since") != "" {
+		return t.base.RoundTrip(req)
+	}
+	t.requests.Add(1)
+
+	path := t.entryPath(req)
+	cached := t.load(path, req)
+
+	condReq := req
+	if cached != nil {
+		condReq = req.Clone(req.Context())
+		if etag := cached.Header.Get("ETag"); etag != "" {
+			condReq.Header.Set("If-None-Match", etag)
+		}
+		if modified := cached.Header.Get("Last-Modified"); modified != "" {
+			condReq.Header.Set("If-Modified-Since", modified)
+		}
+	}
+
+	resp, err := t.base.RoundTrip(condReq)
+	if err != nil {
+		if cached != nil {
+			cached.Body.Close()
+		}
+		return nil, err
+	}
+
+	if cached != nil && resp.StatusCode == http.StatusNotModified {
+		t.notModified.Add(1)
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		// The 304 carries the current rate limit and pagination headers
+		for key, values := range resp.Header {
+			if key != "Content-Length" {
+				cached.Header[key] = values
+			}
+		}
+		now := time.Now()
+		os.Chtimes(path, now, now) // Recently used entries survive Prune
+		return cached, nil
+	}
+
+	if cached != nil {
+		cached.Body.Close()
+	}
+	if resp.StatusCode == http.StatusOK && cacheable(resp) {
+		t.store(path, resp)
+	}
+	return resp, nil
+}
+
+// cacheable reports whether a response can be revalidated later
+func cacheable(resp *http.Response) bool {
+	if strings.Contains(resp.Header.Get("Cache-Control"), "no-store") {
+		return false
+	}
+	return resp.Header.Get("ETag") != "" || resp.Header.Get("Last-Modified") != ""
+}
+
+// entryPath returns the file that caches the response to req
+func (t *CacheTransport) entryPath(req *http.Request) string {
+	sum := sha256.Sum256([]byte(req.Header.Get("Accept") + " " + req.URL.String()))
+	return filepath.Join(t.dir, hex.EncodeToString(sum[:])+cacheSuffix)
+}
+
+// load returns the cached response at path, or nil. Unreadable entries are
+// removed so they are replaced by the next response.
+func (t *CacheTransport) load(path string, req *http.Request) *http.Response {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(data)), req)
+	if err != nil {
+		os.Remove(path)
+		return nil
+	}
+	return resp
+}
+
+// store writes resp to path, leaving resp readable. The entry is written to
+// a temporary file and renamed, so concurrent readers never see a partial
+// entry. It isn't synced: a lost entry only costs a request.
+func (t *CacheTransport) store(path string, resp *http.Response) {
+	data, err := httputil.DumpResponse(resp, true)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(t.dir, 0755); err != nil {
+		return
+	}
+	tmp, err := os.CreateTemp(t.dir, ".tmp-*")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+}
+
+// Stats returns how many requests could be served from the cache, and how
+// many of them were, because the cached response was still current
+func (t *CacheTransport) Stats() (requests, notModified int64) {
+	return t.requests.Load(), t.notModified.Load()
+}
+
+// Prune removes cached responses that were not used for longer than maxAge
+// and returns how many were removed
+func (t *CacheTransport) Prune(maxAge time.Duration) (int, error) {
+	entries, err := os.ReadDir(t.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read cache directory: %w", err)
+	}
+
+	removed := 0
+	cutoff := time.Now().Add(-maxAge)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), cacheSuffix) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || info.ModTime().After(cutoff) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(t.dir, entry.Name())); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return removed, fmt.Errorf("failed to remove cached response: %w", err)
+		}
+		removed++
+	}
+	return removed, nil
+}
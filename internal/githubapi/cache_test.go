@@ -0,0 +1,198 @@
+package githubapi
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// etagServer serves versioned bodies with ETags and answers matching
+// conditional requests with 304 Not Modified
+type etagServer struct {
+	*httptest.Server
+
+	mu          sync.Mutex
+	version     int
+	requests    int
+	notModified int
+}
+
+func newETagServer(t *testing.T, gzipped bool) *etagServer {
+	t.Helper()
+	s := &etagServer{version: 1}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.requests++
+
+		etag := `W/"v` + strconv.Itoa(s.version) + `"`
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(5000-s.requests))
+		if r.Header.Get("If-None-Match") == etag {
+			s.notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Link", `<`+r.URL.Path+`?page=2>; rel="next"`)
+		body := r.URL.Path + " version " + strconv.Itoa(s.version)
+		if gzipped {
+			w.Header().Set("Content-Encoding", "gzip")
+			gz := gzip.NewWriter(w)
+			io.WriteString(gz, body)
+			gz.Close()
+			return
+		}
+		io.WriteString(w, body)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func get(t *testing.T, client *http.Client, url string) (*http.Response, string) {
+	t.Helper()
+	resp, err := client.Get(url)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read body: %v", err)
+	}
+	return resp, string(body)
+}
+
+func TestCacheTransport(t *testing.T) {
+	for _, gzipped := range []bool{false, true} {
+		t.Run("gzip="+strconv.FormatBool(gzipped), func(t *testing.T) {
+			server := newETagServer(t, gzipped)
+			cache := NewCacheTransport(server.Client().Transport, filepath.Join(t.TempDir(), "cache"))
+			client := &http.Client{Transport: cache}
+
+			_, body := get(t, client, server.URL+"/pulls/1/reviews")
+			if body != "/pulls/1/reviews version 1" {
+				t.Fatalf("Unexpected body: %q", body)
+			}
+
+			resp, body := get(t, client, server.URL+"/pulls/1/reviews")
+			if resp.StatusCode != http.StatusOK || body != "/pulls/1/reviews version 1" {
+				t.Errorf("Expected the cached response, got %d %q", resp.StatusCode, body)
+			}
+			if server.notModified != 1 {
+				t.Errorf("Expected 1 conditional request to match, got %d", server.notModified)
+			}
+			if remaining := resp.Header.Get("X-RateLimit-Remaining"); remaining != "4998" {
+				t.Errorf("Expected the rate limit headers of the 304, got %q", remaining)
+			}
+			if !strings.Contains(resp.Header.Get("Link"), `rel="next"`) {
+				t.Errorf("Expected the cached Link header, got %q", resp.Header.Get("Link"))
+			}
+
+			// A changed resource replaces the cached response
+			server.version = 2
+			_, body = get(t, client, server.URL+"/pulls/1/reviews")
+			if body != "/pulls/1/reviews version 2" {
+				t.Errorf("Expected the changed body, got %q", body)
+			}
+			_, body = get(t, client, server.URL+"/pulls/1/reviews")
+			if body != "/pulls/1/reviews version 2" || server.notModified != 2 {
+				t.Errorf("Expected the changed body from the cache, got %q with %d matches", body, server.notModified)
+			}
+
+			requests, notModified := cache.Stats()
+			if requests != 4 || notModified != 2 {
+				t.Errorf("Expected stats of 4 requests and 2 not modified, got %d and %d", requests, notModified)
+			}
+		})
+	}
+}
+
+func TestCacheTransportSkipsUncacheable(t *testing.T) {
+	server := newETagServer(t, false)
+	dir := t.TempDir()
+	client := &http.Client{Transport: NewCacheTransport(server.Client().Transport, dir)}
+
+	for range 2 {
+		resp, err := client.Post(server.URL+"/graphql", "application/json", strings.NewReader("{}"))
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	if server.notModified != 0 {
+		t.Errorf("Expected POST requests not to be conditional, got %d matches", server.notModified)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("Expected nothing to be cached, got %d entries", len(entries))
+	}
+}
+
+func TestCacheTransportCorruptEntry(t *testing.T) {
+	server := newETagServer(t, false)
+	dir := t.TempDir()
+	cache := NewCacheTransport(server.Client().Transport, dir)
+	client := &http.Client{Transport: cache}
+
+	get(t, client, server.URL+"/pulls")
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 cached response, got %d", len(entries))
+	}
+	if err := os.WriteFile(filepath.Join(dir, entries[0].Name()), []byte("garbage"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, body := get(t, client, server.URL+"/pulls")
+	if body != "/pulls version 1" {
+		t.Errorf("Expected a corrupt entry to be refetched, got %q", body)
+	}
+	if server.notModified != 0 {
+		t.Errorf("Expected an unconditional request, got %d matches", server.notModified)
+	}
+
+	get(t, client, server.URL+"/pulls")
+	if server.notModified != 1 {
+		t.Errorf("Expected the refetched response to be cached, got %d matches", server.notModified)
+	}
+}
+
+func TestCacheTransportPrune(t *testing.T) {
+	server := newETagServer(t, false)
+	dir := t.TempDir()
+	cache := NewCacheTransport(server.Client().Transport, dir)
+	client := &http.Client{Transport: cache}
+
+	get(t, client, server.URL+"/old")
+	get(t, client, server.URL+"/recent")
+
+	old := time.Now().Add(-48 * time.Hour)
+	oldPath := cache.entryPath(httptest.NewRequest(http.MethodGet, server.URL+"/old", nil))
+	if err := os.Chtimes(oldPath, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := cache.Prune(24 * time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to prune: %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("Expected 1 removed entry, got %d", removed)
+	}
+	if _, err := os.Stat(oldPath); !os.IsNotExist(err) {
+		t.Errorf("Expected the old entry to be removed, got: %v", err)
+	}
+
+	if removed, err := NewCacheTransport(nil, filepath.Join(dir, "missing")).Prune(time.Hour); err != nil || removed != 0 {
+		t.Errorf("Expected pruning a missing cache to do nothing, got %d: %v", removed, err)
+	}
+}
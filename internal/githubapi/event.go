@@ -0,0 +1,103 @@
+package githubapi
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/google/go-github/v62/github"
+)
+
+// Trigger is the pull request activity that triggered a workflow run, read
+// from the run's webhook event payload
+type Trigger struct {
+	Event         string // Webhook event name, such as "pull_request_review"
+	Action        string // Activity type, such as "submitted" or "deleted"
+	PullRequest   PullRequest
+	Review        *Review  // The review of a pull_request_review event
+	ReviewComment *Comment // The comment of a pull_request_review_comment event
+	IssueComment  *Comment // The comment of an issue_comment event
+}
+
+// ErrNotPullRequest is returned for comment events on issues, which have no
+// pull request activity to score
+var ErrNotPullRequest = errors.New("event is not about a pull request")
+
+// ReadTrigger reads the webhook event payload at path, as set in
+// GITHUB_EVENT_PATH. It returns nil for events that aren't about pull
+// request activity, such as schedule or workflow_dispatch.
+func ReadTrigger(eventName, path string) (*Trigger, error) {
+	if !isPullRequestEvent(eventName) {
+		return nil, nil
+	}
+
+	payload, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s event payload: %w", eventName, err)
+	}
+	return ParseTrigger(eventName, payload)
+}
+
+// ParseTrigger parses a webhook event payload. It returns nil for events
+// that aren't about pull request activity.
+func ParseTrigger(eventName string, payload []byte) (*Trigger, error) {
+	if !isPullRequestEvent(eventName) {
+		return nil, nil
+	}
+
+	event, err := github.ParseWebHook(eventName, payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s event payload: %w", eventName, err)
+	}
+
+	trigger := &Trigger{Event: eventName}
+	switch e := event.(type) {
+	case *github.PullRequestEvent:
+		trigger.Action = e.GetAction()
+		trigger.PullRequest = convertPullRequest(e.GetPullRequest())
+	case *github.PullRequestTargetEvent:
+		trigger.Action = e.GetAction()
+		trigger.PullRequest = convertPullRequest(e.GetPullRequest())
+	case *github.PullRequestReviewEvent:
+		review := convertReview(e.GetReview())
+		trigger.Action = e.GetAction()
+		trigger.PullRequest = convertPullRequest(e.GetPullRequest())
+		trigger.Review = &review
+	case *github.PullRequestReviewCommentEvent:
+		comment := convertReviewComment(e.GetComment())
+		trigger.Action = e.GetAction()
+		trigger.PullRequest = convertPullRequest(e.GetPullRequest())
+		trigger.ReviewComment = &comment
+	case *github.IssueCommentEvent:
+		issue := e.GetIssue()
+		if issue == nil || !issue.IsPullRequest() {
+			return nil, ErrNotPullRequest
+		}
+		comment := convertIssueComment(e.GetComment())
+		trigger.Action = e.GetAction()
+		trigger.PullRequest = PullRequest{
+			Number:    issue.GetNumber(),
+			Title:     issue.GetTitle(),
+			Author:    issue.GetUser().GetLogin(),
+			State:     issue.GetState(),
+			CreatedAt: issue.GetCreatedAt().Time,
+			UpdatedAt: issue.GetUpdatedAt().Time,
+		}
+		trigger.IssueComment = &comment
+	}
+
+	if trigger.PullRequest.Number == 0 {
+		return nil, fmt.Errorf("%s event payload has no pull request number", eventName)
+	}
+	return trigger, nil
+}
+
+// isPullRequestEvent reports whether events of a type are about pull
+// request activity
+func isPullRequestEvent(eventName string) bool {
+	switch eventName {
+	case "pull_request", "pull_request_target", "pull_request_review", "pull_request_review_comment", "issue_comment":
+		return true
+	}
+	return false
+}
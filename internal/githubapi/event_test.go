@@ -0,0 +1,103 @@
+package githubapi
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseTrigger(t *testing.T) {
+	tests := []struct {
+		name      string
+		event     string
+		payload   string
+		prNumber  int
+		check     func(t *testing.T, trigger *Trigger)
+		expectNil bool
+		expectErr error
+	}{
+		{
+			name:     "Review",
+			event:    "pull_request_review",
+			payload:  `{"action": "submitted", "review": {"id": 9, "user": {"login": "bob"}, "state": "approved", "submitted_at": "2024-01-02T00:00:00Z"}, "pull_request": {"number": 7, "user": {"login": "alice"}, "updated_at": "2024-01-02T00:00:00Z"}}`,
+			prNumber: 7,
+			check: func(t *testing.T, trigger *Trigger) {
+				if trigger.Review == nil || trigger.Review.ID != 9 || trigger.Review.State != "APPROVED" {
+					t.Errorf("Expected approved review 9, got %+v", trigger.Review)
+				}
+				if trigger.PullRequest.Author != "alice" || trigger.PullRequest.UpdatedAt.IsZero() {
+					t.Errorf("Expected the PR author and update time, got %+v", trigger.PullRequest)
+				}
+			},
+		},
+		{
+			name:     "Review comment",
+			event:    "pull_request_review_comment",
+			payload:  `{"action": "edited", "comment": {"id": 11, "user": {"login": "carol"}, "body": "Nice 👍"}, "pull_request": {"number": 7}}`,
+			prNumber: 7,
+			check: func(t *testing.T, trigger *Trigger) {
+				if trigger.ReviewComment == nil || trigger.ReviewComment.Body != "Nice 👍" || trigger.Action != "edited" {
+					t.Errorf("Expected edited comment 11, got %+v", trigger.ReviewComment)
+				}
+			},
+		},
+		{
+			name:     "Conversation comment on a pull request",
+			event:    "issue_comment",
+			payload:  `{"action": "created", "comment": {"id": 12, "user": {"login": "dave"}}, "issue": {"number": 8, "user": {"login": "alice"}, "pull_request": {"url": "https://api.github.com/repos/o/r/pulls/8"}}}`,
+			prNumber: 8,
+			check: func(t *testing.T, trigger *Trigger) {
+				if trigger.IssueComment == nil || trigger.IssueComment.User != "dave" || trigger.PullRequest.Author != "alice" {
+					t.Errorf("Expected dave's comment on alice's PR, got %+v", trigger)
+				}
+			},
+		},
+		{
+			name:      "Conversation comment on an issue",
+			event:     "issue_comment",
+			payload:   `{"action": "created", "comment": {"id": 12}, "issue": {"number": 8}}`,
+			expectErr: ErrNotPullRequest,
+		},
+		{
+			name:     "Pull request",
+			event:    "pull_request",
+			payload:  `{"action": "closed", "pull_request": {"number": 5, "title": "Fix bug"}}`,
+			prNumber: 5,
+			check: func(t *testing.T, trigger *Trigger) {
+				if trigger.Review != nil || trigger.ReviewComment != nil || trigger.IssueComment != nil {
+					t.Errorf("Expected no review or comment, got %+v", trigger)
+				}
+			},
+		},
+		{
+			name:      "Manual run",
+			event:     "workflow_dispatch",
+			payload:   `{}`,
+			expectNil: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trigger, err := ParseTrigger(tt.event, []byte(tt.payload))
+			if tt.expectErr != nil {
+				if !errors.Is(err, tt.expectErr) {
+					t.Errorf("Expected error %v, got %v", tt.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to parse trigger: %v", err)
+			}
+			if tt.expectNil {
+				if trigger != nil {
+					t.Errorf("Expected no trigger, got %+v", trigger)
+				}
+				return
+			}
+			if trigger.PullRequest.Number != tt.prNumber {
+				t.Errorf("Expected PR #%d, got #%d", tt.prNumber, trigger.PullRequest.Number)
+			}
+			tt.check(t, trigger)
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package githubapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// FakeSource is an in-memory Source for tests. Its JSON form is the fixture
+// format read by LoadFixture.
+type FakeSource struct {
+	PullRequests           []PullRequest        `json:"pull_requests"`
+	Reviews                map[int][]Review     `json:"reviews"`
+	ReviewComments         map[int][]Comment    `json:"review_comments"`
+	IssueComments          map[int][]Comment    `json:"issue_comments"`
+	PullRequestReactions   map[int][]Reaction   `json:"pull_request_reactions"`
+	ReviewReactions        map[int64][]Reaction `json:"review_reactions"`
+	ReviewCommentReactions map[int64][]Reaction `json:"review_comment_reactions"`
+	IssueCommentReactions  map[int64][]Reaction `json:"issue_comment_reactions"`
+
+	// Errors maps a pull request number to the error returned when fetching its activity
+	Errors map[int]error `json:"-"`
+}
+
+// NewFakeSource creates an empty in-memory source
+func NewFakeSource() *FakeSource {
+	return &FakeSource{
+		Reviews:                make(map[int][]Review),
+		ReviewComments:         make(map[int][]Comment),
+		IssueComments:          make(map[int][]Comment),
+		PullRequestReactions:   make(map[int][]Reaction),
+		ReviewReactions:        make(map[int64][]Reaction),
+		ReviewCommentReactions: make(map[int64][]Reaction),
+		IssueCommentReactions:  make(map[int64][]Reaction),
+		Errors:                 make(map[int]error),
+	}
+}
+
+// LoadFixture loads a FakeSource from a JSON fixture file
+func LoadFixture(path string) (*FakeSource, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixture: %w", err)
+	}
+
+	source := NewFakeSource()
+	if err := json.Unmarshal(data, source); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal fixture: %w", err)
+	}
+
+	return source, nil
+}
+
+// AddPullRequest adds a pull request to the source
+func (s *FakeSource) AddPullRequest(pr PullRequest) {
+	s.PullRequests = append(s.PullRequests, pr)
+}
+
+// ListPullRequests lists all pull requests
+func (s *FakeSource) ListPullRequests(ctx context.Context) ([]PullRequest, error) {
+	return s.PullRequests, nil
+}
+
+// ListPullRequestsUpdatedSince lists the pull requests updated at or after
+// since, most recently updated first
+func (s *FakeSource) ListPullRequestsUpdatedSince(ctx context.Context, since time.Time) ([]PullRequest, error) {
+	var result []PullRequest
+	for _, pr := range s.PullRequests {
+		if !pr.UpdatedAt.Before(since) {
+			result = append(result, pr)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].UpdatedAt.After(result[j].UpdatedAt)
+	})
+	return result, nil
+}
+
+// ListReviews lists the reviews on a pull request
+func (s *FakeSource) ListReviews(ctx context.Context, prNumber int) ([]Review, error) {
+	if err := s.Errors[prNumber]; err != nil {
+		return nil, err
+	}
+	result := make([]Review, len(s.Reviews[prNumber]))
+	for i, review := range s.Reviews[prNumber] {
+		review.Reactions = len(s.ReviewReactions[review.ID])
+		result[i] = review
+	}
+	return result, nil
+}
+
+// ListReviewComments lists the inline review comments on a pull request
+func (s *FakeSource) ListReviewComments(ctx context.Context, prNumber int) ([]Comment, error) {
+	if err := s.Errors[prNumber]; err != nil {
+		return nil, err
+	}
+	return withReactionCounts(s.ReviewComments[prNumber], s.ReviewCommentReactions), nil
+}
+
+// ListIssueComments lists the conversation comments on a pull request
+func (s *FakeSource) ListIssueComments(ctx context.Context, prNumber int) ([]Comment, error) {
+	if err := s.Errors[prNumber]; err != nil {
+		return nil, err
+	}
+	return withReactionCounts(s.IssueComments[prNumber], s.IssueCommentReactions), nil
+}
+
+// withReactionCounts fills in each comment's reaction count, as the REST API does
+func withReactionCounts(comments []Comment, reactions map[int64][]Reaction) []Comment {
+	result := make([]Comment, len(comments))
+	for i, comment := range comments {
+		comment.Reactions = len(reactions[comment.ID])
+		result[i] = comment
+	}
+	return result
+}
+
+// ListPullRequestReactions lists the reactions on a pull request
+func (s *FakeSource) ListPullRequestReactions(ctx context.Context, prNumber int) ([]Reaction, error) {
+	if err := s.Errors[prNumber]; err != nil {
+		return nil, err
+	}
+	return s.PullRequestReactions[prNumber], nil
+}
+
+// ListReviewReactions lists the reactions on a review
+func (s *FakeSource) ListReviewReactions(ctx context.Context, reviewID int64) ([]Reaction, error) {
+	return s.ReviewReactions[reviewID], nil
+}
+
+// ListReviewCommentReactions lists the reactions on an inline review comment
+func (s *FakeSource) ListReviewCommentReactions(ctx context.Context, commentID int64) ([]Reaction, error) {
+	return s.ReviewCommentReactions[commentID], nil
+}
+
+// ListIssueCommentReactions lists the reactions on a conversation comment
+func (s *FakeSource) ListIssueCommentReactions(ctx context.Context, commentID int64) ([]Reaction, error) {
+	return s.IssueCommentReactions[commentID], nil
+}
@@ -0,0 +1,58 @@
+package githubapi
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadFixture(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fixture.json")
+	fixture := `{
+  "pull_requests": [{"number": 7, "title": "Add feature", "author": "alice"}],
+  "reviews": {"7": [{"id": 1, "user": "bob", "state": "APPROVED"}]},
+  "issue_comment_reactions": {"42": [{"id": 3, "user": "carol", "content": "+1"}]}
+}`
+	if err := os.WriteFile(path, []byte(fixture), 0644); err != nil {
+		t.Fatalf("Failed to write fixture: %v", err)
+	}
+
+	source, err := LoadFixture(path)
+	if err != nil {
+		t.Fatalf("Failed to load fixture: %v", err)
+	}
+
+	ctx := context.Background()
+
+	prs, _ := source.ListPullRequests(ctx)
+	if len(prs) != 1 || prs[0].Author != "alice" {
+		t.Errorf("Unexpected pull requests: %v", prs)
+	}
+
+	reviews, _ := source.ListReviews(ctx, 7)
+	if len(reviews) != 1 || reviews[0].State != "APPROVED" {
+		t.Errorf("Unexpected reviews: %v", reviews)
+	}
+
+	reactions, _ := source.ListIssueCommentReactions(ctx, 42)
+	if len(reactions) != 1 || reactions[0].Content != "+1" {
+		t.Errorf("Unexpected reactions: %v", reactions)
+	}
+
+	comments, err := source.ListReviewComments(ctx, 7)
+	if err != nil || len(comments) != 0 {
+		t.Errorf("Expected no review comments, got %v (err: %v)", comments, err)
+	}
+}
+
+func TestFakeSourceErrors(t *testing.T) {
+	source := NewFakeSource()
+	source.AddPullRequest(PullRequest{Number: 1})
+	source.Errors[1] = errors.New("boom")
+
+	if _, err := source.ListReviews(context.Background(), 1); err == nil {
+		t.Error("Expected configured error from ListReviews")
+	}
+}
@@ -2,6 +2,8 @@ package githubapi
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	"github.com/google/go-github/v62/github"
 )
@@ -32,6 +34,41 @@ func FetchAllPullRequests(ctx context.Context, client *github.Client, owner, rep
 	return allPRs, nil
 }
 
+// FetchPullRequestsUpdatedSince fetches the pull requests updated at or after
+// since, most recently updated first. Listing stops at the first older pull
+// request instead of paging through the repository's whole history.
+func FetchPullRequestsUpdatedSince(ctx context.Context, client *github.Client, owner, repo string, since time.Time) ([]*github.PullRequest, error) {
+	var recentPRs []*github.PullRequest
+	opts := &github.PullRequestListOptions{
+		State:     "all",
+		Sort:      "updated",
+		Direction: "desc",
+		ListOptions: github.ListOptions{
+			PerPage: 100,
+		},
+	}
+
+	for {
+		prs, resp, err := client.PullRequests.List(ctx, owner, repo, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, pr := range prs {
+			if pr.GetUpdatedAt().Time.Before(since) {
+				return recentPRs, nil
+			}
+			recentPRs = append(recentPRs, pr)
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return recentPRs, nil
+}
+
 // FetchPullRequestReviews fetches all reviews for a specific pull request
 func FetchPullRequestReviews(ctx context.Context, client *github.Client, owner, repo string, prNumber int) ([]*github.PullRequestReview, error) {
 	var allReviews []*github.PullRequestReview
@@ -79,3 +116,76 @@ func FetchPullRequestComments(ctx context.Context, client *github.Client, owner,
 
 	return allComments, nil
 }
+
+// FetchIssueComments fetches all conversation comments for a specific pull request
+func FetchIssueComments(ctx context.Context, client *github.Client, owner, repo string, prNumber int) ([]*github.IssueComment, error) {
+	var allComments []*github.IssueComment
+	opts := &github.IssueListCommentsOptions{
+		ListOptions: github.ListOptions{
+			PerPage: 100,
+		},
+	}
+
+	for {
+		comments, resp, err := client.Issues.ListComments(ctx, owner, repo, prNumber, opts)
+		if err != nil {
+			return nil, err
+		}
+		allComments = append(allComments, comments...)
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return allComments, nil
+}
+
+// TimedReaction is a REST reaction along with when it was added, which
+// go-github's Reaction doesn't decode
+type TimedReaction struct {
+	github.Reaction
+	CreatedAt github.Timestamp `json:"created_at"`
+}
+
+// FetchPullRequestReactions fetches all reactions on a pull request itself
+func FetchPullRequestReactions(ctx context.Context, client *github.Client, owner, repo string, prNumber int) ([]*TimedReaction, error) {
+	return fetchReactions(ctx, client, fmt.Sprintf("repos/%v/%v/issues/%d/reactions", owner, repo, prNumber))
+}
+
+// FetchReviewCommentReactions fetches all reactions on an inline review comment
+func FetchReviewCommentReactions(ctx context.Context, client *github.Client, owner, repo string, commentID int64) ([]*TimedReaction, error) {
+	return fetchReactions(ctx, client, fmt.Sprintf("repos/%v/%v/pulls/comments/%d/reactions", owner, repo, commentID))
+}
+
+// FetchIssueCommentReactions fetches all reactions on a conversation comment
+func FetchIssueCommentReactions(ctx context.Context, client *github.Client, owner, repo string, commentID int64) ([]*TimedReaction, error) {
+	return fetchReactions(ctx, client, fmt.Sprintf("repos/%v/%v/issues/comments/%d/reactions", owner, repo, commentID))
+}
+
+// fetchReactions fetches every page of a reactions endpoint
+func fetchReactions(ctx context.Context, client *github.Client, url string) ([]*TimedReaction, error) {
+	var allReactions []*TimedReaction
+	page := 1
+
+	for {
+		req, err := client.NewRequest("GET", fmt.Sprintf("%s?per_page=100&page=%d", url, page), nil)
+		if err != nil {
+			return nil, err
+		}
+		var reactions []*TimedReaction
+		resp, err := client.Do(ctx, req, &reactions)
+		if err != nil {
+			return nil, err
+		}
+		allReactions = append(allReactions, reactions...)
+
+		if resp.NextPage == 0 {
+			break
+		}
+		page = resp.NextPage
+	}
+
+	return allReactions, nil
+}
@@ -0,0 +1,715 @@
+package githubapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BatchSource is a Source that can fetch the activity of many pull requests
+// at once. Prefetch loads the activity of up to BatchSize pull requests, and
+// the List methods then answer from what was loaded. Release drops whatever
+// is left of it once the pull requests' events were collected.
+type BatchSource interface {
+	Source
+	BatchSize() int
+	Prefetch(ctx context.Context, prs []PullRequest) error
+	Release(prs []PullRequest)
+}
+
+// DefaultGraphQLEndpoint is the URL of the GitHub GraphQL API
+const DefaultGraphQLEndpoint = "https://api.github.com/graphql"
+
+// GraphQLOptions configures a GraphQLSource
+type GraphQLOptions struct {
+	Endpoint  string // GraphQL API URL, DefaultGraphQLEndpoint if empty
+	BatchSize int    // Pull requests whose activity is fetched per query, 20 if 0
+	Reactions bool   // Also fetch reactions on pull requests and comments
+}
+
+// Page sizes of the nested connections fetched per pull request. They keep a
+// batch well below GitHub's limit of 500,000 nodes per query.
+const (
+	defaultGraphQLBatchSize = 20
+	graphQLPageSize         = 50 // Reviews and conversation comments
+	graphQLThreadPageSize   = 20 // Review threads and the comments in each
+	graphQLReactionPageSize = 20 // Reactions on each pull request and comment
+)
+
+// GraphQLSource is a Source backed by the GitHub GraphQL API. It fetches the
+// reviews, review comments, conversation comments and reactions of a batch
+// of pull requests in a single query, instead of several REST requests per
+// pull request. Pull requests with more activity than a batch query returns
+// are read from the fallback source.
+type GraphQLSource struct {
+	client   *http.Client
+	owner    string
+	repo     string
+	fallback Source
+	opts     GraphQLOptions
+
+	mu                     sync.Mutex
+	activity               map[int]*prActivity
+	reviewReactions        map[int64][]Reaction
+	reviewCommentReactions map[int64][]Reaction
+	issueCommentReactions  map[int64][]Reaction
+	reacted                map[int][]reactedItem // Reviews and comments with stored reactions, by pull request
+}
+
+// reactedItem is a review or comment whose reactions are stored in reactions
+type reactedItem struct {
+	reactions map[int64][]Reaction
+	id        int64
+}
+
+// prActivity is the activity of a pull request loaded by Prefetch
+type prActivity struct {
+	reviews        []Review
+	reviewComments []Comment
+	issueComments  []Comment
+	reactions      []Reaction
+
+	fallback bool // Too much activity for a batch, read from the fallback source
+	read     int  // The parts already read, so the activity is dropped once all were
+}
+
+// Parts of a pull request's activity read by the List methods
+const (
+	partReviews = 1 << iota
+	partReviewComments
+	partIssueComments
+	partReactions
+)
+
+// NewGraphQLSource creates a source for the given repository. The client must
+// authenticate its requests, like one from oauth2.NewClient.
+func NewGraphQLSource(client *http.Client, owner, repo string, fallback Source, opts GraphQLOptions) *GraphQLSource {
+	if opts.Endpoint == "" {
+		opts.Endpoint = DefaultGraphQLEndpoint
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultGraphQLBatchSize
+	}
+	return &GraphQLSource{
+		client:                 client,
+		owner:                  owner,
+		repo:                   repo,
+		fallback:               fallback,
+		opts:                   opts,
+		activity:               make(map[int]*prActivity),
+		reviewReactions:        make(map[int64][]Reaction),
+		reviewCommentReactions: make(map[int64][]Reaction),
+		issueCommentReactions:  make(map[int64][]Reaction),
+		reacted:                make(map[int][]reactedItem),
+	}
+}
+
+// BatchSize returns how many pull requests Prefetch loads per query
+func (s *GraphQLSource) BatchSize() int {
+	return s.opts.BatchSize
+}
+
+// listPullRequestsQuery lists pull requests in descending order of a field
+const listPullRequestsQuery = `query($owner: String!, $repo: String!, $cursor: String) {
+  repository(owner: $owner, name: $repo) {
+    pullRequests(first: 100, after: $cursor, orderBy: {field: %s, direction: DESC}) {
+      pageInfo { hasNextPage endCursor }
+      nodes { number title state createdAt updatedAt author { __typename login } }
+    }
+  }
+}`
+
+// ListPullRequests lists all pull requests in the repository, newest first
+// like the REST API. Only their metadata is fetched, 100 per query.
+func (s *GraphQLSource) ListPullRequests(ctx context.Context) ([]PullRequest, error) {
+	return s.listPullRequests(ctx, "CREATED_AT", time.Time{})
+}
+
+// ListPullRequestsUpdatedSince lists the pull requests updated at or after
+// since, most recently updated first, stopping at the first older one
+func (s *GraphQLSource) ListPullRequestsUpdatedSince(ctx context.Context, since time.Time) ([]PullRequest, error) {
+	return s.listPullRequests(ctx, "UPDATED_AT", since)
+}
+
+// listPullRequests lists pull requests in descending order of field, until
+// one was last updated before since
+func (s *GraphQLSource) listPullRequests(ctx context.Context, field string, since time.Time) ([]PullRequest, error) {
+	query := fmt.Sprintf(listPullRequestsQuery, field)
+	var result []PullRequest
+	var cursor *string
+	for {
+		var data struct {
+			Repository struct {
+				PullRequests struct {
+					PageInfo pageInfo `json:"pageInfo"`
+					Nodes    []struct {
+						Number    int       `json:"number"`
+						Title     string    `json:"title"`
+						State     string    `json:"state"`
+						CreatedAt time.Time `json:"createdAt"`
+						UpdatedAt time.Time `json:"updatedAt"`
+						Author    *actor    `json:"author"`
+					} `json:"nodes"`
+				} `json:"pullRequests"`
+			} `json:"repository"`
+		}
+		vars := map[string]any{"owner": s.owner, "repo": s.repo, "cursor": cursor}
+		if err := s.query(ctx, query, vars, &data); err != nil {
+			return nil, fmt.Errorf("failed to list pull requests: %w", err)
+		}
+
+		prs := data.Repository.PullRequests
+		for _, pr := range prs.Nodes {
+			if pr.UpdatedAt.Before(since) {
+				return result, nil
+			}
+			result = append(result, PullRequest{
+				Number:    pr.Number,
+				Title:     pr.Title,
+				Author:    pr.Author.login(),
+				State:     restState(pr.State),
+				CreatedAt: pr.CreatedAt,
+				UpdatedAt: pr.UpdatedAt,
+			})
+		}
+
+		if !prs.PageInfo.HasNextPage {
+			return result, nil
+		}
+		cursor = &prs.PageInfo.EndCursor
+	}
+}
+
+// activityFragment selects the activity of a pull request. Reactions on the
+// pull request, its reviews and comments are only fetched when reactions are
+// scored.
+var activityFragment = fmt.Sprintf(`fragment activity on PullRequest {
+  number
+  reactionNodes: reactions(first: %[3]d) @include(if: $reactions) { ...reactionPage }
+  reviews(first: %[1]d) {
+    pageInfo { hasNextPage }
+    nodes {
+      fullDatabaseId author { __typename login } body state submittedAt
+      reactions { totalCount }
+      reactionNodes: reactions(first: %[3]d) @include(if: $reactions) { ...reactionPage }
+    }
+  }
+  reviewThreads(first: %[2]d) {
+    pageInfo { hasNextPage }
+    nodes {
+      comments(first: %[2]d) {
+        pageInfo { hasNextPage }
+        nodes { ...comment }
+      }
+    }
+  }
+  comments(first: %[1]d) {
+    pageInfo { hasNextPage }
+    nodes { ...comment }
+  }
+}
+
+fragment comment on Comment {
+  body
+  createdAt
+  author { __typename login }
+  ... on IssueComment { fullDatabaseId }
+  ... on PullRequestReviewComment { fullDatabaseId }
+  ... on Reactable {
+    reactions { totalCount }
+    reactionNodes: reactions(first: %[3]d) @include(if: $reactions) { ...reactionPage }
+  }
+}
+
+fragment reactionPage on ReactionConnection {
+  pageInfo { hasNextPage }
+  nodes { databaseId content createdAt user { __typename login } }
+}`, graphQLPageSize, graphQLThreadPageSize, graphQLReactionPageSize)
+
+// Prefetch loads the activity of prs in a single query. Pull requests with
+// more activity than fits in it are left to the fallback source.
+func (s *GraphQLSource) Prefetch(ctx context.Context, prs []PullRequest) error {
+	if len(prs) == 0 {
+		return nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString("query($owner: String!, $repo: String!, $reactions: Boolean!) {\n  repository(owner: $owner, name: $repo) {\n")
+	for i, pr := range prs {
+		fmt.Fprintf(&sb, "    pr%d: pullRequest(number: %d) { ...activity }\n", i, pr.Number)
+	}
+	sb.WriteString("  }\n}\n\n")
+	sb.WriteString(activityFragment)
+
+	var data struct {
+		Repository map[string]*graphQLPullRequest `json:"repository"`
+	}
+	vars := map[string]any{"owner": s.owner, "repo": s.repo, "reactions": s.opts.Reactions}
+	if err := s.query(ctx, sb.String(), vars, &data); err != nil {
+		return fmt.Errorf("failed to fetch activity of %d pull requests: %w", len(prs), err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, pr := range prs {
+		// Missing pull requests are left to the fallback as well, which
+		// reports them the way the REST API does
+		loaded := data.Repository[fmt.Sprintf("pr%d", i)]
+		if loaded == nil || loaded.truncated() {
+			s.activity[pr.Number] = &prActivity{fallback: true}
+			continue
+		}
+		s.store(loaded)
+	}
+	return nil
+}
+
+// store keeps the normalized activity of a pull request. Only the reactions
+// of reviews and comments that have any are kept, since no others are
+// looked up.
+func (s *GraphQLSource) store(pr *graphQLPullRequest) {
+	activity := &prActivity{
+		reactions: pr.ReactionNodes.normalize(),
+	}
+
+	for _, review := range pr.Reviews.Nodes {
+		activity.reviews = append(activity.reviews, Review{
+			ID:          int64(review.FullDatabaseID),
+			User:        review.Author.login(),
+			Body:        review.Body,
+			State:       review.State,
+			SubmittedAt: review.SubmittedAt,
+			Reactions:   review.Reactions.TotalCount,
+		})
+		s.storeReactions(pr.Number, s.reviewReactions, int64(review.FullDatabaseID), review.ReactionNodes)
+	}
+
+	for _, thread := range pr.ReviewThreads.Nodes {
+		for _, comment := range thread.Comments.Nodes {
+			activity.reviewComments = append(activity.reviewComments, comment.normalize())
+			s.storeReactions(pr.Number, s.reviewCommentReactions, int64(comment.FullDatabaseID), comment.ReactionNodes)
+		}
+	}
+	// Threads group comments by location; the REST API lists them by creation
+	sort.SliceStable(activity.reviewComments, func(i, j int) bool {
+		return activity.reviewComments[i].ID < activity.reviewComments[j].ID
+	})
+
+	for _, comment := range pr.Comments.Nodes {
+		activity.issueComments = append(activity.issueComments, comment.normalize())
+		s.storeReactions(pr.Number, s.issueCommentReactions, int64(comment.FullDatabaseID), comment.ReactionNodes)
+	}
+
+	s.activity[pr.Number] = activity
+}
+
+// storeReactions keeps the reactions of a review or comment of a pull
+// request in reactions, if it has any
+func (s *GraphQLSource) storeReactions(prNumber int, reactions map[int64][]Reaction, id int64, page *graphQLReactionPage) {
+	if normalized := page.normalize(); len(normalized) > 0 {
+		reactions[id] = normalized
+		s.reacted[prNumber] = append(s.reacted[prNumber], reactedItem{reactions: reactions, id: id})
+	}
+}
+
+// take returns a part of the activity of a pull request, prefetching it on
+// its own if it wasn't part of a batch. It returns nil if the pull request
+// has to be read from the fallback source. Once every part was read, the
+// activity is dropped, so a long run doesn't keep all of it.
+func (s *GraphQLSource) take(ctx context.Context, prNumber int, part int) (*prActivity, error) {
+	s.mu.Lock()
+	_, ok := s.activity[prNumber]
+	s.mu.Unlock()
+	if !ok {
+		if err := s.Prefetch(ctx, []PullRequest{{Number: prNumber}}); err != nil {
+			return nil, err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	activity := s.activity[prNumber]
+	if activity == nil {
+		return nil, nil // Dropped after being read already
+	}
+
+	all := partReviews | partReviewComments | partIssueComments
+	if s.opts.Reactions {
+		all |= partReactions
+	}
+	activity.read |= part
+	if activity.read&all == all {
+		delete(s.activity, prNumber)
+	}
+
+	if activity.fallback {
+		return nil, nil
+	}
+	return activity, nil
+}
+
+// Release drops the activity of prs that is left after their events were
+// collected, such as reactions that weren't looked up
+func (s *GraphQLSource) Release(prs []PullRequest) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, pr := range prs {
+		delete(s.activity, pr.Number)
+		for _, item := range s.reacted[pr.Number] {
+			delete(item.reactions, item.id)
+		}
+		delete(s.reacted, pr.Number)
+	}
+}
+
+// ListReviews lists all reviews on a pull request
+func (s *GraphQLSource) ListReviews(ctx context.Context, prNumber int) ([]Review, error) {
+	activity, err := s.take(ctx, prNumber, partReviews)
+	if err != nil || activity == nil {
+		return orFallback(s, err, func() ([]Review, error) { return s.fallback.ListReviews(ctx, prNumber) })
+	}
+	return activity.reviews, nil
+}
+
+// ListReviewComments lists all inline review comments on a pull request
+func (s *GraphQLSource) ListReviewComments(ctx context.Context, prNumber int) ([]Comment, error) {
+	activity, err := s.take(ctx, prNumber, partReviewComments)
+	if err != nil || activity == nil {
+		return orFallback(s, err, func() ([]Comment, error) { return s.fallback.ListReviewComments(ctx, prNumber) })
+	}
+	return activity.reviewComments, nil
+}
+
+// ListIssueComments lists all conversation comments on a pull request
+func (s *GraphQLSource) ListIssueComments(ctx context.Context, prNumber int) ([]Comment, error) {
+	activity, err := s.take(ctx, prNumber, partIssueComments)
+	if err != nil || activity == nil {
+		return orFallback(s, err, func() ([]Comment, error) { return s.fallback.ListIssueComments(ctx, prNumber) })
+	}
+	return activity.issueComments, nil
+}
+
+// ListPullRequestReactions lists all reactions on a pull request
+func (s *GraphQLSource) ListPullRequestReactions(ctx context.Context, prNumber int) ([]Reaction, error) {
+	if !s.opts.Reactions {
+		return orFallback(s, nil, func() ([]Reaction, error) { return s.fallback.ListPullRequestReactions(ctx, prNumber) })
+	}
+	activity, err := s.take(ctx, prNumber, partReactions)
+	if err != nil || activity == nil {
+		return orFallback(s, err, func() ([]Reaction, error) { return s.fallback.ListPullRequestReactions(ctx, prNumber) })
+	}
+	return activity.reactions, nil
+}
+
+// ListReviewReactions lists all reactions on a review
+func (s *GraphQLSource) ListReviewReactions(ctx context.Context, reviewID int64) ([]Reaction, error) {
+	if reactions, ok := s.takeReactions(s.reviewReactions, reviewID); ok {
+		return reactions, nil
+	}
+	return orFallback(s, nil, func() ([]Reaction, error) { return s.fallback.ListReviewReactions(ctx, reviewID) })
+}
+
+// ListReviewCommentReactions lists all reactions on an inline review comment
+func (s *GraphQLSource) ListReviewCommentReactions(ctx context.Context, commentID int64) ([]Reaction, error) {
+	if reactions, ok := s.takeReactions(s.reviewCommentReactions, commentID); ok {
+		return reactions, nil
+	}
+	return orFallback(s, nil, func() ([]Reaction, error) { return s.fallback.ListReviewCommentReactions(ctx, commentID) })
+}
+
+// ListIssueCommentReactions lists all reactions on a conversation comment
+func (s *GraphQLSource) ListIssueCommentReactions(ctx context.Context, commentID int64) ([]Reaction, error) {
+	if reactions, ok := s.takeReactions(s.issueCommentReactions, commentID); ok {
+		return reactions, nil
+	}
+	return orFallback(s, nil, func() ([]Reaction, error) { return s.fallback.ListIssueCommentReactions(ctx, commentID) })
+}
+
+// takeReactions returns and drops the stored reactions of a review or comment
+func (s *GraphQLSource) takeReactions(reactions map[int64][]Reaction, id int64) ([]Reaction, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := reactions[id]
+	delete(reactions, id)
+	return stored, ok
+}
+
+// orFallback returns err if set, and otherwise reads from the fallback source
+func orFallback[T any](s *GraphQLSource, err error, read func() ([]T, error)) ([]T, error) {
+	if err != nil {
+		return nil, err
+	}
+	if s.fallback == nil {
+		return nil, fmt.Errorf("activity is too large for a GraphQL query and no fallback source is set")
+	}
+	return read()
+}
+
+// query runs a GraphQL query and decodes its data into out
+func (s *GraphQLSource) query(ctx context.Context, query string, vars map[string]any, out any) error {
+	body, err := json.Marshal(map[string]any{"query": query, "variables": vars})
+	if err != nil {
+		return err
+	}
+
+	// A rate limit reported in the response body has reset by the time the
+	// transport returns it, so the query is tried once more
+	for attempt := 1; ; attempt++ {
+		err = s.post(ctx, body, out)
+		var gqlErr *GraphQLError
+		if attempt == 2 || !errors.As(err, &gqlErr) || gqlErr.Type != graphQLRateLimited {
+			return err
+		}
+	}
+}
+
+// post sends a GraphQL request body and decodes the response data into out
+func (s *GraphQLSource) post(ctx context.Context, body []byte, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.opts.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	var result struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []GraphQLError  `json:"errors"`
+	}
+	if err := json.Unmarshal(content, &result); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("GraphQL request failed: %s", resp.Status)
+		}
+		return fmt.Errorf("failed to parse GraphQL response: %w", err)
+	}
+	for i, gqlErr := range result.Errors {
+		// Pull requests that don't exist are null in otherwise valid data
+		if gqlErr.Type != graphQLNotFound || len(result.Data) == 0 || string(result.Data) == "null" {
+			return &result.Errors[i]
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GraphQL request failed: %s", resp.Status)
+	}
+
+	return json.Unmarshal(result.Data, out)
+}
+
+// Error types reported by the GitHub GraphQL API
+const (
+	graphQLRateLimited = "RATE_LIMITED"
+	graphQLNotFound    = "NOT_FOUND"
+)
+
+// GraphQLError is an error reported in a GraphQL response
+type GraphQLError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// Error returns the error message
+func (e *GraphQLError) Error() string {
+	if e.Type != "" {
+		return fmt.Sprintf("GraphQL error %s: %s", e.Type, e.Message)
+	}
+	return "GraphQL error: " + e.Message
+}
+
+// pageInfo is the pagination state of a GraphQL connection
+type pageInfo struct {
+	HasNextPage bool   `json:"hasNextPage"`
+	EndCursor   string `json:"endCursor"`
+}
+
+// actor is the author of a GraphQL object
+type actor struct {
+	Type  string `json:"__typename"`
+	Login string `json:"login"`
+}
+
+// login returns the actor's login as the REST API reports it. Deleted
+// accounts have no actor and are reported as "ghost", and the logins of
+// apps lack the "[bot]" suffix in GraphQL.
+func (a *actor) login() string {
+	if a == nil {
+		return "ghost"
+	}
+	if a.Type == "Bot" {
+		return a.Login + "[bot]"
+	}
+	return a.Login
+}
+
+// bigInt decodes GraphQL BigInt values, which are serialized as strings
+type bigInt int64
+
+// UnmarshalJSON accepts quoted and plain numbers
+func (b *bigInt) UnmarshalJSON(data []byte) error {
+	n, err := strconv.ParseInt(strings.Trim(string(data), `"`), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid BigInt %s: %w", data, err)
+	}
+	*b = bigInt(n)
+	return nil
+}
+
+// graphQLPullRequest is the activity of a pull request as selected by
+// activityFragment
+type graphQLPullRequest struct {
+	Number        int                  `json:"number"`
+	ReactionNodes *graphQLReactionPage `json:"reactionNodes"`
+	Reviews       struct {
+		PageInfo pageInfo `json:"pageInfo"`
+		Nodes    []struct {
+			FullDatabaseID bigInt    `json:"fullDatabaseId"`
+			Author         *actor    `json:"author"`
+			Body           string    `json:"body"`
+			State          string    `json:"state"`
+			SubmittedAt    time.Time `json:"submittedAt"`
+			Reactions      struct {
+				TotalCount int `json:"totalCount"`
+			} `json:"reactions"`
+			ReactionNodes *graphQLReactionPage `json:"reactionNodes"`
+		} `json:"nodes"`
+	} `json:"reviews"`
+	ReviewThreads struct {
+		PageInfo pageInfo `json:"pageInfo"`
+		Nodes    []struct {
+			Comments graphQLCommentPage `json:"comments"`
+		} `json:"nodes"`
+	} `json:"reviewThreads"`
+	Comments graphQLCommentPage `json:"comments"`
+}
+
+// truncated reports whether any connection of the pull request has more
+// pages than the query fetched
+func (pr *graphQLPullRequest) truncated() bool {
+	if pr.Reviews.PageInfo.HasNextPage || pr.ReviewThreads.PageInfo.HasNextPage || pr.ReactionNodes.truncated() {
+		return true
+	}
+	for _, review := range pr.Reviews.Nodes {
+		if review.ReactionNodes.truncated() {
+			return true
+		}
+	}
+	for _, thread := range pr.ReviewThreads.Nodes {
+		if thread.Comments.truncated() {
+			return true
+		}
+	}
+	return pr.Comments.truncated()
+}
+
+// graphQLCommentPage is a page of review comments or conversation comments
+type graphQLCommentPage struct {
+	PageInfo pageInfo         `json:"pageInfo"`
+	Nodes    []graphQLComment `json:"nodes"`
+}
+
+// truncated reports whether the page or a comment's reactions were cut off
+func (p graphQLCommentPage) truncated() bool {
+	if p.PageInfo.HasNextPage {
+		return true
+	}
+	for _, comment := range p.Nodes {
+		if comment.ReactionNodes.truncated() {
+			return true
+		}
+	}
+	return false
+}
+
+// graphQLComment is a comment as selected by the comment fragment
+type graphQLComment struct {
+	FullDatabaseID bigInt    `json:"fullDatabaseId"`
+	Author         *actor    `json:"author"`
+	Body           string    `json:"body"`
+	CreatedAt      time.Time `json:"createdAt"`
+	Reactions      struct {
+		TotalCount int `json:"totalCount"`
+	} `json:"reactions"`
+	ReactionNodes *graphQLReactionPage `json:"reactionNodes"`
+}
+
+// normalize converts the comment to the shared model
+func (c graphQLComment) normalize() Comment {
+	return Comment{
+		ID:        int64(c.FullDatabaseID),
+		User:      c.Author.login(),
+		Body:      c.Body,
+		CreatedAt: c.CreatedAt,
+		Reactions: c.Reactions.TotalCount,
+	}
+}
+
+// graphQLReactionPage is a page of reactions, nil when not fetched
+type graphQLReactionPage struct {
+	PageInfo pageInfo `json:"pageInfo"`
+	Nodes    []struct {
+		DatabaseID int64     `json:"databaseId"`
+		Content    string    `json:"content"`
+		CreatedAt  time.Time `json:"createdAt"`
+		User       *actor    `json:"user"`
+	} `json:"nodes"`
+}
+
+// truncated reports whether there are more reactions than were fetched
+func (p *graphQLReactionPage) truncated() bool {
+	return p != nil && p.PageInfo.HasNextPage
+}
+
+// normalize converts the reactions to the shared model
+func (p *graphQLReactionPage) normalize() []Reaction {
+	if p == nil {
+		return nil
+	}
+	reactions := make([]Reaction, 0, len(p.Nodes))
+	for _, node := range p.Nodes {
+		reactions = append(reactions, Reaction{
+			ID:        node.DatabaseID,
+			User:      node.User.login(),
+			Content:   restReactions[node.Content],
+			CreatedAt: node.CreatedAt,
+		})
+	}
+	return reactions
+}
+
+// restReactions maps GraphQL reaction contents to their REST names
+var restReactions = map[string]string{
+	"THUMBS_UP":   "+1",
+	"THUMBS_DOWN": "-1",
+	"LAUGH":       "laugh",
+	"CONFUSED":    "confused",
+	"HEART":       "heart",
+	"HOORAY":      "hooray",
+	"ROCKET":      "rocket",
+	"EYES":        "eyes",
+}
+
+// restState maps a GraphQL pull request state to its REST name
+func restState(state string) string {
+	if state == "OPEN" {
+		return "open"
+	}
+	return "closed" // CLOSED and MERGED
+}
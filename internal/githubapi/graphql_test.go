@@ -0,0 +1,349 @@
+package githubapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// graphQLServer answers GraphQL queries from canned pull request data
+type graphQLServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests int
+	vars     []map[string]any
+	queries  []string
+
+	pages      []string       // Pull request list pages, by cursor index
+	activity   map[int]string // Activity JSON by pull request number
+	errorsJSON string         // Errors returned with every response
+}
+
+var aliasPattern = regexp.MustCompile(`(pr\d+): pullRequest\(number: (\d+)\)`)
+
+func newGraphQLServer(t *testing.T) *graphQLServer {
+	t.Helper()
+	s := &graphQLServer{activity: make(map[int]string)}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Query     string         `json:"query"`
+			Variables map[string]any `json:"variables"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Invalid GraphQL request: %v", err)
+		}
+
+		s.mu.Lock()
+		s.requests++
+		s.vars = append(s.vars, req.Variables)
+		s.queries = append(s.queries, req.Query)
+		s.mu.Unlock()
+
+		var data string
+		if strings.Contains(req.Query, "pullRequests(first: 100") {
+			page := 0
+			if cursor, ok := req.Variables["cursor"].(string); ok {
+				fmt.Sscanf(cursor, "%d", &page)
+			}
+			data = s.pages[page]
+		} else {
+			var fields []string
+			for _, match := range aliasPattern.FindAllStringSubmatch(req.Query, -1) {
+				var number int
+				fmt.Sscanf(match[2], "%d", &number)
+				activity, ok := s.activity[number]
+				if !ok {
+					activity = "null"
+				}
+				fields = append(fields, fmt.Sprintf("%q: %s", match[1], activity))
+			}
+			data = `{"repository": {` + strings.Join(fields, ", ") + `}}`
+		}
+
+		if s.errorsJSON != "" {
+			fmt.Fprintf(w, `{"data": %s, "errors": %s}`, data, s.errorsJSON)
+			return
+		}
+		fmt.Fprintf(w, `{"data": %s}`, data)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *graphQLServer) source(fallback Source, reactions bool) *GraphQLSource {
+	return NewGraphQLSource(s.Client(), "owner", "repo", fallback, GraphQLOptions{
+		Endpoint:  s.URL + "/graphql",
+		Reactions: reactions,
+	})
+}
+
+const emptyActivity = `{
+  "number": %d,
+  "reviews": {"pageInfo": {"hasNextPage": %t}, "nodes": []},
+  "reviewThreads": {"pageInfo": {"hasNextPage": false}, "nodes": []},
+  "comments": {"pageInfo": {"hasNextPage": false}, "nodes": []}
+}`
+
+func TestGraphQLSourceListPullRequests(t *testing.T) {
+	server := newGraphQLServer(t)
+	server.pages = []string{
+		`{"repository": {"pullRequests": {"pageInfo": {"hasNextPage": true, "endCursor": "1"}, "nodes": [
+			{"number": 3, "title": "Open", "state": "OPEN", "createdAt": "2024-01-03T00:00:00Z", "updatedAt": "2024-01-04T00:00:00Z", "author": {"login": "alice"}},
+			{"number": 2, "title": "Merged", "state": "MERGED", "createdAt": "2024-01-02T00:00:00Z", "updatedAt": "2024-01-02T00:00:00Z", "author": null}
+		]}}}`,
+		`{"repository": {"pullRequests": {"pageInfo": {"hasNextPage": false, "endCursor": "2"}, "nodes": [
+			{"number": 1, "title": "Closed", "state": "CLOSED", "createdAt": "2024-01-01T00:00:00Z", "updatedAt": "2024-01-01T00:00:00Z", "author": {"login": "bob"}}
+		]}}}`,
+	}
+
+	prs, err := server.source(nil, false).ListPullRequests(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to list pull requests: %v", err)
+	}
+
+	expected := []PullRequest{
+		{Number: 3, Title: "Open", Author: "alice", State: "open"},
+		{Number: 2, Title: "Merged", Author: "ghost", State: "closed"},
+		{Number: 1, Title: "Closed", Author: "bob", State: "closed"},
+	}
+	if len(prs) != len(expected) {
+		t.Fatalf("Expected %d pull requests, got %d", len(expected), len(prs))
+	}
+	for i, pr := range prs {
+		want := expected[i]
+		if pr.Number != want.Number || pr.Title != want.Title || pr.Author != want.Author || pr.State != want.State {
+			t.Errorf("Expected %+v, got %+v", want, pr)
+		}
+	}
+	if prs[0].UpdatedAt.Day() != 4 {
+		t.Errorf("Expected the update time to be parsed, got %v", prs[0].UpdatedAt)
+	}
+	if server.requests != 2 {
+		t.Errorf("Expected 2 requests, got %d", server.requests)
+	}
+}
+
+func TestGraphQLSourceListPullRequestsUpdatedSince(t *testing.T) {
+	server := newGraphQLServer(t)
+	server.pages = []string{
+		`{"repository": {"pullRequests": {"pageInfo": {"hasNextPage": true, "endCursor": "1"}, "nodes": [
+			{"number": 1, "state": "OPEN", "updatedAt": "2024-03-05T00:00:00Z"},
+			{"number": 3, "state": "OPEN", "updatedAt": "2024-02-20T00:00:00Z"}
+		]}}}`,
+		`{"repository": {"pullRequests": {"pageInfo": {"hasNextPage": false, "endCursor": "2"}, "nodes": [
+			{"number": 2, "state": "OPEN", "updatedAt": "2024-01-01T00:00:00Z"}
+		]}}}`,
+	}
+
+	prs, err := server.source(nil, false).ListPullRequestsUpdatedSince(context.Background(), time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Failed to list pull requests: %v", err)
+	}
+	if len(prs) != 1 || prs[0].Number != 1 {
+		t.Errorf("Expected only PR #1, got %+v", prs)
+	}
+	if server.requests != 1 {
+		t.Errorf("Expected listing to stop after the first page, got %d requests", server.requests)
+	}
+	if !strings.Contains(server.queries[0], "field: UPDATED_AT") {
+		t.Errorf("Expected PRs ordered by update time, got query %s", server.queries[0])
+	}
+}
+
+func TestGraphQLSourcePrefetch(t *testing.T) {
+	server := newGraphQLServer(t)
+	server.activity[1] = `{
+  "number": 1,
+  "reactionNodes": {"pageInfo": {"hasNextPage": false}, "nodes": [{"databaseId": 7, "content": "ROCKET", "createdAt": "2024-02-01T08:00:00Z", "user": {"login": "erin"}}]},
+  "reviews": {"pageInfo": {"hasNextPage": false}, "nodes": [
+    {"fullDatabaseId": "101", "author": {"login": "bob"}, "body": "LGTM", "state": "APPROVED", "submittedAt": "2024-01-02T12:00:00Z", "reactions": {"totalCount": 1}, "reactionNodes": {"pageInfo": {"hasNextPage": false}, "nodes": [{"databaseId": 9, "content": "HEART", "createdAt": "2024-03-01T09:00:00Z", "user": {"login": "dave"}}]}}
+  ]},
+  "reviewThreads": {"pageInfo": {"hasNextPage": false}, "nodes": [
+    {"comments": {"pageInfo": {"hasNextPage": false}, "nodes": [
+      {"fullDatabaseId": "2002", "author": {"login": "carol"}, "body": "Second", "createdAt": "2024-01-02T13:10:00Z", "reactions": {"totalCount": 0}, "reactionNodes": {"pageInfo": {"hasNextPage": false}, "nodes": []}}
+    ]}},
+    {"comments": {"pageInfo": {"hasNextPage": false}, "nodes": [
+      {"fullDatabaseId": "2001", "author": {"login": "carol"}, "body": "First", "createdAt": "2024-01-02T13:00:00Z", "reactions": {"totalCount": 1}, "reactionNodes": {"pageInfo": {"hasNextPage": false}, "nodes": [{"databaseId": 8, "content": "THUMBS_UP", "user": {"login": "alice"}}]}}
+    ]}}
+  ]},
+  "comments": {"pageInfo": {"hasNextPage": false}, "nodes": [
+    {"fullDatabaseId": "3000000001", "author": null, "body": "Thanks", "createdAt": "2024-01-02T15:00:00Z", "reactions": {"totalCount": 0}, "reactionNodes": {"pageInfo": {"hasNextPage": false}, "nodes": []}}
+  ]}
+}`
+	server.activity[2] = fmt.Sprintf(emptyActivity, 2, true) // More reviews than one page
+	server.errorsJSON = `[{"type": "NOT_FOUND", "message": "Could not resolve to a PullRequest with the number of 3."}]`
+
+	fallback := NewFakeSource()
+	fallback.Reviews[2] = []Review{{ID: 201, User: "dave", State: "COMMENTED"}}
+
+	source := server.source(fallback, true)
+	ctx := context.Background()
+	if err := source.Prefetch(ctx, []PullRequest{{Number: 1}, {Number: 2}, {Number: 3}}); err != nil {
+		t.Fatalf("Failed to prefetch: %v", err)
+	}
+	if server.vars[0]["reactions"] != true {
+		t.Errorf("Expected reactions to be requested, got variables %v", server.vars[0])
+	}
+
+	reviews, _ := source.ListReviews(ctx, 1)
+	if len(reviews) != 1 || reviews[0].ID != 101 || reviews[0].User != "bob" || reviews[0].State != "APPROVED" || reviews[0].Reactions != 1 {
+		t.Errorf("Unexpected reviews: %+v", reviews)
+	}
+
+	reviewReactions, _ := source.ListReviewReactions(ctx, 101)
+	if len(reviewReactions) != 1 || reviewReactions[0].Content != "heart" || !reviewReactions[0].CreatedAt.Equal(time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected review reactions: %+v", reviewReactions)
+	}
+
+	comments, _ := source.ListReviewComments(ctx, 1)
+	if len(comments) != 2 || comments[0].ID != 2001 || comments[1].ID != 2002 || comments[0].Reactions != 1 {
+		t.Errorf("Expected review comments in creation order, got %+v", comments)
+	}
+
+	issueComments, _ := source.ListIssueComments(ctx, 1)
+	if len(issueComments) != 1 || issueComments[0].ID != 3000000001 || issueComments[0].User != "ghost" {
+		t.Errorf("Unexpected conversation comments: %+v", issueComments)
+	}
+
+	prReactions, _ := source.ListPullRequestReactions(ctx, 1)
+	if len(prReactions) != 1 || prReactions[0].Content != "rocket" || prReactions[0].User != "erin" || !prReactions[0].CreatedAt.Equal(time.Date(2024, 2, 1, 8, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected pull request reactions: %+v", prReactions)
+	}
+
+	commentReactions, _ := source.ListReviewCommentReactions(ctx, 2001)
+	if len(commentReactions) != 1 || commentReactions[0].Content != "+1" {
+		t.Errorf("Unexpected comment reactions: %+v", commentReactions)
+	}
+
+	if server.requests != 1 {
+		t.Errorf("Expected PR #1 to be answered from the prefetched batch, got %d requests", server.requests)
+	}
+
+	// PR #2 had more reviews than one page, so the fallback provides them
+	reviews, err := source.ListReviews(ctx, 2)
+	if err != nil || len(reviews) != 1 || reviews[0].ID != 201 {
+		t.Errorf("Expected the fallback's reviews, got %+v: %v", reviews, err)
+	}
+
+	// PR #3 is missing from the batch, so the fallback reports it
+	if _, err := source.ListReviews(ctx, 3); err != nil {
+		t.Errorf("Expected a missing PR to fall back, got: %v", err)
+	}
+	if _, err := source.ListIssueComments(ctx, 2); err != nil {
+		t.Errorf("Expected the fallback's conversation comments, got: %v", err)
+	}
+	if server.requests != 1 {
+		t.Errorf("Expected PRs #2 and #3 to go to the fallback without another query, got %d requests", server.requests)
+	}
+
+	// Activity is dropped once every part of it was read
+	if _, ok := source.activity[1]; ok {
+		t.Error("Expected the activity of PR #1 to be dropped after it was read")
+	}
+	if _, ok := source.activity[2]; !ok {
+		t.Error("Expected the fallback marker of PR #2 to be kept until all of it was read")
+	}
+}
+
+func TestGraphQLSourceRelease(t *testing.T) {
+	server := newGraphQLServer(t)
+	server.activity[1] = `{
+  "number": 1,
+  "reactionNodes": {"pageInfo": {"hasNextPage": false}, "nodes": [{"databaseId": 7, "content": "ROCKET", "user": {"login": "erin"}}]},
+  "reviews": {"pageInfo": {"hasNextPage": false}, "nodes": []},
+  "reviewThreads": {"pageInfo": {"hasNextPage": false}, "nodes": [
+    {"comments": {"pageInfo": {"hasNextPage": false}, "nodes": [
+      {"fullDatabaseId": "2001", "author": {"login": "carol"}, "body": "First", "createdAt": "2024-01-02T13:00:00Z", "reactions": {"totalCount": 1}, "reactionNodes": {"pageInfo": {"hasNextPage": false}, "nodes": [{"databaseId": 8, "content": "THUMBS_UP", "user": {"login": "alice"}}]}},
+      {"fullDatabaseId": "2002", "author": {"login": "carol"}, "body": "Second", "createdAt": "2024-01-02T13:10:00Z", "reactions": {"totalCount": 0}, "reactionNodes": {"pageInfo": {"hasNextPage": false}, "nodes": []}}
+    ]}}
+  ]},
+  "comments": {"pageInfo": {"hasNextPage": false}, "nodes": [
+    {"fullDatabaseId": "3001", "author": {"login": "dave"}, "body": "Thanks", "createdAt": "2024-01-02T15:00:00Z", "reactions": {"totalCount": 0}, "reactionNodes": {"pageInfo": {"hasNextPage": false}, "nodes": []}}
+  ]}
+}`
+
+	source := server.source(nil, true)
+	ctx := context.Background()
+	prs := []PullRequest{{Number: 1}}
+	if err := source.Prefetch(ctx, prs); err != nil {
+		t.Fatalf("Failed to prefetch: %v", err)
+	}
+
+	// Comments without reactions are never looked up, so they aren't kept
+	if len(source.reviewCommentReactions) != 1 || len(source.issueCommentReactions) != 0 {
+		t.Errorf("Expected only the reactions of comment 2001 to be kept, got %v and %v", source.reviewCommentReactions, source.issueCommentReactions)
+	}
+
+	// A pull request outside the window never has its own reactions read
+	source.ListReviews(ctx, 1)
+	source.ListReviewComments(ctx, 1)
+	source.ListIssueComments(ctx, 1)
+	source.Release(prs)
+
+	if len(source.activity) != 0 || len(source.reviewCommentReactions) != 0 || len(source.reacted) != 0 {
+		t.Errorf("Expected the activity to be released, got %v, %v and %v", source.activity, source.reviewCommentReactions, source.reacted)
+	}
+}
+
+func TestGraphQLSourceBotLogins(t *testing.T) {
+	server := newGraphQLServer(t)
+	server.pages = []string{
+		`{"repository": {"pullRequests": {"pageInfo": {"hasNextPage": false}, "nodes": [
+			{"number": 1, "title": "Bump deps", "state": "OPEN", "createdAt": "2024-01-01T00:00:00Z", "updatedAt": "2024-01-01T00:00:00Z", "author": {"__typename": "Bot", "login": "dependabot"}}
+		]}}}`,
+	}
+	server.activity[1] = `{
+  "number": 1,
+  "reviews": {"pageInfo": {"hasNextPage": false}, "nodes": [
+    {"fullDatabaseId": "101", "author": {"__typename": "Bot", "login": "github-actions"}, "body": "👍", "state": "APPROVED", "submittedAt": "2024-01-02T12:00:00Z"},
+    {"fullDatabaseId": "102", "author": {"__typename": "User", "login": "bob"}, "body": "LGTM", "state": "APPROVED", "submittedAt": "2024-01-02T13:00:00Z"}
+  ]},
+  "reviewThreads": {"pageInfo": {"hasNextPage": false}, "nodes": []},
+  "comments": {"pageInfo": {"hasNextPage": false}, "nodes": []}
+}`
+
+	source := server.source(nil, false)
+	ctx := context.Background()
+
+	// GraphQL leaves out the "[bot]" suffix the REST API and bot patterns use
+	prs, err := source.ListPullRequests(ctx)
+	if err != nil {
+		t.Fatalf("Failed to list pull requests: %v", err)
+	}
+	if len(prs) != 1 || prs[0].Author != "dependabot[bot]" {
+		t.Errorf("Expected the bot author dependabot[bot], got %+v", prs)
+	}
+
+	if err := source.Prefetch(ctx, prs); err != nil {
+		t.Fatalf("Failed to prefetch: %v", err)
+	}
+	reviews, _ := source.ListReviews(ctx, 1)
+	if len(reviews) != 2 || reviews[0].User != "github-actions[bot]" || reviews[1].User != "bob" {
+		t.Errorf("Expected bot reviewers to have the [bot] suffix, got %+v", reviews)
+	}
+	if !strings.Contains(server.queries[1], "author { __typename login }") {
+		t.Errorf("Expected author types to be requested, got query %s", server.queries[1])
+	}
+}
+
+func TestGraphQLSourceRateLimited(t *testing.T) {
+	server := newGraphQLServer(t)
+	server.pages = []string{`null`}
+	server.errorsJSON = `[{"type": "RATE_LIMITED", "message": "API rate limit exceeded"}]`
+
+	_, err := server.source(nil, false).ListPullRequests(context.Background())
+	if !IsRateLimit(err) {
+		t.Errorf("Expected a rate limit error, got: %v", err)
+	}
+	if server.requests != 2 {
+		t.Errorf("Expected the query to be tried twice, got %d requests", server.requests)
+	}
+}
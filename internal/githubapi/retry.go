@@ -0,0 +1,209 @@
+package githubapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v62/github"
+)
+
+// RetryOptions configures how a RetryTransport retries requests
+type RetryOptions struct {
+	MaxRetries int           // Retries of a request after its first attempt
+	BaseDelay  time.Duration // Backoff before the first retry of a failed request, doubled on each retry
+	MaxWait    time.Duration // Longest wait for a rate limit to reset; longer limits are returned as errors
+
+	// OnWait is called before waiting, with the reason and the wait duration
+	OnWait func(reason string, wait time.Duration)
+}
+
+// DefaultRetryOptions returns the retry settings used by the action
+func DefaultRetryOptions() RetryOptions {
+	return RetryOptions{
+		MaxRetries: 5,
+		BaseDelay:  time.Second,
+		MaxWait:    15 * time.Minute,
+	}
+}
+
+// secondaryRateLimitDelay is the minimum wait GitHub asks for after a
+// secondary rate limit that doesn't say how long to wait
+const secondaryRateLimitDelay = time.Minute
+
+// RetryTransport retries GitHub API requests that failed transiently. It
+// waits out rate limits as told by the Retry-After and X-RateLimit-* headers,
+// backs off exponentially on server errors and network failures, and waits
+// for the rate limit to reset when a response says it was exhausted, so the
+// next request doesn't fail. Only reads are retried: GET and HEAD requests,
+// and GraphQL queries, which are POSTed.
+type RetryTransport struct {
+	base  http.RoundTripper
+	opts  RetryOptions
+	now   func() time.Time
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// NewRetryTransport wraps base, or http.DefaultTransport if nil, with retries
+func NewRetryTransport(base http.RoundTripper, opts RetryOptions) *RetryTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &RetryTransport{
+		base:  base,
+		opts:  opts,
+		now:   time.Now,
+		sleep: sleepContext,
+	}
+}
+
+// RoundTrip sends the request, retrying it while the failure is transient
+func (t *RetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !isRead(req) {
+		return t.base.RoundTrip(req)
+	}
+
+	for attempt := 0; ; attempt++ {
+		attemptReq := req
+		if attempt > 0 && req.Body != nil {
+			// Each attempt needs a fresh copy of the body
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attemptReq = req.Clone(req.Context())
+			attemptReq.Body = body
+		}
+
+		resp, err := t.base.RoundTrip(attemptReq)
+		if err != nil && req.Context().Err() != nil {
+			return nil, err
+		}
+
+		reason, wait, retry := t.classify(resp, err, attempt)
+		if wait <= 0 || wait > t.opts.MaxWait || (retry && attempt >= t.opts.MaxRetries) {
+			return resp, err
+		}
+
+		if retry && resp != nil {
+			// The body isn't returned, so drain it to reuse the connection
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		if t.opts.OnWait != nil {
+			t.opts.OnWait(reason, wait)
+		}
+		if err := t.sleep(req.Context(), wait); err != nil {
+			if !retry && resp != nil {
+				resp.Body.Close()
+			}
+			return nil, err
+		}
+
+		if !retry {
+			// The rate limit was exhausted by this request; it has reset now
+			return resp, nil
+		}
+	}
+}
+
+// isRead reports whether a request only reads data and can be retried
+func isRead(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead:
+		return true
+	case http.MethodPost:
+		return strings.HasSuffix(req.URL.Path, "/graphql") && req.GetBody != nil
+	}
+	return false
+}
+
+// classify decides what to do with the outcome of an attempt: wait and
+// retry it, wait and return it, or return it right away when wait is 0
+func (t *RetryTransport) classify(resp *http.Response, err error, attempt int) (reason string, wait time.Duration, retry bool) {
+	if err != nil {
+		return "network error: " + err.Error(), t.backoff(attempt), true
+	}
+
+	reset := t.rateLimitReset(resp)
+	switch {
+	case resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests:
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			return "secondary rate limit", time.Duration(seconds) * time.Second, true
+		}
+		if reset > 0 {
+			return "rate limit exhausted", reset, true
+		}
+		if resp.StatusCode == http.StatusTooManyRequests || isSecondaryRateLimit(resp) {
+			return "secondary rate limit", max(t.backoff(attempt), secondaryRateLimitDelay), true
+		}
+	case resp.StatusCode >= 500 && resp.StatusCode != http.StatusNotImplemented:
+		return "server error " + resp.Status, t.backoff(attempt), true
+	case reset > 0:
+		return "rate limit exhausted", reset, false
+	}
+
+	return "", 0, false
+}
+
+// rateLimitReset returns how long until the rate limit resets if the
+// response says it was exhausted, or 0
+func (t *RetryTransport) rateLimitReset(resp *http.Response) time.Duration {
+	if resp.Header.Get("X-RateLimit-Remaining") != "0" {
+		return 0
+	}
+	reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return 0
+	}
+	// A second of margin for clock skew between the runner and GitHub
+	return time.Unix(reset, 0).Sub(t.now()) + time.Second
+}
+
+// backoff returns the exponential backoff before retry attempt+1, with up to
+// 50% jitter so concurrent requests don't retry in lockstep
+func (t *RetryTransport) backoff(attempt int) time.Duration {
+	delay := t.opts.BaseDelay << attempt
+	if delay <= 0 {
+		return time.Millisecond
+	}
+	return delay + rand.N(delay/2+1)
+}
+
+// isSecondaryRateLimit reports whether a 403 response is a secondary rate
+// limit without a Retry-After header. The body is kept readable.
+func isSecondaryRateLimit(resp *http.Response) bool {
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return err == nil && strings.Contains(strings.ToLower(string(body)), "secondary rate limit")
+}
+
+// sleepContext waits for d or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// IsRateLimit reports whether err is caused by an exhausted primary or
+// secondary GitHub rate limit, of the REST or the GraphQL API
+func IsRateLimit(err error) bool {
+	var rateLimitErr *github.RateLimitError
+	var abuseErr *github.AbuseRateLimitError
+	var gqlErr *GraphQLError
+	return errors.As(err, &rateLimitErr) || errors.As(err, &abuseErr) ||
+		(errors.As(err, &gqlErr) && gqlErr.Type == graphQLRateLimited)
+}
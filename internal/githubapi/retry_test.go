@@ -0,0 +1,198 @@
+package githubapi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v62/github"
+)
+
+// scriptedTransport returns its responses in order, one per request
+type scriptedTransport struct {
+	responses []*http.Response
+	requests  int
+}
+
+func (s *scriptedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp := s.responses[s.requests]
+	s.requests++
+	if resp == nil {
+		return nil, errors.New("connection reset")
+	}
+	return resp, nil
+}
+
+func response(status int, headers map[string]string, body string) *http.Response {
+	resp := &http.Response{
+		StatusCode: status,
+		Status:     strconv.Itoa(status) + " " + http.StatusText(status),
+		Header:     make(http.Header),
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+	for name, value := range headers {
+		resp.Header.Set(name, value)
+	}
+	return resp
+}
+
+func TestRetryTransport(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	reset := strconv.FormatInt(now.Add(30*time.Second).Unix(), 10)
+	exhausted := map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": reset}
+
+	tests := []struct {
+		name      string
+		method    string
+		responses []*http.Response
+		status    int
+		requests  int
+		waits     []time.Duration
+	}{
+		{
+			name:      "success is returned right away",
+			responses: []*http.Response{response(200, nil, "")},
+			status:    200,
+			requests:  1,
+		},
+		{
+			name:      "server errors are retried",
+			responses: []*http.Response{response(502, nil, ""), nil, response(200, nil, "")},
+			status:    200,
+			requests:  3,
+			waits:     []time.Duration{time.Second, 2 * time.Second},
+		},
+		{
+			name:      "retry after is honored",
+			responses: []*http.Response{response(403, map[string]string{"Retry-After": "7"}, ""), response(200, nil, "")},
+			status:    200,
+			requests:  2,
+			waits:     []time.Duration{7 * time.Second},
+		},
+		{
+			name:      "exhausted rate limit waits for the reset",
+			responses: []*http.Response{response(403, exhausted, ""), response(200, nil, "")},
+			status:    200,
+			requests:  2,
+			waits:     []time.Duration{31 * time.Second},
+		},
+		{
+			name:      "secondary rate limit without retry after waits a minute",
+			responses: []*http.Response{response(403, nil, `{"message": "You have exceeded a secondary rate limit"}`), response(200, nil, "")},
+			status:    200,
+			requests:  2,
+			waits:     []time.Duration{time.Minute},
+		},
+		{
+			name:      "last request before the limit waits before returning",
+			responses: []*http.Response{response(200, exhausted, "")},
+			status:    200,
+			requests:  1,
+			waits:     []time.Duration{31 * time.Second},
+		},
+		{
+			name:      "permission errors are not retried",
+			responses: []*http.Response{response(403, nil, `{"message": "Resource not accessible by integration"}`)},
+			status:    403,
+			requests:  1,
+		},
+		{
+			name:      "retries give up",
+			responses: []*http.Response{response(500, nil, ""), response(500, nil, ""), response(500, nil, ""), response(500, nil, "")},
+			status:    500,
+			requests:  4,
+			waits:     []time.Duration{time.Second, 2 * time.Second, 4 * time.Second},
+		},
+		{
+			name:      "rate limits beyond the maximum wait are returned",
+			responses: []*http.Response{response(429, map[string]string{"Retry-After": "3600"}, "")},
+			status:    429,
+			requests:  1,
+		},
+		{
+			name:      "only reads are retried",
+			method:    http.MethodPost,
+			responses: []*http.Response{response(502, nil, "")},
+			status:    502,
+			requests:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := &scriptedTransport{responses: tt.responses}
+			var waits []time.Duration
+			transport := NewRetryTransport(base, RetryOptions{MaxRetries: 3, BaseDelay: time.Second, MaxWait: 10 * time.Minute})
+			transport.now = func() time.Time { return now }
+			transport.sleep = func(ctx context.Context, d time.Duration) error {
+				waits = append(waits, d)
+				return nil
+			}
+
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			req, _ := http.NewRequest(method, "https://api.github.com/repos/o/r/pulls", nil)
+			resp, err := transport.RoundTrip(req)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if resp.StatusCode != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, resp.StatusCode)
+			}
+			if base.requests != tt.requests {
+				t.Errorf("Expected %d requests, got %d", tt.requests, base.requests)
+			}
+			if len(waits) != len(tt.waits) {
+				t.Fatalf("Expected waits %v, got %v", tt.waits, waits)
+			}
+			for i, wait := range waits {
+				// Backoffs have up to 50% jitter
+				if wait < tt.waits[i] || wait > tt.waits[i]*3/2 {
+					t.Errorf("Expected wait %d to be about %v, got %v", i, tt.waits[i], wait)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryTransportCanceled(t *testing.T) {
+	base := &scriptedTransport{responses: []*http.Response{response(503, nil, ""), response(200, nil, "")}}
+	transport := NewRetryTransport(base, DefaultRetryOptions())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "https://api.github.com/", nil)
+	if _, err := transport.RoundTrip(req); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if base.requests != 1 {
+		t.Errorf("Expected no retry after cancellation, got %d requests", base.requests)
+	}
+}
+
+func TestIsRateLimit(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected bool
+	}{
+		{&github.RateLimitError{Message: "API rate limit exceeded"}, true},
+		{&github.AbuseRateLimitError{Message: "secondary rate limit"}, true},
+		{&GraphQLError{Type: "RATE_LIMITED", Message: "API rate limit exceeded"}, true},
+		{&GraphQLError{Type: "NOT_FOUND", Message: "Could not resolve to a PullRequest"}, false},
+		{errors.New("not found"), false},
+		{nil, false},
+	}
+
+	for _, tt := range tests {
+		if got := IsRateLimit(tt.err); got != tt.expected {
+			t.Errorf("IsRateLimit(%v): expected %v, got %v", tt.err, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package githubapi
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v62/github"
+)
+
+// PullRequest is the normalized pull request model shared by all sources
+type PullRequest struct {
+	Number    int       `json:"number"`
+	Title     string    `json:"title"`
+	Author    string    `json:"author"`
+	State     string    `json:"state"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Review is the normalized pull request review model
+type Review struct {
+	ID          int64     `json:"id"`
+	User        string    `json:"user"`
+	Body        string    `json:"body"`
+	State       string    `json:"state"`
+	SubmittedAt time.Time `json:"submitted_at"`
+	Reactions   int       `json:"reactions"` // Total number of reactions on the review
+}
+
+// Comment is the normalized model for review comments and issue comments
+type Comment struct {
+	ID        int64     `json:"id"`
+	User      string    `json:"user"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+	Reactions int       `json:"reactions"` // Total number of reactions on the comment
+}
+
+// Reaction is the normalized reaction model
+type Reaction struct {
+	ID        int64     `json:"id"`
+	User      string    `json:"user"`
+	Content   string    `json:"content"`    // "+1", "-1", "laugh", "confused", "heart", "hooray", "rocket" or "eyes"
+	CreatedAt time.Time `json:"created_at"` // When the reaction was added
+}
+
+// Source provides the pull request activity of a single repository
+type Source interface {
+	ListPullRequests(ctx context.Context) ([]PullRequest, error)
+	ListPullRequestsUpdatedSince(ctx context.Context, since time.Time) ([]PullRequest, error)
+	ListReviews(ctx context.Context, prNumber int) ([]Review, error)
+	ListReviewComments(ctx context.Context, prNumber int) ([]Comment, error)
+	ListIssueComments(ctx context.Context, prNumber int) ([]Comment, error)
+	ListPullRequestReactions(ctx context.Context, prNumber int) ([]Reaction, error)
+	ListReviewReactions(ctx context.Context, reviewID int64) ([]Reaction, error)
+	ListReviewCommentReactions(ctx context.Context, commentID int64) ([]Reaction, error)
+	ListIssueCommentReactions(ctx context.Context, commentID int64) ([]Reaction, error)
+}
+
+// GitHubSource is a Source backed by the GitHub REST API
+type GitHubSource struct {
+	client *github.Client
+	owner  string
+	repo   string
+}
+
+// NewGitHubSource creates a new source for the given repository
+func NewGitHubSource(client *github.Client, owner, repo string) *GitHubSource {
+	return &GitHubSource{
+		client: client,
+		owner:  owner,
+		repo:   repo,
+	}
+}
+
+// ListPullRequests lists all pull requests in the repository
+func (s *GitHubSource) ListPullRequests(ctx context.Context) ([]PullRequest, error) {
+	prs, err := FetchAllPullRequests(ctx, s.client, s.owner, s.repo)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertPullRequests(prs), nil
+}
+
+// ListPullRequestsUpdatedSince lists the pull requests updated at or after
+// since, most recently updated first
+func (s *GitHubSource) ListPullRequestsUpdatedSince(ctx context.Context, since time.Time) ([]PullRequest, error) {
+	prs, err := FetchPullRequestsUpdatedSince(ctx, s.client, s.owner, s.repo, since)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertPullRequests(prs), nil
+}
+
+// ListReviews lists all reviews on a pull request
+func (s *GitHubSource) ListReviews(ctx context.Context, prNumber int) ([]Review, error) {
+	reviews, err := FetchPullRequestReviews(ctx, s.client, s.owner, s.repo, prNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Review, 0, len(reviews))
+	for _, review := range reviews {
+		result = append(result, convertReview(review))
+	}
+
+	return result, nil
+}
+
+// ListReviewComments lists all inline review comments on a pull request
+func (s *GitHubSource) ListReviewComments(ctx context.Context, prNumber int) ([]Comment, error) {
+	comments, err := FetchPullRequestComments(ctx, s.client, s.owner, s.repo, prNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Comment, 0, len(comments))
+	for _, comment := range comments {
+		result = append(result, convertReviewComment(comment))
+	}
+
+	return result, nil
+}
+
+// ListIssueComments lists all conversation comments on a pull request
+func (s *GitHubSource) ListIssueComments(ctx context.Context, prNumber int) ([]Comment, error) {
+	comments, err := FetchIssueComments(ctx, s.client, s.owner, s.repo, prNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Comment, 0, len(comments))
+	for _, comment := range comments {
+		result = append(result, convertIssueComment(comment))
+	}
+
+	return result, nil
+}
+
+// ListPullRequestReactions lists all reactions on a pull request
+func (s *GitHubSource) ListPullRequestReactions(ctx context.Context, prNumber int) ([]Reaction, error) {
+	reactions, err := FetchPullRequestReactions(ctx, s.client, s.owner, s.repo, prNumber)
+	if err != nil {
+		return nil, err
+	}
+	return convertReactions(reactions), nil
+}
+
+// ListReviewReactions lists all reactions on a review. The REST API doesn't
+// expose them, so there are none.
+func (s *GitHubSource) ListReviewReactions(ctx context.Context, reviewID int64) ([]Reaction, error) {
+	return nil, nil
+}
+
+// ListReviewCommentReactions lists all reactions on an inline review comment
+func (s *GitHubSource) ListReviewCommentReactions(ctx context.Context, commentID int64) ([]Reaction, error) {
+	reactions, err := FetchReviewCommentReactions(ctx, s.client, s.owner, s.repo, commentID)
+	if err != nil {
+		return nil, err
+	}
+	return convertReactions(reactions), nil
+}
+
+// ListIssueCommentReactions lists all reactions on a conversation comment
+func (s *GitHubSource) ListIssueCommentReactions(ctx context.Context, commentID int64) ([]Reaction, error) {
+	reactions, err := FetchIssueCommentReactions(ctx, s.client, s.owner, s.repo, commentID)
+	if err != nil {
+		return nil, err
+	}
+	return convertReactions(reactions), nil
+}
+
+// convertReactions normalizes REST reactions
+func convertReactions(reactions []*TimedReaction) []Reaction {
+	result := make([]Reaction, 0, len(reactions))
+	for _, reaction := range reactions {
+		result = append(result, Reaction{
+			ID:        reaction.GetID(),
+			User:      reaction.GetUser().GetLogin(),
+			Content:   reaction.GetContent(),
+			CreatedAt: reaction.CreatedAt.Time,
+		})
+	}
+	return result
+}
+
+// convertPullRequests normalizes a list of go-github pull requests
+func convertPullRequests(prs []*github.PullRequest) []PullRequest {
+	result := make([]PullRequest, 0, len(prs))
+	for _, pr := range prs {
+		result = append(result, convertPullRequest(pr))
+	}
+	return result
+}
+
+// convertPullRequest normalizes a go-github pull request
+func convertPullRequest(pr *github.PullRequest) PullRequest {
+	return PullRequest{
+		Number:    pr.GetNumber(),
+		Title:     pr.GetTitle(),
+		Author:    pr.GetUser().GetLogin(),
+		State:     pr.GetState(),
+		CreatedAt: pr.GetCreatedAt().Time,
+		UpdatedAt: pr.GetUpdatedAt().Time,
+	}
+}
+
+// convertReview normalizes a go-github review. Webhook payloads report the
+// state in lower case, the REST API in upper case.
+func convertReview(review *github.PullRequestReview) Review {
+	return Review{
+		ID:          review.GetID(),
+		User:        review.GetUser().GetLogin(),
+		Body:        review.GetBody(),
+		State:       strings.ToUpper(review.GetState()),
+		SubmittedAt: review.GetSubmittedAt().Time,
+	}
+}
+
+// convertReviewComment normalizes a go-github inline review comment
+func convertReviewComment(comment *github.PullRequestComment) Comment {
+	return Comment{
+		ID:        comment.GetID(),
+		User:      comment.GetUser().GetLogin(),
+		Body:      comment.GetBody(),
+		CreatedAt: comment.GetCreatedAt().Time,
+		Reactions: comment.GetReactions().GetTotalCount(),
+	}
+}
+
+// convertIssueComment normalizes a go-github conversation comment
+func convertIssueComment(comment *github.IssueComment) Comment {
+	return Comment{
+		ID:        comment.GetID(),
+		User:      comment.GetUser().GetLogin(),
+		Body:      comment.GetBody(),
+		CreatedAt: comment.GetCreatedAt().Time,
+		Reactions: comment.GetReactions().GetTotalCount(),
+	}
+}
@@ -0,0 +1,97 @@
+package githubapi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v62/github"
+)
+
+func TestGitHubSourceListPullRequestsUpdatedSince(t *testing.T) {
+	updated := []string{"2024-03-05T00:00:00Z", "2024-03-01T00:00:00Z", "2024-02-20T00:00:00Z"}
+	pages := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pages++
+		query := r.URL.Query()
+		if query.Get("sort") != "updated" || query.Get("direction") != "desc" || query.Get("state") != "all" {
+			t.Errorf("Expected all PRs sorted by update time, got %s", r.URL.RawQuery)
+		}
+
+		// Two PRs per page, linking to the next page
+		page := 1
+		fmt.Sscanf(query.Get("page"), "%d", &page)
+		if page == 1 {
+			next := *r.URL
+			next.RawQuery = url.Values{"state": {"all"}, "sort": {"updated"}, "direction": {"desc"}, "page": {"2"}}.Encode()
+			w.Header().Set("Link", fmt.Sprintf(`<http://%s%s>; rel="next"`, r.Host, next.RequestURI()))
+			fmt.Fprintf(w, `[{"number": 3, "updated_at": %q}, {"number": 2, "updated_at": %q}]`, updated[0], updated[1])
+			return
+		}
+		fmt.Fprintf(w, `[{"number": 1, "updated_at": %q}]`, updated[2])
+	}))
+	defer server.Close()
+
+	client := github.NewClient(server.Client())
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+	source := NewGitHubSource(client, "owner", "repo")
+
+	tests := []struct {
+		name     string
+		since    time.Time
+		expected []int
+		pages    int
+	}{
+		{"stops within the first page", time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC), []int{3}, 1},
+		{"includes PRs updated at since", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), []int{3, 2}, 2},
+		{"pages until the last PR", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), []int{3, 2, 1}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pages = 0
+			prs, err := source.ListPullRequestsUpdatedSince(context.Background(), tt.since)
+			if err != nil {
+				t.Fatalf("Failed to list pull requests: %v", err)
+			}
+			if len(prs) != len(tt.expected) {
+				t.Fatalf("Expected PRs %v, got %+v", tt.expected, prs)
+			}
+			for i, pr := range prs {
+				if pr.Number != tt.expected[i] {
+					t.Errorf("Expected PRs %v, got %+v", tt.expected, prs)
+				}
+			}
+			if pages != tt.pages {
+				t.Errorf("Expected %d pages to be fetched, got %d", tt.pages, pages)
+			}
+		})
+	}
+}
+
+func TestGitHubSourceReactionTimes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/owner/repo/issues/comments/42/reactions" {
+			t.Errorf("Unexpected request to %s", r.URL.Path)
+		}
+		fmt.Fprint(w, `[{"id": 3, "user": {"login": "carol"}, "content": "heart", "created_at": "2024-03-05T10:00:00Z"}]`)
+	}))
+	defer server.Close()
+
+	client := github.NewClient(server.Client())
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+	source := NewGitHubSource(client, "owner", "repo")
+
+	reactions, err := source.ListIssueCommentReactions(context.Background(), 42)
+	if err != nil {
+		t.Fatalf("Failed to list reactions: %v", err)
+	}
+	expected := Reaction{ID: 3, User: "carol", Content: "heart", CreatedAt: time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC)}
+	if len(reactions) != 1 || reactions[0] != expected {
+		t.Errorf("Expected %+v, got %+v", expected, reactions)
+	}
+}
@@ -2,10 +2,11 @@ package karma
 
 import (
 	"fmt"
-	"os"
 	"sort"
 	"strings"
 	"time"
+
+	"github.com/master-wayne7/reviewer-karma-action/internal/atomicfile"
 )
 
 // Reviewer represents a user with their karma points
@@ -17,6 +18,8 @@ type Reviewer struct {
 // Leaderboard represents the karma leaderboard
 type Leaderboard struct {
 	Reviewers []Reviewer `json:"reviewers"`
+	Window    Window     `json:"-"` // Time range the points were scored in
+	Title     string     `json:"-"` // Heading of the rankings, "Current Rankings" when empty
 }
 
 // Positive emojis that award bonus points
@@ -25,13 +28,20 @@ var positiveEmojis = map[string]bool{
 	"💯": true, "✅": true, "⭐": true, "❤️": true, "👏": true,
 }
 
+// Default patterns used to detect bot accounts
+var defaultBotPatterns = []string{"[bot]", "-bot", "bot-", "github-actions[bot]", "dependabot[bot]"}
+
 // IsBot checks if a username belongs to a bot
 func IsBot(username string) bool {
-	botSuffixes := []string{"[bot]", "-bot", "bot-", "github-actions[bot]", "dependabot[bot]"}
+	return IsBotWithPatterns(username, defaultBotPatterns)
+}
+
+// IsBotWithPatterns checks if a username contains any of the given bot patterns
+func IsBotWithPatterns(username string, patterns []string) bool {
 	usernameLower := strings.ToLower(username)
 
-	for _, suffix := range botSuffixes {
-		if strings.Contains(usernameLower, strings.ToLower(suffix)) {
+	for _, pattern := range patterns {
+		if strings.Contains(usernameLower, strings.ToLower(pattern)) {
 			return true
 		}
 	}
@@ -54,6 +64,21 @@ func HasPositiveEmoji(text string) bool {
 	return false
 }
 
+// HasPositiveEmojiIn checks if text contains any of the given emojis
+func HasPositiveEmojiIn(text string, emojis []string) bool {
+	if text == "" {
+		return false
+	}
+
+	for _, emoji := range emojis {
+		if emoji != "" && strings.Contains(text, emoji) {
+			return true
+		}
+	}
+
+	return false
+}
+
 // IsConstructiveComment checks if a comment is constructive
 func IsConstructiveComment(text string) bool {
 	if text == "" {
@@ -90,20 +115,22 @@ func GenerateLeaderboard(reviewerKarma map[string]int) Leaderboard {
 		})
 	}
 
-	// Sort by points (descending)
+	// Sort by points (descending), ties by username so output is stable
 	sort.Slice(reviewers, func(i, j int) bool {
-		return reviewers[i].Points > reviewers[j].Points
+		if reviewers[i].Points != reviewers[j].Points {
+			return reviewers[i].Points > reviewers[j].Points
+		}
+		return reviewers[i].Username < reviewers[j].Username
 	})
 
 	return Leaderboard{Reviewers: reviewers}
 }
 
-// WriteLeaderboardFile writes the leaderboard to REVIEWERS.md
-func WriteLeaderboardFile(leaderboard Leaderboard) error {
-	content := generateLeaderboardMarkdown(leaderboard)
+// WriteLeaderboards writes one rankings table per leaderboard to the given path, describing the engine's scoring rules
+func WriteLeaderboards(path string, leaderboards []Leaderboard, engine *Engine) error {
+	content := generateLeaderboardsMarkdown(leaderboards, engine)
 
-	// Write to REVIEWERS.md
-	err := os.WriteFile("REVIEWERS.md", []byte(content), 0644)
+	err := atomicfile.Write(path, []byte(content), 0644)
 	if err != nil {
 		return err
 	}
@@ -111,35 +138,41 @@ func WriteLeaderboardFile(leaderboard Leaderboard) error {
 	return nil
 }
 
-// WriteLeaderboardFileWithConfig writes the leaderboard to REVIEWERS.md with custom scoring display
-func WriteLeaderboardFileWithConfig(leaderboard Leaderboard, reviewPoint, emojiPoint, commentPoint int) error {
-	content := generateLeaderboardMarkdownWithConfig(leaderboard, reviewPoint, emojiPoint, commentPoint)
+// generateLeaderboardsMarkdown generates markdown content with a rankings table per leaderboard
+func generateLeaderboardsMarkdown(leaderboards []Leaderboard, engine *Engine) string {
+	var sb strings.Builder
 
-	// Write to REVIEWERS.md
-	err := os.WriteFile("REVIEWERS.md", []byte(content), 0644)
-	if err != nil {
-		return err
+	sb.WriteString("# Reviewer Karma Leaderboard\n\n")
+	sb.WriteString("This leaderboard tracks reviewer engagement and contributions to the repository.\n\n")
+	sb.WriteString("## Scoring System\n\n")
+	for _, line := range engine.Describe() {
+		sb.WriteString("- " + line + "\n")
+	}
+	sb.WriteString("\n")
+	for _, leaderboard := range leaderboards {
+		writeRankings(&sb, leaderboard)
 	}
 
-	return nil
-}
+	sb.WriteString("---\n")
+	sb.WriteString(fmt.Sprintf("*Last updated: %s*\n", time.Now().Format("2006-01-02 15:04:05 UTC")))
 
-// generateLeaderboardMarkdown generates markdown content for the leaderboard
-func generateLeaderboardMarkdown(leaderboard Leaderboard) string {
-	return generateLeaderboardMarkdownWithConfig(leaderboard, 1, 2, 1) // Default values
+	return sb.String()
 }
 
-// generateLeaderboardMarkdownWithConfig generates markdown content with custom scoring display
-func generateLeaderboardMarkdownWithConfig(leaderboard Leaderboard, reviewPoint, emojiPoint, commentPoint int) string {
-	var sb strings.Builder
-
-	sb.WriteString("# Reviewer Karma Leaderboard\n\n")
-	sb.WriteString("This leaderboard tracks reviewer engagement and contributions to the repository.\n\n")
-	sb.WriteString("## Scoring System\n\n")
-	sb.WriteString(fmt.Sprintf("- ✅ Giving a code review: +%d point(s)\n", reviewPoint))
-	sb.WriteString(fmt.Sprintf("- ✅ Review includes a positive emoji (👍, 🔥, 😄, etc.): +%d point(s)\n", emojiPoint))
-	sb.WriteString(fmt.Sprintf("- ✅ Review comment contains a constructive message (>10 words): +%d point(s)\n\n", commentPoint))
-	sb.WriteString("## Current Rankings\n\n")
+// writeRankings writes the heading and rankings table of a leaderboard
+func writeRankings(sb *strings.Builder, leaderboard Leaderboard) {
+	title := leaderboard.Title
+	if title == "" {
+		title = "Current Rankings"
+	}
+	sb.WriteString("## " + title + "\n\n")
+	if !leaderboard.Window.IsZero() {
+		sb.WriteString("Activity " + leaderboard.Window.String() + ".\n\n")
+		if len(leaderboard.Reviewers) == 0 {
+			sb.WriteString("No activity in this period.\n\n")
+			return
+		}
+	}
 	sb.WriteString("| Rank | Reviewer | Points |\n")
 	sb.WriteString("|------|----------|--------|\n")
 
@@ -155,9 +188,5 @@ func generateLeaderboardMarkdownWithConfig(leaderboard Leaderboard, reviewPoint,
 
 		sb.WriteString(fmt.Sprintf("| %d | %s@%s | %d |\n", rank, medal, reviewer.Username, reviewer.Points))
 	}
-
-	sb.WriteString("\n---\n")
-	sb.WriteString(fmt.Sprintf("*Last updated: %s*\n", time.Now().Format("2006-01-02 15:04:05 UTC")))
-
-	return sb.String()
+	sb.WriteString("\n")
 }
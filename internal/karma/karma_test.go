@@ -3,6 +3,7 @@ package karma
 import (
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestIsBot(t *testing.T) {
@@ -111,6 +112,49 @@ func TestGenerateLeaderboard(t *testing.T) {
 	}
 }
 
+func TestGenerateLeaderboardTies(t *testing.T) {
+	leaderboard := GenerateLeaderboard(map[string]int{"dave": 3, "bob": 5, "carol": 3, "alice": 3})
+
+	expected := []string{"bob", "alice", "carol", "dave"}
+	for i, username := range expected {
+		if leaderboard.Reviewers[i].Username != username {
+			t.Errorf("Expected %s at rank %d, got %s", username, i+1, leaderboard.Reviewers[i].Username)
+		}
+	}
+}
+
+func TestGenerateLeaderboardsMarkdown(t *testing.T) {
+	week := GenerateLeaderboard(map[string]int{"bob": 2})
+	week.Title = "This Week"
+	week.Window = Window{Since: time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)}
+
+	today := GenerateLeaderboard(nil)
+	today.Title = "Today"
+	today.Window = Window{Since: time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)}
+
+	allTime := GenerateLeaderboard(map[string]int{"alice": 5, "bob": 7})
+	allTime.Title = "All Time"
+
+	content := generateLeaderboardsMarkdown([]Leaderboard{week, today, allTime}, NewEngine(testRules(1, 2, 1)...))
+
+	expected := []string{
+		"## This Week\n\nActivity since 2024-03-04.\n\n| Rank | Reviewer | Points |\n|------|----------|--------|\n| 1 | 🥇 @bob | 2 |\n\n",
+		"## Today\n\nActivity since 2024-03-10.\n\nNo activity in this period.\n\n",
+		"## All Time\n\n| Rank | Reviewer | Points |\n|------|----------|--------|\n| 1 | 🥇 @bob | 7 |\n| 2 | 🥈 @alice | 5 |\n\n---\n",
+	}
+	last := -1
+	for _, section := range expected {
+		index := strings.Index(content, section)
+		if index <= last {
+			t.Fatalf("Expected section %q after the previous one in:\n%s", section, content)
+		}
+		last = index
+	}
+	if strings.Contains(content, "Current Rankings") {
+		t.Error("Expected titled leaderboards to replace the Current Rankings heading")
+	}
+}
+
 func TestDebugConstructiveComment(t *testing.T) {
 	text := "The implementation looks good but we should consider adding more test cases"
 
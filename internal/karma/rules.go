@@ -0,0 +1,552 @@
+package karma
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// EventKind identifies the type of activity being scored
+type EventKind string
+
+const (
+	// EventReview is a submitted pull request review
+	EventReview EventKind = "review"
+	// EventReviewComment is an inline comment on a pull request diff
+	EventReviewComment EventKind = "review_comment"
+	// EventIssueComment is a comment in a pull request's conversation tab
+	EventIssueComment EventKind = "issue_comment"
+	// EventReaction is a reaction on a pull request or comment
+	EventReaction EventKind = "reaction"
+)
+
+// Review states as reported by the GitHub API
+const (
+	ReviewStateApproved         = "APPROVED"
+	ReviewStateChangesRequested = "CHANGES_REQUESTED"
+	ReviewStateCommented        = "COMMENTED"
+	ReviewStateDismissed        = "DISMISSED"
+	ReviewStatePending          = "PENDING"
+)
+
+// Event is a normalized review or comment that rules are evaluated against
+type Event struct {
+	Kind      EventKind
+	ID        int64
+	PRNumber  int
+	PRAuthor  string // Author of the pull request the event belongs to
+	User      string
+	Body      string
+	State     string // Review state, empty for comments
+	Reaction  string // Reaction content such as "+1" or "rocket", empty unless Kind is EventReaction
+	CreatedAt time.Time
+}
+
+// Award is a single itemized point award produced by a rule
+type Award struct {
+	User   string
+	Rule   string
+	Points int
+	Reason string
+
+	// The event that produced the award, set by the engine
+	PRNumber  int
+	EventKind EventKind
+	EventID   int64
+	CreatedAt time.Time
+}
+
+// Rule evaluates an event and returns the awards it produces, if any
+type Rule interface {
+	Name() string
+	Evaluate(event Event) []Award
+}
+
+// Filter excludes events from scoring before any rule is evaluated
+type Filter interface {
+	Name() string
+	Exclude(event Event) bool
+}
+
+// Modifier adjusts the awards produced for an event after all rules have run
+type Modifier interface {
+	Modify(event Event, award Award) Award
+}
+
+// Result is the outcome of evaluating a batch of events
+type Result struct {
+	Awards   []Award
+	Excluded map[string]int // Filter name -> number of events it excluded
+}
+
+// CappedRule is implemented by rules whose awards are capped per user
+// within a single call to EvaluateAll
+type CappedRule interface {
+	Rule
+	Cap() int
+}
+
+// Describer is implemented by rules and filters that document themselves
+// in the "Scoring System" section of the leaderboard
+type Describer interface {
+	Describe() []string
+}
+
+// Engine evaluates an ordered list of rules against events
+type Engine struct {
+	rules       []Rule
+	filters     []Filter
+	botPatterns []string
+}
+
+// NewEngine creates a new engine that evaluates rules in the given order
+func NewEngine(rules ...Rule) *Engine {
+	return &Engine{rules: rules}
+}
+
+// Rules returns the rules evaluated by the engine
+func (e *Engine) Rules() []Rule {
+	return e.rules
+}
+
+// Filters returns the filters applied by the engine
+func (e *Engine) Filters() []Filter {
+	return e.filters
+}
+
+// AddFilters appends filters that exclude events from scoring
+func (e *Engine) AddFilters(filters ...Filter) {
+	e.filters = append(e.filters, filters...)
+}
+
+// SetBotPatterns replaces the default patterns used to skip bot accounts
+func (e *Engine) SetBotPatterns(patterns []string) {
+	e.botPatterns = patterns
+}
+
+// isBot checks the username against the engine's bot patterns
+func (e *Engine) isBot(username string) bool {
+	if e.botPatterns == nil {
+		return IsBot(username)
+	}
+	return IsBotWithPatterns(username, e.botPatterns)
+}
+
+// Describe returns the scoring system lines of all self-describing rules and filters
+func (e *Engine) Describe() []string {
+	var lines []string
+	for _, rule := range e.rules {
+		if d, ok := rule.(Describer); ok {
+			lines = append(lines, d.Describe()...)
+		}
+	}
+	for _, filter := range e.filters {
+		if d, ok := filter.(Describer); ok {
+			lines = append(lines, d.Describe()...)
+		}
+	}
+	return lines
+}
+
+// Evaluate runs every rule against the event and returns the combined awards
+func (e *Engine) Evaluate(event Event) []Award {
+	if event.User == "" || e.isBot(event.User) {
+		return nil
+	}
+
+	// Pending reviews have not been submitted yet
+	if event.Kind == EventReview && event.State == ReviewStatePending {
+		return nil
+	}
+
+	if e.ExcludedBy(event) != "" {
+		return nil
+	}
+
+	var awards []Award
+	for _, rule := range e.rules {
+		awards = append(awards, rule.Evaluate(event)...)
+	}
+
+	for _, filter := range e.filters {
+		if modifier, ok := filter.(Modifier); ok {
+			for i := range awards {
+				awards[i] = modifier.Modify(event, awards[i])
+			}
+		}
+	}
+
+	for i := range awards {
+		awards[i].PRNumber = event.PRNumber
+		awards[i].EventKind = event.Kind
+		awards[i].EventID = event.ID
+		awards[i].CreatedAt = event.CreatedAt
+	}
+
+	return awards
+}
+
+// ExcludedBy returns the name of the first filter that excludes the event,
+// or an empty string if no filter does
+func (e *Engine) ExcludedBy(event Event) string {
+	for _, filter := range e.filters {
+		if filter.Exclude(event) {
+			return filter.Name()
+		}
+	}
+	return ""
+}
+
+// EvaluateAll evaluates a batch of events, normally those of one pull request,
+// and trims the awards of capped rules so no user exceeds a rule's cap. The
+// result counts the events excluded by each filter.
+func (e *Engine) EvaluateAll(events []Event) Result {
+	caps := make(map[string]int)
+	for _, rule := range e.rules {
+		if capped, ok := rule.(CappedRule); ok && capped.Cap() > 0 {
+			caps[rule.Name()] = capped.Cap()
+		}
+	}
+
+	used := make(map[string]int) // rule + user -> points awarded so far
+	result := Result{Excluded: make(map[string]int)}
+	for _, event := range events {
+		if event.User != "" && !e.isBot(event.User) {
+			if name := e.ExcludedBy(event); name != "" {
+				result.Excluded[name]++
+				continue
+			}
+		}
+
+		for _, award := range e.Evaluate(event) {
+			if limit, ok := caps[award.Rule]; ok {
+				key := award.Rule + "/" + award.User
+				remaining := limit - used[key]
+				if remaining <= 0 {
+					continue
+				}
+				if award.Points > remaining {
+					award.Points = remaining
+				}
+				used[key] += award.Points
+			}
+			result.Awards = append(result.Awards, award)
+		}
+	}
+
+	return result
+}
+
+// Score evaluates all events as one batch and sums the awarded points per user
+func (e *Engine) Score(events []Event) map[string]int {
+	totals := make(map[string]int)
+	for _, award := range e.EvaluateAll(events).Awards {
+		totals[award.User] += award.Points
+	}
+	return totals
+}
+
+// ReviewStateRule awards points for a review depending on its state
+type ReviewStateRule struct {
+	Approved         int
+	ChangesRequested int
+	Commented        int
+	Dismissed        int
+}
+
+// Name returns the rule name
+func (r ReviewStateRule) Name() string {
+	return "review"
+}
+
+// Evaluate awards the points configured for the review's state
+func (r ReviewStateRule) Evaluate(event Event) []Award {
+	if event.Kind != EventReview {
+		return nil
+	}
+
+	var points int
+	var reason string
+	switch event.State {
+	case ReviewStateApproved:
+		points, reason = r.Approved, "approving review"
+	case ReviewStateChangesRequested:
+		points, reason = r.ChangesRequested, "review requesting changes"
+	case ReviewStateDismissed:
+		points, reason = r.Dismissed, "dismissed review"
+	default:
+		points, reason = r.Commented, "code review"
+	}
+
+	return []Award{{
+		User:   event.User,
+		Rule:   r.Name(),
+		Points: points,
+		Reason: reason,
+	}}
+}
+
+// Describe returns the scoring system lines for the rule
+func (r ReviewStateRule) Describe() []string {
+	lines := []string{
+		fmt.Sprintf("✅ Approving a pull request: +%d point(s)", r.Approved),
+		fmt.Sprintf("✅ Requesting changes: +%d point(s)", r.ChangesRequested),
+		fmt.Sprintf("✅ Leaving a review comment: +%d point(s)", r.Commented),
+	}
+	if r.Dismissed != 0 {
+		lines = append(lines, fmt.Sprintf("⚠️ Review dismissed: %+d point(s)", r.Dismissed))
+	}
+	return lines
+}
+
+// PositiveEmojiRule awards points for reviews or comments containing a positive emoji
+type PositiveEmojiRule struct {
+	Points int
+	Emojis []string // Overrides the default positive emojis when set
+}
+
+// Name returns the rule name
+func (r PositiveEmojiRule) Name() string {
+	return "positive_emoji"
+}
+
+// Evaluate awards points when the event body contains a positive emoji
+func (r PositiveEmojiRule) Evaluate(event Event) []Award {
+	if r.Points == 0 || !r.matches(event.Body) {
+		return nil
+	}
+
+	reason := "positive emoji"
+	if event.Kind != EventReview {
+		reason = "positive emoji in comment"
+	}
+
+	return []Award{{
+		User:   event.User,
+		Rule:   r.Name(),
+		Points: r.Points,
+		Reason: reason,
+	}}
+}
+
+// matches checks the text against the rule's emojis
+func (r PositiveEmojiRule) matches(text string) bool {
+	if len(r.Emojis) == 0 {
+		return HasPositiveEmoji(text)
+	}
+	return HasPositiveEmojiIn(text, r.Emojis)
+}
+
+// Describe returns the scoring system lines for the rule
+func (r PositiveEmojiRule) Describe() []string {
+	examples := "👍, 🔥, 😄, etc."
+	if len(r.Emojis) > 0 {
+		examples = strings.Join(r.Emojis, ", ")
+	}
+	return []string{fmt.Sprintf("✅ Review includes a positive emoji (%s): +%d point(s)", examples, r.Points)}
+}
+
+// ConstructiveCommentRule awards points for constructive review or comment bodies
+type ConstructiveCommentRule struct {
+	Points int
+}
+
+// Name returns the rule name
+func (r ConstructiveCommentRule) Name() string {
+	return "constructive_comment"
+}
+
+// Evaluate awards points when the event body is a constructive comment
+func (r ConstructiveCommentRule) Evaluate(event Event) []Award {
+	if r.Points == 0 || !IsConstructiveComment(event.Body) {
+		return nil
+	}
+
+	return []Award{{
+		User:   event.User,
+		Rule:   r.Name(),
+		Points: r.Points,
+		Reason: "constructive comment",
+	}}
+}
+
+// Describe returns the scoring system lines for the rule
+func (r ConstructiveCommentRule) Describe() []string {
+	return []string{fmt.Sprintf("✅ Review comment contains a constructive message (>10 words): +%d point(s)", r.Points)}
+}
+
+// DismissedReviewFilter excludes dismissed reviews so they earn no points at all,
+// revoking any points previously awarded when their pull request is re-scored
+type DismissedReviewFilter struct{}
+
+// Name returns the filter name
+func (f DismissedReviewFilter) Name() string {
+	return "dismissed_review"
+}
+
+// Exclude reports whether the event is a dismissed review
+func (f DismissedReviewFilter) Exclude(event Event) bool {
+	return event.Kind == EventReview && event.State == ReviewStateDismissed
+}
+
+// Describe returns the scoring system lines for the filter
+func (f DismissedReviewFilter) Describe() []string {
+	return []string{"❌ Dismissed reviews earn no points"}
+}
+
+// IssueCommentRule awards points for commenting in a pull request's conversation
+type IssueCommentRule struct {
+	Points int
+}
+
+// Name returns the rule name
+func (r IssueCommentRule) Name() string {
+	return "issue_comment"
+}
+
+// Evaluate awards points for conversation comments
+func (r IssueCommentRule) Evaluate(event Event) []Award {
+	if event.Kind != EventIssueComment || r.Points == 0 {
+		return nil
+	}
+
+	return []Award{{
+		User:   event.User,
+		Rule:   r.Name(),
+		Points: r.Points,
+		Reason: "conversation comment",
+	}}
+}
+
+// Describe returns the scoring system lines for the rule
+func (r IssueCommentRule) Describe() []string {
+	if r.Points == 0 {
+		return nil
+	}
+	return []string{fmt.Sprintf("✅ Commenting in a pull request conversation: +%d point(s)", r.Points)}
+}
+
+// Emoji shown for each GitHub reaction type
+var reactionEmojis = map[string]string{
+	"+1": "👍", "-1": "👎", "laugh": "😄", "confused": "😕",
+	"heart": "❤️", "hooray": "🎉", "rocket": "🚀", "eyes": "👀",
+}
+
+// ReactionTypes lists the reaction types supported by GitHub
+var ReactionTypes = []string{"+1", "-1", "laugh", "confused", "heart", "hooray", "rocket", "eyes"}
+
+// ReactionRule awards points to users who react to pull requests and comments
+type ReactionRule struct {
+	Points   map[string]int // Reaction type -> points
+	MaxPerPR int            // Maximum reaction points per user per pull request, 0 for no cap
+}
+
+// Name returns the rule name
+func (r ReactionRule) Name() string {
+	return "reaction"
+}
+
+// Cap returns the maximum reaction points per user per pull request
+func (r ReactionRule) Cap() int {
+	return r.MaxPerPR
+}
+
+// Evaluate awards the points configured for the reaction type
+func (r ReactionRule) Evaluate(event Event) []Award {
+	if event.Kind != EventReaction {
+		return nil
+	}
+
+	points := r.Points[event.Reaction]
+	if points == 0 {
+		return nil
+	}
+
+	return []Award{{
+		User:   event.User,
+		Rule:   r.Name(),
+		Points: points,
+		Reason: reactionEmojis[event.Reaction] + " reaction",
+	}}
+}
+
+// Describe returns the scoring system lines for the rule
+func (r ReactionRule) Describe() []string {
+	var weights []string
+	for _, reaction := range ReactionTypes {
+		if points := r.Points[reaction]; points != 0 {
+			weights = append(weights, fmt.Sprintf("%s %+d", reactionEmojis[reaction], points))
+		}
+	}
+	if len(weights) == 0 {
+		return nil
+	}
+
+	line := "✅ Reacting to a pull request or comment: " + strings.Join(weights, ", ")
+	if r.MaxPerPR > 0 {
+		line += fmt.Sprintf(" (up to %d point(s) per pull request)", r.MaxPerPR)
+	}
+	return []string{line}
+}
+
+// ExcludedUserFilter excludes events from a fixed set of users
+type ExcludedUserFilter struct {
+	Users []string
+}
+
+// Name returns the filter name
+func (f ExcludedUserFilter) Name() string {
+	return "excluded_user"
+}
+
+// Exclude reports whether the event's user is excluded
+func (f ExcludedUserFilter) Exclude(event Event) bool {
+	for _, user := range f.Users {
+		if strings.EqualFold(user, event.User) {
+			return true
+		}
+	}
+	return false
+}
+
+// SelfActivityFilter handles activity by the author on their own pull request.
+// With Percent 0 the activity is excluded, otherwise its awards are scaled to
+// Percent percent of their points.
+type SelfActivityFilter struct {
+	Percent int
+}
+
+// Name returns the filter name
+func (f SelfActivityFilter) Name() string {
+	return "self_activity"
+}
+
+// Exclude reports whether the event is excluded self activity
+func (f SelfActivityFilter) Exclude(event Event) bool {
+	return f.Percent == 0 && isSelfActivity(event)
+}
+
+// Modify scales the points of self activity awards
+func (f SelfActivityFilter) Modify(event Event, award Award) Award {
+	if isSelfActivity(event) && f.Percent != 100 {
+		award.Points = award.Points * f.Percent / 100
+		award.Reason += " on own pull request"
+	}
+	return award
+}
+
+// Describe returns the scoring system lines for the filter
+func (f SelfActivityFilter) Describe() []string {
+	if f.Percent == 0 {
+		return []string{"❌ Reviews, comments and reactions on your own pull requests earn no points"}
+	}
+	if f.Percent != 100 {
+		return []string{fmt.Sprintf("⚠️ Activity on your own pull requests earns %d%% of the usual points", f.Percent)}
+	}
+	return nil
+}
+
+// isSelfActivity reports whether the event's user authored its pull request
+func isSelfActivity(event Event) bool {
+	return event.PRAuthor != "" && strings.EqualFold(event.User, event.PRAuthor)
+}
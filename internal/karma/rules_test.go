@@ -0,0 +1,252 @@
+package karma
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// testRules returns the review, positive emoji and constructive comment rules,
+// with every review state worth reviewPoint
+func testRules(reviewPoint, emojiPoint, commentPoint int) []Rule {
+	return []Rule{
+		ReviewStateRule{Approved: reviewPoint, ChangesRequested: reviewPoint, Commented: reviewPoint},
+		PositiveEmojiRule{Points: emojiPoint},
+		ConstructiveCommentRule{Points: commentPoint},
+	}
+}
+
+func TestEngineEvaluate(t *testing.T) {
+	engine := NewEngine(testRules(1, 2, 3)...)
+
+	tests := []struct {
+		name     string
+		event    Event
+		expected int
+	}{
+		{"plain review", Event{Kind: EventReview, User: "alice", Body: ""}, 1},
+		{"review with emoji", Event{Kind: EventReview, User: "alice", Body: "Great work! 👍"}, 3},
+		{"constructive review", Event{Kind: EventReview, User: "alice", Body: "I think we should refactor this function to improve readability and add better error handling"}, 4},
+		{"plain comment", Event{Kind: EventReviewComment, User: "bob", Body: "ok"}, 0},
+		{"comment with emoji", Event{Kind: EventReviewComment, User: "bob", Body: "🚀"}, 2},
+		{"bot review", Event{Kind: EventReview, User: "dependabot[bot]", Body: "👍"}, 0},
+		{"anonymous review", Event{Kind: EventReview, User: "", Body: "👍"}, 0},
+	}
+
+	for _, test := range tests {
+		total := 0
+		for _, award := range engine.Evaluate(test.event) {
+			if award.User != test.event.User {
+				t.Errorf("%s: award for %q, expected %q", test.name, award.User, test.event.User)
+			}
+			total += award.Points
+		}
+		if total != test.expected {
+			t.Errorf("%s: got %d points, expected %d", test.name, total, test.expected)
+		}
+	}
+}
+
+func TestEngineEvaluateItemized(t *testing.T) {
+	engine := NewEngine(testRules(1, 2, 1)...)
+
+	awards := engine.Evaluate(Event{Kind: EventReview, User: "alice", Body: "🎉"})
+	if len(awards) != 2 {
+		t.Fatalf("Expected 2 awards, got %d", len(awards))
+	}
+
+	if awards[0].Rule != "review" || awards[1].Rule != "positive_emoji" {
+		t.Errorf("Expected rules in order [review positive_emoji], got [%s %s]", awards[0].Rule, awards[1].Rule)
+	}
+}
+
+func TestEngineEvaluateSetsEventReference(t *testing.T) {
+	engine := NewEngine(testRules(1, 2, 3)...)
+	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	event := Event{Kind: EventReview, ID: 42, PRNumber: 7, User: "alice", Body: "👍", CreatedAt: createdAt}
+
+	awards := engine.Evaluate(event)
+	if len(awards) != 2 {
+		t.Fatalf("Expected 2 awards, got %d", len(awards))
+	}
+
+	for _, award := range awards {
+		if award.PRNumber != 7 || award.EventKind != EventReview || award.EventID != 42 || !award.CreatedAt.Equal(createdAt) {
+			t.Errorf("Unexpected event reference on %s award: %+v", award.Rule, award)
+		}
+	}
+}
+
+func TestEngineScore(t *testing.T) {
+	engine := NewEngine(testRules(1, 2, 1)...)
+
+	events := []Event{
+		{Kind: EventReview, User: "alice", Body: "👍"},
+		{Kind: EventReview, User: "bob"},
+		{Kind: EventReviewComment, User: "alice", Body: "🔥"},
+		{Kind: EventReview, User: "github-actions[bot]", Body: "👍"},
+	}
+
+	totals := engine.Score(events)
+
+	if totals["alice"] != 5 {
+		t.Errorf("Expected alice to have 5 points, got %d", totals["alice"])
+	}
+	if totals["bob"] != 1 {
+		t.Errorf("Expected bob to have 1 point, got %d", totals["bob"])
+	}
+	if _, ok := totals["github-actions[bot]"]; ok {
+		t.Error("Bots should not be scored")
+	}
+}
+
+func TestReviewStateRule(t *testing.T) {
+	rule := ReviewStateRule{Approved: 3, ChangesRequested: 2, Commented: 1, Dismissed: -1}
+
+	tests := []struct {
+		state    string
+		expected int
+	}{
+		{ReviewStateApproved, 3},
+		{ReviewStateChangesRequested, 2},
+		{ReviewStateCommented, 1},
+		{ReviewStateDismissed, -1},
+	}
+
+	for _, test := range tests {
+		awards := rule.Evaluate(Event{Kind: EventReview, User: "alice", State: test.state})
+		if len(awards) != 1 || awards[0].Points != test.expected {
+			t.Errorf("ReviewStateRule(%s) = %v, expected %d points", test.state, awards, test.expected)
+		}
+	}
+
+	if awards := rule.Evaluate(Event{Kind: EventReviewComment, User: "alice"}); len(awards) != 0 {
+		t.Errorf("Expected no awards for comments, got %v", awards)
+	}
+}
+
+func TestEngineReviewStates(t *testing.T) {
+	engine := NewEngine(ReviewStateRule{Approved: 3, Commented: 1}, PositiveEmojiRule{Points: 2})
+
+	pending := engine.Evaluate(Event{Kind: EventReview, User: "alice", State: ReviewStatePending, Body: "👍"})
+	if len(pending) != 0 {
+		t.Errorf("Expected pending reviews to be ignored, got %v", pending)
+	}
+
+	dismissed := Event{Kind: EventReview, User: "alice", State: ReviewStateDismissed, Body: "👍"}
+	if total := engine.Score([]Event{dismissed})["alice"]; total != 2 {
+		t.Errorf("Expected dismissed review to keep emoji points, got %d", total)
+	}
+
+	engine.AddFilters(DismissedReviewFilter{})
+	if awards := engine.Evaluate(dismissed); len(awards) != 0 {
+		t.Errorf("Expected dismissed review to be revoked, got %v", awards)
+	}
+}
+
+func TestEngineDescribe(t *testing.T) {
+	engine := NewEngine(ReviewStateRule{Approved: 3, ChangesRequested: 2, Commented: 1})
+	engine.AddFilters(DismissedReviewFilter{})
+
+	lines := engine.Describe()
+	if len(lines) != 4 {
+		t.Fatalf("Expected 4 description lines, got %d: %v", len(lines), lines)
+	}
+
+	if !strings.Contains(lines[0], "+3") {
+		t.Errorf("Expected approving line to show +3, got %q", lines[0])
+	}
+}
+
+func TestIssueCommentRule(t *testing.T) {
+	engine := NewEngine(append(testRules(1, 2, 1), IssueCommentRule{Points: 1})...)
+
+	totals := engine.Score([]Event{
+		{Kind: EventIssueComment, User: "alice", Body: "Thanks! 🎉"},
+		{Kind: EventIssueComment, User: "bob", Body: "ok"},
+		{Kind: EventReviewComment, User: "carol", Body: "ok"},
+	})
+
+	if totals["alice"] != 3 {
+		t.Errorf("Expected alice to have 3 points, got %d", totals["alice"])
+	}
+	if totals["bob"] != 1 {
+		t.Errorf("Expected bob to have 1 point, got %d", totals["bob"])
+	}
+	if totals["carol"] != 0 {
+		t.Errorf("Expected carol to have 0 points, got %d", totals["carol"])
+	}
+}
+
+func TestReactionRuleCap(t *testing.T) {
+	engine := NewEngine(ReactionRule{Points: map[string]int{"+1": 1, "rocket": 2}, MaxPerPR: 4})
+
+	events := []Event{
+		{Kind: EventReaction, User: "alice", Reaction: "rocket"},
+		{Kind: EventReaction, User: "alice", Reaction: "rocket"},
+		{Kind: EventReaction, User: "alice", Reaction: "+1"},
+		{Kind: EventReaction, User: "bob", Reaction: "+1"},
+		{Kind: EventReaction, User: "bob", Reaction: "eyes"},
+	}
+
+	totals := engine.Score(events)
+
+	if totals["alice"] != 4 {
+		t.Errorf("Expected alice to be capped at 4 points, got %d", totals["alice"])
+	}
+	if totals["bob"] != 1 {
+		t.Errorf("Expected bob to have 1 point, got %d", totals["bob"])
+	}
+}
+
+func TestReactionRuleUncapped(t *testing.T) {
+	engine := NewEngine(ReactionRule{Points: map[string]int{"heart": 3}})
+
+	totals := engine.Score([]Event{
+		{Kind: EventReaction, User: "alice", Reaction: "heart"},
+		{Kind: EventReaction, User: "alice", Reaction: "heart"},
+	})
+
+	if totals["alice"] != 6 {
+		t.Errorf("Expected alice to have 6 points, got %d", totals["alice"])
+	}
+}
+
+func TestReactionRuleDescribe(t *testing.T) {
+	lines := ReactionRule{Points: map[string]int{"+1": 1, "-1": -1}, MaxPerPR: 4}.Describe()
+
+	expected := "✅ Reacting to a pull request or comment: 👍 +1, 👎 -1 (up to 4 point(s) per pull request)"
+	if len(lines) != 1 || lines[0] != expected {
+		t.Errorf("Expected %q, got %v", expected, lines)
+	}
+}
+
+func TestSelfActivityFilter(t *testing.T) {
+	events := []Event{
+		{Kind: EventReview, PRAuthor: "alice", User: "alice", State: ReviewStateCommented, Body: "👍"},
+		{Kind: EventIssueComment, PRAuthor: "alice", User: "alice", Body: "🎉"},
+		{Kind: EventReview, PRAuthor: "alice", User: "bob", State: ReviewStateApproved, Body: "👍"},
+	}
+
+	engine := NewEngine(testRules(2, 2, 1)...)
+	engine.AddFilters(SelfActivityFilter{Percent: 0})
+
+	result := engine.EvaluateAll(events)
+	if result.Excluded["self_activity"] != 2 {
+		t.Errorf("Expected 2 excluded self activity events, got %d", result.Excluded["self_activity"])
+	}
+	if len(result.Awards) != 2 || result.Awards[0].User != "bob" {
+		t.Errorf("Expected only bob's awards, got %v", result.Awards)
+	}
+
+	weighted := NewEngine(testRules(2, 2, 1)...)
+	weighted.AddFilters(SelfActivityFilter{Percent: 50})
+
+	totals := weighted.Score(events)
+	if totals["alice"] != 3 { // (2 + 2) / 2 for the review, 2 / 2 for the comment
+		t.Errorf("Expected alice to have 3 points at 50%%, got %d", totals["alice"])
+	}
+	if totals["bob"] != 4 {
+		t.Errorf("Expected bob to keep 4 points, got %d", totals["bob"])
+	}
+}
@@ -0,0 +1,68 @@
+package karma
+
+import (
+	"strings"
+	"time"
+)
+
+// Window is a time range events are scored in: from Since, inclusive, to
+// Until, exclusive. A zero bound leaves that side of the window open.
+type Window struct {
+	Since time.Time
+	Until time.Time
+}
+
+// IsZero reports whether the window is open on both sides
+func (w Window) IsZero() bool {
+	return w.Since.IsZero() && w.Until.IsZero()
+}
+
+// String describes the window, such as "since 2024-01-01 and before 2024-04-01"
+func (w Window) String() string {
+	var bounds []string
+	if !w.Since.IsZero() {
+		bounds = append(bounds, "since "+formatBound(w.Since))
+	}
+	if !w.Until.IsZero() {
+		bounds = append(bounds, "before "+formatBound(w.Until))
+	}
+	if len(bounds) == 0 {
+		return "all time"
+	}
+	return strings.Join(bounds, " and ")
+}
+
+// formatBound formats a window bound in UTC, as a date when it is midnight
+func formatBound(t time.Time) string {
+	t = t.UTC()
+	if t.Equal(t.Truncate(24 * time.Hour)) {
+		return t.Format(time.DateOnly)
+	}
+	return t.Format("2006-01-02 15:04 UTC")
+}
+
+// Contains reports whether t falls within the window
+func (w Window) Contains(t time.Time) bool {
+	if !w.Since.IsZero() && t.Before(w.Since) {
+		return false
+	}
+	if !w.Until.IsZero() && !t.Before(w.Until) {
+		return false
+	}
+	return true
+}
+
+// FilterEvents returns the events that happened within the window
+func (w Window) FilterEvents(events []Event) []Event {
+	if w.IsZero() {
+		return events
+	}
+
+	var filtered []Event
+	for _, event := range events {
+		if w.Contains(event.CreatedAt) {
+			filtered = append(filtered, event)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,72 @@
+package karma
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWindowContains(t *testing.T) {
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	until := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		window   Window
+		at       time.Time
+		expected bool
+	}{
+		{"open window", Window{}, since.Add(-time.Hour), true},
+		{"at since", Window{Since: since, Until: until}, since, true},
+		{"before since", Window{Since: since, Until: until}, since.Add(-time.Second), false},
+		{"before until", Window{Since: since, Until: until}, until.Add(-time.Second), true},
+		{"at until", Window{Since: since, Until: until}, until, false},
+		{"open since", Window{Until: until}, time.Time{}, true},
+		{"open until", Window{Since: since}, until.AddDate(10, 0, 0), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.window.Contains(tt.at); got != tt.expected {
+				t.Errorf("Expected Contains(%v) to be %v, got %v", tt.at, tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestWindowString(t *testing.T) {
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	until := time.Date(2024, 3, 31, 12, 30, 0, 0, time.FixedZone("CEST", 2*60*60))
+
+	tests := []struct {
+		window   Window
+		expected string
+	}{
+		{Window{}, "all time"},
+		{Window{Since: since}, "since 2024-01-01"},
+		{Window{Since: since, Until: until}, "since 2024-01-01 and before 2024-03-31 10:30 UTC"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.window.String(); got != tt.expected {
+			t.Errorf("Expected %q, got %q", tt.expected, got)
+		}
+	}
+}
+
+func TestWindowFilterEvents(t *testing.T) {
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	events := []Event{
+		{ID: 1, CreatedAt: since.AddDate(0, 0, -1)},
+		{ID: 2, CreatedAt: since},
+		{ID: 3, CreatedAt: since.AddDate(0, 1, 0)},
+	}
+
+	filtered := Window{Since: since, Until: since.AddDate(0, 1, 0)}.FilterEvents(events)
+	if len(filtered) != 1 || filtered[0].ID != 2 {
+		t.Errorf("Expected only event 2, got %+v", filtered)
+	}
+
+	if all := (Window{}).FilterEvents(events); len(all) != len(events) {
+		t.Errorf("Expected an open window to keep all %d events, got %d", len(events), len(all))
+	}
+}
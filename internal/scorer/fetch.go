@@ -0,0 +1,113 @@
+package scorer
+
+import (
+	"context"
+	"sync"
+
+	"github.com/master-wayne7/reviewer-karma-action/internal/githubapi"
+	"github.com/master-wayne7/reviewer-karma-action/internal/karma"
+)
+
+// PREvents is the outcome of collecting the events of a pull request
+type PREvents struct {
+	PR     githubapi.PullRequest
+	Events []karma.Event
+	Err    error // Fetch error; Events holds what was collected before it
+}
+
+// FetchEvents collects the events of prs with up to workers fetches running
+// at the same time, and calls fn with each outcome in the order of prs, so
+// output doesn't depend on which fetch finishes first. Only a bounded number
+// of outcomes is buffered ahead of fn. If fn returns an error or ctx is
+// canceled, the remaining fetches are canceled and the error is returned.
+// If the source is a githubapi.BatchSource, each worker prefetches a batch
+// of pull requests at once before collecting their events, and releases it
+// afterwards.
+func (s *Scorer) FetchEvents(ctx context.Context, prs []githubapi.PullRequest, workers int, fn func(PREvents) error) error {
+	if workers < 1 {
+		workers = 1
+	}
+
+	batchSource, batched := s.source.(githubapi.BatchSource)
+	batch := 1
+	if batched {
+		batch = max(batchSource.BatchSize(), 1)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Each outcome has its own slot so workers never block on delivering it
+	results := make([]chan PREvents, len(prs))
+	for i := range results {
+		results[i] = make(chan PREvents, 1)
+	}
+
+	// The window bounds how many batches fetching runs ahead of fn
+	window := make(chan struct{}, 2*workers)
+	jobs := make(chan int) // Index of the first PR of a batch
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for range workers {
+		go func() {
+			defer wg.Done()
+			for start := range jobs {
+				end := min(start+batch, len(prs))
+				var prefetchErr error
+				if batched {
+					prefetchErr = batchSource.Prefetch(ctx, prs[start:end])
+				}
+				for i := start; i < end; i++ {
+					if prefetchErr != nil {
+						results[i] <- PREvents{PR: prs[i], Err: prefetchErr}
+						continue
+					}
+					events, err := s.Events(ctx, prs[i])
+					results[i] <- PREvents{PR: prs[i], Events: events, Err: err}
+				}
+				if batched {
+					batchSource.Release(prs[start:end])
+				}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for start := 0; start < len(prs); start += batch {
+			select {
+			case window <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case jobs <- start:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var err error
+	for i := range prs {
+		var outcome PREvents
+		select {
+		case outcome = <-results[i]:
+		case <-ctx.Done():
+		}
+		if err = ctx.Err(); err != nil {
+			break
+		}
+		if (i+1)%batch == 0 || i == len(prs)-1 {
+			<-window
+		}
+		if err = fn(outcome); err != nil {
+			break
+		}
+	}
+
+	cancel()
+	wg.Wait()
+	return err
+}
@@ -0,0 +1,219 @@
+package scorer
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/master-wayne7/reviewer-karma-action/internal/githubapi"
+	"github.com/master-wayne7/reviewer-karma-action/internal/karma"
+)
+
+// slowSource delays fetching reviews, later PRs finishing first, and records
+// the most fetches in flight at once
+type slowSource struct {
+	*githubapi.FakeSource
+
+	mu       sync.Mutex
+	inFlight int
+	maxSeen  int
+}
+
+func (s *slowSource) ListReviews(ctx context.Context, prNumber int) ([]githubapi.Review, error) {
+	s.mu.Lock()
+	s.inFlight++
+	s.maxSeen = max(s.maxSeen, s.inFlight)
+	s.mu.Unlock()
+
+	time.Sleep(time.Duration(20-prNumber) * time.Millisecond)
+
+	s.mu.Lock()
+	s.inFlight--
+	s.mu.Unlock()
+	return s.FakeSource.ListReviews(ctx, prNumber)
+}
+
+func newSlowSource(count int) *slowSource {
+	source := &slowSource{FakeSource: githubapi.NewFakeSource()}
+	for number := 1; number <= count; number++ {
+		source.AddPullRequest(githubapi.PullRequest{Number: number, Author: "alice"})
+		source.Reviews[number] = []githubapi.Review{{ID: int64(number), User: "bob", State: karma.ReviewStateApproved}}
+	}
+	return source
+}
+
+func TestFetchEventsOrder(t *testing.T) {
+	tests := []struct {
+		name    string
+		workers int
+	}{
+		{"sequential", 1},
+		{"concurrent", 4},
+		{"more workers than PRs", 50},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := newSlowSource(12)
+			sc := NewScorer(source, karma.NewEngine())
+
+			var got []int
+			err := sc.FetchEvents(context.Background(), source.PullRequests, tt.workers, func(outcome PREvents) error {
+				if outcome.Err != nil {
+					t.Errorf("Unexpected error for PR #%d: %v", outcome.PR.Number, outcome.Err)
+				}
+				if len(outcome.Events) != 1 || outcome.Events[0].PRNumber != outcome.PR.Number {
+					t.Errorf("Unexpected events for PR #%d: %+v", outcome.PR.Number, outcome.Events)
+				}
+				got = append(got, outcome.PR.Number)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("Failed to fetch events: %v", err)
+			}
+
+			if len(got) != 12 {
+				t.Fatalf("Expected 12 outcomes, got %v", got)
+			}
+			for i, number := range got {
+				if number != i+1 {
+					t.Fatalf("Expected outcomes in PR order, got %v", got)
+				}
+			}
+			if source.maxSeen > tt.workers {
+				t.Errorf("Expected at most %d fetches at once, got %d", tt.workers, source.maxSeen)
+			}
+		})
+	}
+}
+
+func TestFetchEventsStopsOnError(t *testing.T) {
+	source := newSlowSource(12)
+	sc := NewScorer(source, karma.NewEngine())
+	stop := errors.New("stop")
+
+	calls := 0
+	err := sc.FetchEvents(context.Background(), source.PullRequests, 4, func(outcome PREvents) error {
+		calls++
+		if outcome.PR.Number == 3 {
+			return stop
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) {
+		t.Errorf("Expected the callback error, got %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("Expected 3 callbacks, got %d", calls)
+	}
+}
+
+func TestFetchEventsCanceled(t *testing.T) {
+	source := newSlowSource(12)
+	sc := NewScorer(source, karma.NewEngine())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	err := sc.FetchEvents(ctx, source.PullRequests, 2, func(outcome PREvents) error {
+		cancel()
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+// batchSource records the batches prefetched from a fake source, failing
+// the batch that contains failPR, and the pull requests released
+type batchSource struct {
+	*githubapi.FakeSource
+
+	mu       sync.Mutex
+	batches  [][]int
+	released map[int]bool
+	failPR   int
+}
+
+func (s *batchSource) BatchSize() int { return 5 }
+
+func (s *batchSource) Prefetch(ctx context.Context, prs []githubapi.PullRequest) error {
+	var numbers []int
+	for _, pr := range prs {
+		numbers = append(numbers, pr.Number)
+	}
+
+	s.mu.Lock()
+	s.batches = append(s.batches, numbers)
+	s.mu.Unlock()
+
+	for _, number := range numbers {
+		if number == s.failPR {
+			return errors.New("batch failed")
+		}
+	}
+	return nil
+}
+
+func (s *batchSource) Release(prs []githubapi.PullRequest) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.released == nil {
+		s.released = make(map[int]bool)
+	}
+	for _, pr := range prs {
+		s.released[pr.Number] = true
+	}
+}
+
+func TestFetchEventsBatches(t *testing.T) {
+	source := &batchSource{FakeSource: newSlowSource(12).FakeSource, failPR: 7}
+	sc := NewScorer(source, karma.NewEngine())
+
+	var got, failed []int
+	err := sc.FetchEvents(context.Background(), source.PullRequests, 2, func(outcome PREvents) error {
+		got = append(got, outcome.PR.Number)
+		if outcome.Err != nil {
+			failed = append(failed, outcome.PR.Number)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to fetch events: %v", err)
+	}
+
+	if len(got) != 12 {
+		t.Fatalf("Expected 12 outcomes, got %v", got)
+	}
+	for i, number := range got {
+		if number != i+1 {
+			t.Fatalf("Expected outcomes in PR order, got %v", got)
+		}
+	}
+
+	if len(source.batches) != 3 {
+		t.Fatalf("Expected 3 batches, got %v", source.batches)
+	}
+	sizes := map[int]int{}
+	for _, batch := range source.batches {
+		sizes[batch[0]] = len(batch)
+	}
+	if sizes[1] != 5 || sizes[6] != 5 || sizes[11] != 2 {
+		t.Errorf("Expected batches of 5, 5 and 2 PRs, got %v", source.batches)
+	}
+
+	expectedFailed := []int{6, 7, 8, 9, 10}
+	if len(failed) != len(expectedFailed) {
+		t.Fatalf("Expected PRs %v to fail with their batch, got %v", expectedFailed, failed)
+	}
+	for i, number := range failed {
+		if number != expectedFailed[i] {
+			t.Errorf("Expected PRs %v to fail with their batch, got %v", expectedFailed, failed)
+			break
+		}
+	}
+
+	if len(source.released) != 12 {
+		t.Errorf("Expected every batch to be released, got %v", source.released)
+	}
+}
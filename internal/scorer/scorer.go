@@ -0,0 +1,221 @@
+package scorer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/master-wayne7/reviewer-karma-action/internal/githubapi"
+	"github.com/master-wayne7/reviewer-karma-action/internal/karma"
+)
+
+// Scorer collects pull request activity from a source and scores it with an engine
+type Scorer struct {
+	source    githubapi.Source
+	engine    *karma.Engine
+	reactions bool
+	window    karma.Window
+}
+
+// NewScorer creates a new scorer
+func NewScorer(source githubapi.Source, engine *karma.Engine) *Scorer {
+	return &Scorer{
+		source: source,
+		engine: engine,
+	}
+}
+
+// EnableReactions makes the scorer fetch reactions on pull requests and
+// comments. This costs one extra request per pull request and per comment
+// that has reactions, so it is off by default.
+func (s *Scorer) EnableReactions() {
+	s.reactions = true
+}
+
+// SetWindow limits scoring to the pull requests and events within window
+func (s *Scorer) SetWindow(window karma.Window) {
+	s.window = window
+}
+
+// PullRequests lists the pull requests that can have activity within the
+// window: those updated since it started and created before it ended
+func (s *Scorer) PullRequests(ctx context.Context) ([]githubapi.PullRequest, error) {
+	var prs []githubapi.PullRequest
+	var err error
+	if s.window.Since.IsZero() {
+		prs, err = s.source.ListPullRequests(ctx)
+	} else {
+		prs, err = s.source.ListPullRequestsUpdatedSince(ctx, s.window.Since)
+	}
+	if err != nil || s.window.Until.IsZero() {
+		return prs, err
+	}
+
+	var inWindow []githubapi.PullRequest
+	for _, pr := range prs {
+		if pr.CreatedAt.Before(s.window.Until) {
+			inWindow = append(inWindow, pr)
+		}
+	}
+	return inWindow, nil
+}
+
+// Engine returns the engine used for scoring
+func (s *Scorer) Engine() *karma.Engine {
+	return s.engine
+}
+
+// Events collects the normalized events of a pull request within the window.
+// If fetching fails part way, the events collected so far are returned along
+// with the error.
+func (s *Scorer) Events(ctx context.Context, pr githubapi.PullRequest) ([]karma.Event, error) {
+	events, err := s.collectEvents(ctx, pr)
+	return s.window.FilterEvents(events), err
+}
+
+// collectEvents collects the events of a pull request. Reactions are fetched
+// for items outside the window as well, since they may have been added within it.
+func (s *Scorer) collectEvents(ctx context.Context, pr githubapi.PullRequest) ([]karma.Event, error) {
+	var events []karma.Event
+
+	reviews, err := s.source.ListReviews(ctx, pr.Number)
+	if err != nil {
+		return events, fmt.Errorf("failed to fetch reviews for PR #%d: %w", pr.Number, err)
+	}
+
+	for _, review := range reviews {
+		events = append(events, reviewEvent(pr, review))
+	}
+
+	if s.reactions {
+		for _, review := range reviews {
+			if review.Reactions == 0 {
+				continue
+			}
+			reactions, err := s.source.ListReviewReactions(ctx, review.ID)
+			if err != nil {
+				return events, fmt.Errorf("failed to fetch reactions on review %d of PR #%d: %w", review.ID, pr.Number, err)
+			}
+			events = append(events, reactionEvents(pr, reactions, review.SubmittedAt)...)
+		}
+	}
+
+	comments, err := s.source.ListReviewComments(ctx, pr.Number)
+	if err != nil {
+		return events, fmt.Errorf("failed to fetch comments for PR #%d: %w", pr.Number, err)
+	}
+
+	for _, comment := range comments {
+		events = append(events, commentEvent(pr, karma.EventReviewComment, comment))
+	}
+
+	if s.reactions {
+		for _, comment := range comments {
+			if comment.Reactions == 0 {
+				continue
+			}
+			reactions, err := s.source.ListReviewCommentReactions(ctx, comment.ID)
+			if err != nil {
+				return events, fmt.Errorf("failed to fetch reactions on comment %d of PR #%d: %w", comment.ID, pr.Number, err)
+			}
+			events = append(events, reactionEvents(pr, reactions, comment.CreatedAt)...)
+		}
+	}
+
+	issueComments, err := s.source.ListIssueComments(ctx, pr.Number)
+	if err != nil {
+		return events, fmt.Errorf("failed to fetch conversation comments for PR #%d: %w", pr.Number, err)
+	}
+
+	for _, comment := range issueComments {
+		events = append(events, commentEvent(pr, karma.EventIssueComment, comment))
+	}
+
+	if !s.reactions {
+		return events, nil
+	}
+
+	for _, comment := range issueComments {
+		if comment.Reactions == 0 {
+			continue
+		}
+		reactions, err := s.source.ListIssueCommentReactions(ctx, comment.ID)
+		if err != nil {
+			return events, fmt.Errorf("failed to fetch reactions on comment %d of PR #%d: %w", comment.ID, pr.Number, err)
+		}
+		events = append(events, reactionEvents(pr, reactions, comment.CreatedAt)...)
+	}
+
+	prReactions, err := s.source.ListPullRequestReactions(ctx, pr.Number)
+	if err != nil {
+		return events, fmt.Errorf("failed to fetch reactions for PR #%d: %w", pr.Number, err)
+	}
+	events = append(events, reactionEvents(pr, prReactions, pr.CreatedAt)...)
+
+	return events, nil
+}
+
+// reviewEvent normalizes a review into an event
+func reviewEvent(pr githubapi.PullRequest, review githubapi.Review) karma.Event {
+	return karma.Event{
+		Kind:      karma.EventReview,
+		ID:        review.ID,
+		PRNumber:  pr.Number,
+		PRAuthor:  pr.Author,
+		User:      review.User,
+		Body:      review.Body,
+		State:     review.State,
+		CreatedAt: review.SubmittedAt,
+	}
+}
+
+// commentEvent normalizes a review comment or conversation comment into an event
+func commentEvent(pr githubapi.PullRequest, kind karma.EventKind, comment githubapi.Comment) karma.Event {
+	return karma.Event{
+		Kind:      kind,
+		ID:        comment.ID,
+		PRNumber:  pr.Number,
+		PRAuthor:  pr.Author,
+		User:      comment.User,
+		Body:      comment.Body,
+		CreatedAt: comment.CreatedAt,
+	}
+}
+
+// reactionEvents normalizes reactions into events, dated when each reaction
+// was added. Reactions without that time, such as those in older fixtures,
+// are dated like the reacted-to item, at createdAt.
+func reactionEvents(pr githubapi.PullRequest, reactions []githubapi.Reaction, createdAt time.Time) []karma.Event {
+	events := make([]karma.Event, 0, len(reactions))
+	for _, reaction := range reactions {
+		reactedAt := reaction.CreatedAt
+		if reactedAt.IsZero() {
+			reactedAt = createdAt
+		}
+		events = append(events, karma.Event{
+			Kind:      karma.EventReaction,
+			ID:        reaction.ID,
+			PRNumber:  pr.Number,
+			PRAuthor:  pr.Author,
+			User:      reaction.User,
+			Reaction:  reaction.Content,
+			CreatedAt: reactedAt,
+		})
+	}
+	return events
+}
+
+// ScorePullRequest collects and scores the events of a pull request. If
+// fetching fails part way, the result for the events collected so far is
+// returned along with the error.
+func (s *Scorer) ScorePullRequest(ctx context.Context, pr githubapi.PullRequest) (karma.Result, error) {
+	events, err := s.Events(ctx, pr)
+	return s.engine.EvaluateAll(events), err
+}
+
+// AddAwards adds the points of each award to the per-user totals
+func AddAwards(totals map[string]int, awards []karma.Award) {
+	for _, award := range awards {
+		totals[award.User] += award.Points
+	}
+}
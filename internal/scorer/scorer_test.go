@@ -0,0 +1,222 @@
+package scorer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/master-wayne7/reviewer-karma-action/internal/githubapi"
+	"github.com/master-wayne7/reviewer-karma-action/internal/karma"
+)
+
+func newTestSource() *githubapi.FakeSource {
+	source := githubapi.NewFakeSource()
+	source.AddPullRequest(githubapi.PullRequest{Number: 1, Author: "alice"})
+	source.Reviews[1] = []githubapi.Review{
+		{ID: 1, User: "bob", Body: "👍", State: karma.ReviewStateApproved},
+		{ID: 2, User: "github-actions[bot]", Body: "👍", State: karma.ReviewStateApproved},
+	}
+	source.ReviewComments[1] = []githubapi.Comment{
+		{ID: 10, User: "carol", Body: "🔥"},
+	}
+	source.IssueComments[1] = []githubapi.Comment{
+		{ID: 20, User: "dave", Body: "Thanks 🎉"},
+	}
+	return source
+}
+
+// newTestEngine scores every review 1 point, positive emojis 2 and
+// constructive comments 1
+func newTestEngine() *karma.Engine {
+	return karma.NewEngine(
+		karma.ReviewStateRule{Approved: 1, ChangesRequested: 1, Commented: 1},
+		karma.PositiveEmojiRule{Points: 2},
+		karma.ConstructiveCommentRule{Points: 1},
+	)
+}
+
+func TestScorePullRequest(t *testing.T) {
+	source := newTestSource()
+	sc := NewScorer(source, newTestEngine())
+
+	result, err := sc.ScorePullRequest(context.Background(), source.PullRequests[0])
+	if err != nil {
+		t.Fatalf("Failed to score PR: %v", err)
+	}
+
+	totals := make(map[string]int)
+	AddAwards(totals, result.Awards)
+
+	if totals["bob"] != 3 {
+		t.Errorf("Expected bob to have 3 points, got %d", totals["bob"])
+	}
+	if totals["carol"] != 2 {
+		t.Errorf("Expected carol to have 2 points, got %d", totals["carol"])
+	}
+	if totals["dave"] != 2 {
+		t.Errorf("Expected dave to have 2 points for a conversation comment, got %d", totals["dave"])
+	}
+	if len(totals) != 3 {
+		t.Errorf("Expected 3 scored users, got %v", totals)
+	}
+}
+
+func TestEventsNormalization(t *testing.T) {
+	source := newTestSource()
+	sc := NewScorer(source, karma.NewEngine())
+
+	events, err := sc.Events(context.Background(), source.PullRequests[0])
+	if err != nil {
+		t.Fatalf("Failed to collect events: %v", err)
+	}
+
+	if len(events) != 4 {
+		t.Fatalf("Expected 4 events, got %d", len(events))
+	}
+
+	if events[0].Kind != karma.EventReview || events[0].State != karma.ReviewStateApproved || events[0].PRNumber != 1 {
+		t.Errorf("Unexpected review event: %+v", events[0])
+	}
+
+	if events[2].Kind != karma.EventReviewComment || events[2].ID != 10 {
+		t.Errorf("Unexpected comment event: %+v", events[2])
+	}
+
+	if events[3].Kind != karma.EventIssueComment || events[3].ID != 20 {
+		t.Errorf("Unexpected conversation comment event: %+v", events[3])
+	}
+}
+
+func TestScorePullRequestFetchError(t *testing.T) {
+	source := newTestSource()
+	source.Errors[1] = errors.New("rate limited")
+	sc := NewScorer(source, newTestEngine())
+
+	result, err := sc.ScorePullRequest(context.Background(), source.PullRequests[0])
+	if err == nil {
+		t.Error("Expected fetch error to be returned")
+	}
+	if len(result.Awards) != 0 {
+		t.Errorf("Expected no awards, got %v", result.Awards)
+	}
+}
+
+func TestScorePullRequestReactions(t *testing.T) {
+	source := newTestSource()
+	source.PullRequestReactions[1] = []githubapi.Reaction{{ID: 1, User: "erin", Content: "rocket"}}
+	source.ReviewCommentReactions[10] = []githubapi.Reaction{{ID: 2, User: "erin", Content: "+1"}}
+	source.IssueCommentReactions[20] = []githubapi.Reaction{{ID: 3, User: "erin", Content: "heart"}}
+
+	engine := karma.NewEngine(karma.ReactionRule{Points: map[string]int{"+1": 1, "rocket": 1, "heart": 1}, MaxPerPR: 2})
+	sc := NewScorer(source, engine)
+
+	result, err := sc.ScorePullRequest(context.Background(), source.PullRequests[0])
+	if err != nil {
+		t.Fatalf("Failed to score PR: %v", err)
+	}
+	if len(result.Awards) != 0 {
+		t.Errorf("Expected reactions to be ignored unless enabled, got %v", result.Awards)
+	}
+
+	sc.EnableReactions()
+	result, err = sc.ScorePullRequest(context.Background(), source.PullRequests[0])
+	if err != nil {
+		t.Fatalf("Failed to score PR: %v", err)
+	}
+
+	totals := make(map[string]int)
+	AddAwards(totals, result.Awards)
+	if totals["erin"] != 2 {
+		t.Errorf("Expected erin to be capped at 2 reaction points, got %d", totals["erin"])
+	}
+}
+
+func TestScorerWindow(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 3, d, 12, 0, 0, 0, time.UTC) }
+
+	source := githubapi.NewFakeSource()
+	source.AddPullRequest(githubapi.PullRequest{Number: 1, Author: "alice", CreatedAt: day(1), UpdatedAt: day(2)})
+	source.AddPullRequest(githubapi.PullRequest{Number: 2, Author: "alice", CreatedAt: day(3), UpdatedAt: day(12)})
+	source.AddPullRequest(githubapi.PullRequest{Number: 3, Author: "alice", CreatedAt: day(20), UpdatedAt: day(21)})
+	source.Reviews[2] = []githubapi.Review{
+		{ID: 1, User: "bob", State: karma.ReviewStateApproved, SubmittedAt: day(4)},
+		{ID: 2, User: "carol", State: karma.ReviewStateApproved, SubmittedAt: day(11)},
+	}
+	source.IssueComments[2] = []githubapi.Comment{
+		{ID: 20, User: "dave", Body: "Early", CreatedAt: day(3), Reactions: 1},
+		{ID: 21, User: "dave", Body: "Late", CreatedAt: day(12), Reactions: 1},
+	}
+	source.IssueCommentReactions[20] = []githubapi.Reaction{{ID: 1, User: "erin", Content: "+1"}}
+	source.IssueCommentReactions[21] = []githubapi.Reaction{{ID: 2, User: "erin", Content: "heart"}}
+
+	sc := NewScorer(source, karma.NewEngine())
+	sc.EnableReactions()
+	sc.SetWindow(karma.Window{Since: day(10), Until: day(15)})
+
+	prs, err := sc.PullRequests(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to list pull requests: %v", err)
+	}
+	if len(prs) != 1 || prs[0].Number != 2 {
+		t.Fatalf("Expected only PR #2, updated within and created before the window, got %+v", prs)
+	}
+
+	events, err := sc.Events(context.Background(), prs[0])
+	if err != nil {
+		t.Fatalf("Failed to collect events: %v", err)
+	}
+
+	var got []string
+	for _, event := range events {
+		got = append(got, fmt.Sprintf("%s/%d", event.Kind, event.ID))
+	}
+	expected := []string{"review/2", "issue_comment/21", "reaction/2"}
+	if strings.Join(got, " ") != strings.Join(expected, " ") {
+		t.Errorf("Expected events %v, got %v", expected, got)
+	}
+}
+
+func TestScorerWindowDatesReactions(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 3, d, 12, 0, 0, 0, time.UTC) }
+
+	source := githubapi.NewFakeSource()
+	source.AddPullRequest(githubapi.PullRequest{Number: 2, Author: "alice", CreatedAt: day(3), UpdatedAt: day(12)})
+	source.Reviews[2] = []githubapi.Review{
+		{ID: 1, User: "bob", State: karma.ReviewStateApproved, SubmittedAt: day(4)},
+		{ID: 2, User: "carol", State: karma.ReviewStateApproved, SubmittedAt: day(11)},
+	}
+	source.IssueComments[2] = []githubapi.Comment{
+		{ID: 20, User: "dave", Body: "Early", CreatedAt: day(3), Reactions: 1},
+		{ID: 21, User: "dave", Body: "Late", CreatedAt: day(12), Reactions: 1},
+	}
+
+	// Reactions are dated when they were added, not like what they react to
+	source.PullRequestReactions[2] = []githubapi.Reaction{
+		{ID: 5, User: "erin", Content: "rocket", CreatedAt: day(11)},
+		{ID: 6, User: "erin", Content: "heart", CreatedAt: day(5)},
+	}
+	source.ReviewReactions[1] = []githubapi.Reaction{{ID: 7, User: "erin", Content: "+1", CreatedAt: day(12)}}
+	source.IssueCommentReactions[20] = []githubapi.Reaction{{ID: 8, User: "erin", Content: "+1", CreatedAt: day(13)}}
+	source.IssueCommentReactions[21] = []githubapi.Reaction{{ID: 9, User: "erin", Content: "heart", CreatedAt: day(16)}}
+
+	sc := NewScorer(source, karma.NewEngine())
+	sc.EnableReactions()
+	sc.SetWindow(karma.Window{Since: day(10), Until: day(15)})
+
+	events, err := sc.Events(context.Background(), source.PullRequests[0])
+	if err != nil {
+		t.Fatalf("Failed to collect events: %v", err)
+	}
+
+	var got []string
+	for _, event := range events {
+		got = append(got, fmt.Sprintf("%s/%d", event.Kind, event.ID))
+	}
+	expected := []string{"review/2", "reaction/7", "issue_comment/21", "reaction/8", "reaction/5"}
+	if strings.Join(got, " ") != strings.Join(expected, " ") {
+		t.Errorf("Expected events %v, got %v", expected, got)
+	}
+}
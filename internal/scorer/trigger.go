@@ -0,0 +1,44 @@
+package scorer
+
+import (
+	"github.com/master-wayne7/reviewer-karma-action/internal/githubapi"
+	"github.com/master-wayne7/reviewer-karma-action/internal/karma"
+)
+
+// ApplyTrigger updates the known events of a pull request with the review or
+// comment that triggered a run, so the pull request can be re-scored without
+// fetching its activity again. It reports false when the trigger can't be
+// applied this way: pull request events carry no review or comment, and a
+// deleted comment's reactions are not known from the payload.
+func ApplyTrigger(events []karma.Event, trigger *githubapi.Trigger) ([]karma.Event, bool) {
+	if trigger.Action == "deleted" {
+		return nil, false
+	}
+
+	pr := trigger.PullRequest
+	var changed karma.Event
+	switch {
+	case trigger.Review != nil:
+		changed = reviewEvent(pr, *trigger.Review)
+	case trigger.ReviewComment != nil:
+		changed = commentEvent(pr, karma.EventReviewComment, *trigger.ReviewComment)
+	case trigger.IssueComment != nil:
+		changed = commentEvent(pr, karma.EventIssueComment, *trigger.IssueComment)
+	default:
+		return nil, false
+	}
+
+	updated := make([]karma.Event, 0, len(events)+1)
+	replaced := false
+	for _, event := range events {
+		if event.Kind == changed.Kind && event.ID == changed.ID {
+			event, replaced = changed, true
+		}
+		updated = append(updated, event)
+	}
+	if !replaced {
+		updated = append(updated, changed)
+	}
+
+	return updated, true
+}
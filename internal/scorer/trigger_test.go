@@ -0,0 +1,69 @@
+package scorer
+
+import (
+	"testing"
+
+	"github.com/master-wayne7/reviewer-karma-action/internal/githubapi"
+	"github.com/master-wayne7/reviewer-karma-action/internal/karma"
+)
+
+func TestApplyTrigger(t *testing.T) {
+	pr := githubapi.PullRequest{Number: 1, Author: "alice"}
+	events := []karma.Event{
+		{Kind: karma.EventReview, ID: 1, PRNumber: 1, User: "bob", State: karma.ReviewStateCommented},
+		{Kind: karma.EventIssueComment, ID: 20, PRNumber: 1, User: "dave", Body: "Thanks"},
+	}
+
+	tests := []struct {
+		name     string
+		trigger  *githubapi.Trigger
+		ok       bool
+		expected int
+		changed  int
+	}{
+		{
+			name:     "dismissed review replaces the stored one",
+			trigger:  &githubapi.Trigger{Action: "dismissed", PullRequest: pr, Review: &githubapi.Review{ID: 1, User: "bob", State: karma.ReviewStateDismissed}},
+			ok:       true,
+			expected: 2,
+			changed:  0,
+		},
+		{
+			name:     "new review comment is appended",
+			trigger:  &githubapi.Trigger{Action: "created", PullRequest: pr, ReviewComment: &githubapi.Comment{ID: 10, User: "carol", Body: "🔥"}},
+			ok:       true,
+			expected: 3,
+			changed:  2,
+		},
+		{
+			name:    "deleted comment can't be applied",
+			trigger: &githubapi.Trigger{Action: "deleted", PullRequest: pr, IssueComment: &githubapi.Comment{ID: 20, User: "dave"}},
+		},
+		{
+			name:    "pull request event has nothing to apply",
+			trigger: &githubapi.Trigger{Action: "synchronize", PullRequest: pr},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updated, ok := ApplyTrigger(events, tt.trigger)
+			if ok != tt.ok {
+				t.Fatalf("Expected ok to be %v, got %v", tt.ok, ok)
+			}
+			if !ok {
+				return
+			}
+			if len(updated) != tt.expected {
+				t.Fatalf("Expected %d events, got %d", tt.expected, len(updated))
+			}
+			if updated[tt.changed].PRAuthor != "alice" {
+				t.Errorf("Expected the changed event to be normalized, got %+v", updated[tt.changed])
+			}
+		})
+	}
+
+	if events[0].State != karma.ReviewStateCommented {
+		t.Errorf("Expected the original events to be unchanged, got %+v", events[0])
+	}
+}
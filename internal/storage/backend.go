@@ -0,0 +1,171 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/master-wayne7/reviewer-karma-action/internal/atomicfile"
+)
+
+// ErrConflict is returned by Save when the stored data changed since it was
+// loaded, such as by a concurrent run. Tx.Commit merges and retries on it.
+var ErrConflict = errors.New("karma data was changed concurrently")
+
+// Backend reads and writes karma data. Storage prepares the data before
+// handing it to Save, so backends only persist it. Save returns ErrConflict
+// rather than overwrite data that changed since the last Load.
+type Backend interface {
+	Load() (*KarmaData, error)
+	Save(data *KarmaData) error
+	Close() error
+}
+
+// Migrator is implemented by backends whose stored data can be upgraded to
+// the current schema version explicitly
+type Migrator interface {
+	Migrate(dryRun bool) (*MigrationResult, error)
+}
+
+// FileBackend stores karma data as a single JSON file. Conflicts are only
+// detected between runs sharing the file, not between workflow runs with
+// their own checkouts; those need the git backend or a concurrency group.
+type FileBackend struct {
+	filePath string
+
+	loaded  bool
+	version string // Hash of the file content when it was loaded
+}
+
+// NewFileBackend creates a backend storing karma data in a JSON file
+func NewFileBackend(filePath string) *FileBackend {
+	return &FileBackend{
+		filePath: filePath,
+	}
+}
+
+// errEmptyFile is returned when a data file exists but has no content
+var errEmptyFile = errors.New("karma data file is empty")
+
+// Load loads karma data from file. If the file can't be read or parsed, the
+// backup of the previous generation is loaded instead, with a loud warning,
+// rather than starting over with empty data.
+func (b *FileBackend) Load() (*KarmaData, error) {
+	// Read the version first, so a change made while loading is a conflict
+	// on Save rather than being overwritten
+	version, err := fileVersion(b.filePath)
+	if err != nil {
+		return nil, err
+	}
+	b.version, b.loaded = version, true
+
+	karmaData, err := readKarmaData(b.filePath)
+	if err == nil {
+		return karmaData, nil
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		// File doesn't exist, return empty data
+		return newKarmaData(time.Time{}), nil
+	}
+
+	backupPath := atomicfile.BackupPath(b.filePath)
+	backup, backupErr := readKarmaData(backupPath)
+	if backupErr != nil {
+		if errors.Is(err, errEmptyFile) {
+			// Handle empty file without a backup to recover
+			return newKarmaData(time.Time{}), nil
+		}
+		return nil, err
+	}
+
+	fmt.Printf("::warning file=%s::Karma data is unreadable (%v), recovered the previous generation from %s\n", b.filePath, err, backupPath)
+	fmt.Printf("🚨 %s is unreadable: %v\n", b.filePath, err)
+	fmt.Printf("🚨 Recovered karma data from the backup %s (last updated %s). PRs scored after that will be processed again.\n",
+		backupPath, backup.LastUpdated.Format(time.RFC3339))
+
+	return backup, nil
+}
+
+// readKarmaData reads and parses a karma data file, upgrading older schema
+// versions in memory
+func readKarmaData(path string) (*KarmaData, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read karma data: %w", err)
+	}
+	return parseKarmaData(data)
+}
+
+// parseKarmaData parses the content of a karma data file, upgrading older
+// schema versions in memory
+func parseKarmaData(data []byte) (*KarmaData, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, errEmptyFile
+	}
+
+	doc, err := decodeDocument(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := migrateDocument(doc); err != nil {
+		return nil, err
+	}
+
+	return documentKarmaData(doc)
+}
+
+// Save saves karma data to file. The file is replaced atomically and its
+// previous generation is kept as a backup for Load to fall back to.
+func (b *FileBackend) Save(data *KarmaData) error {
+	jsonData, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal karma data: %w", err)
+	}
+
+	if b.loaded {
+		current, err := fileVersion(b.filePath)
+		if err != nil {
+			return err
+		}
+		if current != b.version {
+			return ErrConflict
+		}
+	}
+
+	if err := atomicfile.WriteWithBackup(b.filePath, jsonData, 0644, json.Valid); err != nil {
+		return fmt.Errorf("failed to write karma data: %w", err)
+	}
+
+	b.version, b.loaded = contentVersion(jsonData), true
+	return nil
+}
+
+// fileVersion identifies the content of a data file, empty if it doesn't exist
+func fileVersion(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read karma data: %w", err)
+	}
+	return contentVersion(content), nil
+}
+
+// contentVersion identifies file content
+func contentVersion(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// Close does nothing, the file is not kept open between calls
+func (b *FileBackend) Close() error {
+	return nil
+}
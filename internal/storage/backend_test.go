@@ -0,0 +1,174 @@
+package storage
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileBackend(t *testing.T) {
+	open := func(path string) (Backend, error) {
+		return NewFileBackend(path), nil
+	}
+	testBackend(t, filepath.Join(t.TempDir(), "karma.json"), open)
+	testBackendConflict(t, filepath.Join(t.TempDir(), "karma.json"), open)
+}
+
+// testBackend checks that data saved through a backend is loaded back
+// unchanged by a new backend on the same path
+func testBackend(t *testing.T, path string, open func(path string) (Backend, error)) {
+	t.Helper()
+
+	reopen := func() *Storage {
+		backend, err := open(path)
+		if err != nil {
+			t.Fatalf("Failed to open backend: %v", err)
+		}
+		t.Cleanup(func() { backend.Close() })
+		return NewStorageWithBackend(backend)
+	}
+
+	processedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	events := []Event{
+		{Kind: "review", ID: 10, PRAuthor: "alice", User: "bob", State: "APPROVED", CreatedAt: processedAt},
+		{Kind: "comment", ID: 11, PRAuthor: "alice", User: "carol", Body: "LGTM 👍", CreatedAt: processedAt},
+	}
+
+	storage := reopen()
+	tx, err := storage.Begin()
+	if err != nil {
+		t.Fatalf("Failed to begin: %v", err)
+	}
+	tx.Data().ScoringFingerprint = "abc123"
+	tx.Apply(PRUpdate{PRNumber: 1, Events: events, Entries: []LedgerEntry{
+		{EventKind: "review", EventID: 10, User: "bob", Rule: "review", Points: 1, Timestamp: processedAt},
+		{EventKind: "comment", EventID: 11, User: "carol", Rule: "positive_emoji", Points: 2, Timestamp: processedAt},
+	}, ProcessedAt: processedAt})
+	tx.Apply(PRUpdate{PRNumber: 2, Entries: []LedgerEntry{{User: "dave", Points: 3}}, ProcessedAt: processedAt})
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+
+	// Re-score PR 1 in a second save so only the changes are written
+	storage = reopen()
+	err = storage.RecordPR(1, events[:1], []LedgerEntry{
+		{EventKind: "review", EventID: 10, User: "bob", Rule: "review", Points: 1, Timestamp: processedAt},
+	}, processedAt.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to record PR: %v", err)
+	}
+
+	data, err := reopen().Load()
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+
+	expected := map[string]int{"bob": 1, "dave": 3}
+	if len(data.Reviewers) != len(expected) {
+		t.Errorf("Expected reviewers %v, got %v", expected, data.Reviewers)
+	}
+	for username, points := range expected {
+		if data.Reviewers[username] != points {
+			t.Errorf("Expected %s to have %d points, got %d", username, points, data.Reviewers[username])
+		}
+	}
+
+	if len(data.Ledger) != 4 {
+		t.Errorf("Expected 4 ledger entries, got %d", len(data.Ledger))
+	}
+	if len(data.Ledger) == 4 && (!data.Ledger[3].Reversal || data.Ledger[3].Points != -2) {
+		t.Errorf("Expected the last entry to reverse carol's 2 points, got %+v", data.Ledger[3])
+	}
+
+	if len(data.Events[1]) != 1 || data.Events[1][0].Body != "" || data.Events[1][0].State != "APPROVED" {
+		t.Errorf("Expected the re-scored events of PR 1, got %+v", data.Events[1])
+	}
+	if _, ok := data.Events[2]; ok {
+		t.Error("Expected no events for PR 2")
+	}
+	if !data.ProcessedPRs[1].Equal(processedAt.Add(time.Hour)) {
+		t.Errorf("Expected PR 1 processed at %v, got %v", processedAt.Add(time.Hour), data.ProcessedPRs[1])
+	}
+	if data.ScoringFingerprint != "abc123" {
+		t.Errorf("Expected fingerprint abc123, got %q", data.ScoringFingerprint)
+	}
+	if data.SchemaVersion != CurrentSchemaVersion {
+		t.Errorf("Expected schema version %d, got %d", CurrentSchemaVersion, data.SchemaVersion)
+	}
+
+	storage = reopen()
+	if err := storage.Clear(); err != nil {
+		t.Fatalf("Failed to clear: %v", err)
+	}
+	data, err = reopen().Load()
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+	if len(data.Reviewers) != 0 || len(data.Ledger) != 0 || len(data.ProcessedPRs) != 0 || len(data.Events) != 0 {
+		t.Errorf("Expected empty data after Clear, got %+v", data)
+	}
+}
+
+// testBackendConflict checks that a backend rejects saving over data changed
+// since it was loaded, and that transactions merge such changes
+func testBackendConflict(t *testing.T, path string, open func(path string) (Backend, error)) {
+	t.Helper()
+
+	openBackend := func() Backend {
+		backend, err := open(path)
+		if err != nil {
+			t.Fatalf("Failed to open backend: %v", err)
+		}
+		t.Cleanup(func() { backend.Close() })
+		return backend
+	}
+
+	first, second := openBackend(), openBackend()
+	firstData, err := first.Load()
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+	secondData, err := second.Load()
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+
+	if err := first.Save(firstData); err != nil {
+		t.Fatalf("Failed to save: %v", err)
+	}
+	if err := second.Save(secondData); !errors.Is(err, ErrConflict) {
+		t.Fatalf("Expected ErrConflict when saving stale data, got %v", err)
+	}
+
+	// Concurrent transactions both end up in the stored data
+	processedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	firstTx, err := NewStorageWithBackend(openBackend()).Begin()
+	if err != nil {
+		t.Fatalf("Failed to begin: %v", err)
+	}
+	secondTx, err := NewStorageWithBackend(openBackend()).Begin()
+	if err != nil {
+		t.Fatalf("Failed to begin: %v", err)
+	}
+
+	firstTx.Apply(PRUpdate{PRNumber: 1, Entries: []LedgerEntry{{User: "alice", Points: 2}}, ProcessedAt: processedAt})
+	secondTx.Apply(PRUpdate{PRNumber: 2, Entries: []LedgerEntry{{User: "bob", Points: 3}}, ProcessedAt: processedAt})
+	if err := firstTx.Commit(); err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+	if err := secondTx.Commit(); err != nil {
+		t.Fatalf("Failed to commit after a concurrent change: %v", err)
+	}
+
+	data, err := NewStorageWithBackend(openBackend()).Load()
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+	if data.Reviewers["alice"] != 2 || data.Reviewers["bob"] != 3 {
+		t.Errorf("Expected alice 2 and bob 3, got %v", data.Reviewers)
+	}
+	if len(data.ProcessedPRs) != 2 {
+		t.Errorf("Expected 2 processed PRs, got %d", len(data.ProcessedPRs))
+	}
+}
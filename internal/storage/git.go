@@ -0,0 +1,270 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// GitOptions locates the karma data in a git repository
+type GitOptions struct {
+	RepoDir string // Repository to run git in
+	Remote  string // Remote the branch is fetched from and pushed to, empty to stay local
+	Branch  string // Branch holding the data file, usually an orphan branch
+	Path    string // Path of the data file within the branch
+}
+
+// GitBackend stores the karma data file on a dedicated branch of a git
+// repository instead of the working tree. It only uses git plumbing, so the
+// checkout's working tree, index and HEAD are never touched.
+type GitBackend struct {
+	opts GitOptions
+
+	loaded    bool
+	base      string // Commit the data was loaded from, empty if the branch doesn't exist
+	localBase string // Local branch commit when the data was loaded
+}
+
+// NewGitBackend creates a backend storing karma data on a git branch
+func NewGitBackend(opts GitOptions) *GitBackend {
+	return &GitBackend{
+		opts: opts,
+	}
+}
+
+// Load reads the data file from the tip of the branch. With a remote, the
+// branch is fetched first. A missing branch or file is empty data.
+func (b *GitBackend) Load() (*KarmaData, error) {
+	base, err := b.fetch()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read karma data: %w", err)
+	}
+	b.base, b.loaded = base, true
+	if base == "" {
+		return newKarmaData(time.Time{}), nil
+	}
+
+	blob, err := b.resolve(base + ":" + b.opts.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read karma data: %w", err)
+	}
+	if blob == "" {
+		return newKarmaData(time.Time{}), nil
+	}
+
+	content, err := b.output(nil, nil, "cat-file", "blob", blob)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read karma data: %w", err)
+	}
+
+	data, err := parseKarmaData(content)
+	if errors.Is(err, errEmptyFile) {
+		return newKarmaData(time.Time{}), nil
+	}
+	return data, err
+}
+
+// fetch returns the commit the branch currently points to, fetching it from
+// the remote if one is set
+func (b *GitBackend) fetch() (string, error) {
+	local, err := b.resolve(b.localRef())
+	if err != nil {
+		return "", err
+	}
+	b.localBase = local
+
+	if b.opts.Remote == "" {
+		return local, nil
+	}
+
+	// ls-remote exits with 2 when the branch doesn't exist on the remote
+	_, err = b.git(nil, nil, "ls-remote", "--exit-code", "--heads", b.opts.Remote, b.localRef())
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 2 {
+		return local, nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := b.git(nil, nil, "fetch", "--quiet", b.opts.Remote, "+"+b.localRef()+":"+b.remoteRef()); err != nil {
+		return "", err
+	}
+	return b.resolve(b.remoteRef())
+}
+
+// Save commits the data file on top of the commit it was loaded from and
+// moves the branch to it. With a remote, the commit is pushed first. If
+// another run moved the branch in the meantime, ErrConflict is returned.
+func (b *GitBackend) Save(data *KarmaData) error {
+	content, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal karma data: %w", err)
+	}
+
+	// Data that wasn't loaded through this backend replaces the branch tip
+	if !b.loaded {
+		if b.base, err = b.fetch(); err != nil {
+			return fmt.Errorf("failed to write karma data: %w", err)
+		}
+		b.loaded = true
+	}
+
+	commit, err := b.commit(content)
+	if err != nil {
+		return fmt.Errorf("failed to write karma data: %w", err)
+	}
+
+	if b.opts.Remote != "" {
+		// The push only fast-forwards, so it is rejected if another run
+		// pushed since the branch was fetched
+		if _, err := b.git(nil, nil, "push", "--quiet", b.opts.Remote, commit+":"+b.localRef()); err != nil {
+			if moved, _ := b.remoteMoved(); moved {
+				return ErrConflict
+			}
+			return fmt.Errorf("failed to push karma data to %s: %w", b.opts.Remote, err)
+		}
+		if _, err := b.git(nil, nil, "update-ref", b.remoteRef(), commit); err != nil {
+			return fmt.Errorf("failed to write karma data: %w", err)
+		}
+		// The remote is authoritative, so the local branch just follows it
+		if _, err := b.git(nil, nil, "update-ref", "-m", "reviewer-karma: update karma data", b.localRef(), commit); err != nil {
+			return fmt.Errorf("failed to write karma data: %w", err)
+		}
+	} else {
+		// An empty old value makes update-ref fail if the branch was created since
+		if _, err := b.git(nil, nil, "update-ref", "-m", "reviewer-karma: update karma data", b.localRef(), commit, b.localBase); err != nil {
+			if local, _ := b.resolve(b.localRef()); local != b.localBase {
+				return ErrConflict
+			}
+			return fmt.Errorf("failed to write karma data: %w", err)
+		}
+	}
+
+	b.base, b.localBase = commit, commit
+	return nil
+}
+
+// remoteMoved reports whether the branch on the remote no longer points to
+// the commit the data was loaded from
+func (b *GitBackend) remoteMoved() (bool, error) {
+	out, err := b.git(nil, nil, "ls-remote", b.opts.Remote, b.localRef())
+	if err != nil {
+		return false, err
+	}
+	oid, _, _ := strings.Cut(out, "\t")
+	return oid != b.base, nil
+}
+
+// commit writes content to the data file path in a new commit whose parent
+// is the loaded commit, keeping every other file on the branch
+func (b *GitBackend) commit(content []byte) (string, error) {
+	blob, err := b.git(content, nil, "hash-object", "-w", "--stdin")
+	if err != nil {
+		return "", err
+	}
+
+	// Build the tree in a temporary index so the checkout's index is untouched
+	index, err := os.CreateTemp("", "karma-index-*")
+	if err != nil {
+		return "", err
+	}
+	index.Close()
+	os.Remove(index.Name()) // git creates the index itself
+	defer os.Remove(index.Name())
+	env := []string{"GIT_INDEX_FILE=" + index.Name()}
+
+	if b.base != "" {
+		_, err = b.git(nil, env, "read-tree", b.base)
+	} else {
+		_, err = b.git(nil, env, "read-tree", "--empty")
+	}
+	if err != nil {
+		return "", err
+	}
+	if _, err := b.git(nil, env, "update-index", "--add", "--cacheinfo", "100644,"+blob+","+b.opts.Path); err != nil {
+		return "", err
+	}
+	tree, err := b.git(nil, env, "write-tree")
+	if err != nil {
+		return "", err
+	}
+
+	args := []string{"commit-tree", tree, "-m", "Update karma data"}
+	if b.base != "" {
+		args = append(args, "-p", b.base)
+	}
+	return b.git(nil, b.identityEnv(), args...)
+}
+
+// identityEnv returns the identity to commit as when git has none
+// configured, as on a fresh CI runner
+func (b *GitBackend) identityEnv() []string {
+	if _, err := b.git(nil, nil, "var", "GIT_COMMITTER_IDENT"); err == nil {
+		return nil
+	}
+	return []string{
+		"GIT_AUTHOR_NAME=GitHub Action",
+		"GIT_AUTHOR_EMAIL=action@github.com",
+		"GIT_COMMITTER_NAME=GitHub Action",
+		"GIT_COMMITTER_EMAIL=action@github.com",
+	}
+}
+
+// resolve returns the object name of rev, or an empty string if it doesn't
+// exist
+func (b *GitBackend) resolve(rev string) (string, error) {
+	oid, err := b.git(nil, nil, "rev-parse", "--verify", "--quiet", rev)
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return "", nil
+	}
+	return oid, err
+}
+
+// localRef is the full name of the local data branch
+func (b *GitBackend) localRef() string {
+	return "refs/heads/" + b.opts.Branch
+}
+
+// remoteRef is the remote-tracking ref the data branch is fetched into
+func (b *GitBackend) remoteRef() string {
+	return "refs/remotes/" + b.opts.Remote + "/" + b.opts.Branch
+}
+
+// Close does nothing, git is only run during Load and Save
+func (b *GitBackend) Close() error {
+	return nil
+}
+
+// git runs a git command in the repository and returns its trimmed output
+func (b *GitBackend) git(stdin []byte, env []string, args ...string) (string, error) {
+	out, err := b.output(stdin, env, args...)
+	return strings.TrimSpace(string(out)), err
+}
+
+// output runs a git command in the repository and returns its output
+func (b *GitBackend) output(stdin []byte, env []string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = b.opts.RepoDir
+	cmd.Env = append(os.Environ(), env...)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git %s: %w: %s", args[0], err, msg)
+		}
+		return nil, fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return stdout.Bytes(), nil
+}
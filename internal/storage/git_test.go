@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// runGit runs a git command in dir and fails the test on error
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=Test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=Test", "GIT_COMMITTER_EMAIL=test@example.com")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s failed: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// newGitRepo creates a repository with one commit on main and a bare remote
+// named origin
+func newGitRepo(t *testing.T) (repo, remote string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	remote = filepath.Join(t.TempDir(), "remote.git")
+	runGit(t, t.TempDir(), "init", "--quiet", "--bare", remote)
+
+	repo = t.TempDir()
+	runGit(t, repo, "init", "--quiet", "--initial-branch=main")
+	if err := os.WriteFile(filepath.Join(repo, "REVIEWERS.md"), []byte("# Leaderboard\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	runGit(t, repo, "add", "REVIEWERS.md")
+	runGit(t, repo, "commit", "--quiet", "-m", "Initial commit")
+	runGit(t, repo, "remote", "add", "origin", remote)
+	return repo, remote
+}
+
+func TestGitBackend(t *testing.T) {
+	repo, _ := newGitRepo(t)
+
+	open := func(path string) (Backend, error) {
+		return NewGitBackend(GitOptions{RepoDir: repo, Branch: "karma-data", Path: path}), nil
+	}
+	testBackend(t, ".karma-data.json", open)
+	testBackendConflict(t, "conflict.json", open)
+}
+
+func TestGitBackend_Remote(t *testing.T) {
+	repo, remote := newGitRepo(t)
+	head := runGit(t, repo, "rev-parse", "HEAD")
+	opts := GitOptions{RepoDir: repo, Remote: "origin", Branch: "karma-data", Path: "data/karma.json"}
+
+	storage := NewStorageWithBackend(NewGitBackend(opts))
+	if err := storage.UpdateKarma(1, map[string]int{"alice": 3}); err != nil {
+		t.Fatalf("Failed to update karma: %v", err)
+	}
+	if err := storage.UpdateKarma(2, map[string]int{"bob": 2}); err != nil {
+		t.Fatalf("Failed to update karma: %v", err)
+	}
+
+	// The checkout is left alone
+	if got := runGit(t, repo, "rev-parse", "HEAD"); got != head {
+		t.Errorf("Expected HEAD to stay at %s, got %s", head, got)
+	}
+	if status := runGit(t, repo, "status", "--porcelain"); status != "" {
+		t.Errorf("Expected a clean working tree, got:\n%s", status)
+	}
+
+	// The data lives on an orphan branch on the remote, one commit per save
+	if count := runGit(t, remote, "rev-list", "--count", "karma-data"); count != "2" {
+		t.Errorf("Expected 2 commits on the data branch, got %s", count)
+	}
+	if files := runGit(t, remote, "ls-tree", "-r", "--name-only", "karma-data"); files != "data/karma.json" {
+		t.Errorf("Expected only the data file on the branch, got %q", files)
+	}
+
+	// A fresh clone without the local branch loads it from the remote
+	clone := filepath.Join(t.TempDir(), "clone")
+	runGit(t, t.TempDir(), "clone", "--quiet", remote, clone)
+	opts.RepoDir = clone
+	data, err := NewStorageWithBackend(NewGitBackend(opts)).Load()
+	if err != nil {
+		t.Fatalf("Failed to load karma data: %v", err)
+	}
+	if data.Reviewers["alice"] != 3 || data.Reviewers["bob"] != 2 {
+		t.Errorf("Expected alice 3 and bob 2, got %v", data.Reviewers)
+	}
+}
+
+func TestGitBackend_MergesConcurrentSave(t *testing.T) {
+	repo, remote := newGitRepo(t)
+	opts := GitOptions{RepoDir: repo, Remote: "origin", Branch: "karma-data", Path: ".karma-data.json"}
+
+	first := NewStorageWithBackend(NewGitBackend(opts))
+	tx, err := first.Begin()
+	if err != nil {
+		t.Fatalf("Failed to begin: %v", err)
+	}
+
+	// Another run pushes to the branch after the first one loaded it
+	clone := filepath.Join(t.TempDir(), "clone")
+	runGit(t, t.TempDir(), "clone", "--quiet", remote, clone)
+	cloneOpts := opts
+	cloneOpts.RepoDir = clone
+	if err := NewStorageWithBackend(NewGitBackend(cloneOpts)).UpdateKarma(1, map[string]int{"bob": 2}); err != nil {
+		t.Fatalf("Failed to update karma: %v", err)
+	}
+
+	tx.Apply(PRUpdate{PRNumber: 2, Entries: []LedgerEntry{{User: "alice", Points: 1}}, ProcessedAt: time.Now()})
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+
+	data, err := NewStorageWithBackend(NewGitBackend(cloneOpts)).Load()
+	if err != nil {
+		t.Fatalf("Failed to load karma data: %v", err)
+	}
+	if data.Reviewers["alice"] != 1 || data.Reviewers["bob"] != 2 {
+		t.Errorf("Expected both runs' points, got %v", data.Reviewers)
+	}
+	if count := runGit(t, remote, "rev-list", "--count", "karma-data"); count != "2" {
+		t.Errorf("Expected the merged commit on top of the other run's, got %s commits", count)
+	}
+}
@@ -0,0 +1,247 @@
+package storage
+
+import (
+	"sort"
+	"time"
+)
+
+// LedgerEntry is a single scored award. The ledger is append-only: when a PR
+// is re-scored, awards that changed are cancelled by reversal entries rather
+// than removed, so the full scoring history is kept.
+type LedgerEntry struct {
+	PRNumber   int       `json:"pr"`
+	EventKind  string    `json:"event_kind,omitempty"`
+	EventID    int64     `json:"event_id,omitempty"`
+	User       string    `json:"user"`
+	Rule       string    `json:"rule,omitempty"`
+	Points     int       `json:"points"`
+	Timestamp  time.Time `json:"timestamp"`          // When the scored activity happened
+	RecordedAt time.Time `json:"recorded_at"`        // When the entry was appended
+	Reversal   bool      `json:"reversal,omitempty"` // Cancels an earlier entry of the same event
+}
+
+// Event is a raw pull request event as it was fetched, kept so the PR can be
+// re-scored with a different configuration without fetching it again
+type Event struct {
+	Kind      string    `json:"kind"`
+	ID        int64     `json:"id"`
+	PRAuthor  string    `json:"pr_author,omitempty"`
+	User      string    `json:"user"`
+	Body      string    `json:"body,omitempty"`
+	State     string    `json:"state,omitempty"`
+	Reaction  string    `json:"reaction,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ledgerKey identifies the award an entry belongs to
+type ledgerKey struct {
+	prNumber  int
+	eventKind string
+	eventID   int64
+	user      string
+	rule      string
+}
+
+func (e LedgerEntry) key() ledgerKey {
+	return ledgerKey{e.PRNumber, e.EventKind, e.EventID, e.User, e.Rule}
+}
+
+// RecordPR stores the raw events of a PR, appends the entries scored from them
+// to the ledger and marks the PR processed. Only awards that differ from the
+// PR's current ones are recorded: changed or removed awards are reversed and
+// new or changed awards appended, so the totals only count its latest scoring.
+// A nil events slice means the PR's events are not kept.
+func (d *KarmaData) RecordPR(prNumber int, events []Event, entries []LedgerEntry, processedAt time.Time) {
+	now := time.Now()
+
+	currentEntries := d.PREntries(prNumber)
+	current := make(map[ledgerKey]int, len(currentEntries))
+	for _, entry := range currentEntries {
+		current[entry.key()] = entry.Points
+	}
+
+	scored := make(map[ledgerKey]int, len(entries))
+	for i := range entries {
+		entries[i].PRNumber = prNumber
+		scored[entries[i].key()] += entries[i].Points
+	}
+
+	for _, entry := range currentEntries {
+		if scored[entry.key()] == entry.Points {
+			continue // Unchanged
+		}
+		entry.Points = -entry.Points
+		entry.RecordedAt = now
+		entry.Reversal = true
+		d.appendEntry(entry)
+	}
+
+	for _, entry := range entries {
+		if current[entry.key()] == scored[entry.key()] {
+			continue // Unchanged
+		}
+		if entry.RecordedAt.IsZero() {
+			entry.RecordedAt = now
+		}
+		d.appendEntry(entry)
+	}
+
+	if events != nil {
+		d.Events[prNumber] = events
+	} else {
+		delete(d.Events, prNumber)
+	}
+
+	d.ProcessedPRs[prNumber] = processedAt
+
+	if d.dirtyPRs == nil {
+		d.dirtyPRs = make(map[int]bool)
+	}
+	d.dirtyPRs[prNumber] = true
+}
+
+// appendEntry appends an entry and applies its points to the totals
+func (d *KarmaData) appendEntry(entry LedgerEntry) {
+	d.indexLedger()
+	d.prLedger[entry.PRNumber] = append(d.prLedger[entry.PRNumber], len(d.Ledger))
+	d.indexedLedger++
+	d.Ledger = append(d.Ledger, entry)
+	d.Reviewers[entry.User] += entry.Points
+	if d.Reviewers[entry.User] == 0 {
+		delete(d.Reviewers, entry.User)
+	}
+}
+
+// PREntries returns the awards currently counted for a PR, with the points of
+// each event's entries netted and reversed awards left out
+func (d *KarmaData) PREntries(prNumber int) []LedgerEntry {
+	d.indexLedger()
+
+	var order []ledgerKey
+	net := make(map[ledgerKey]LedgerEntry)
+	for _, i := range d.prLedger[prNumber] {
+		entry := d.Ledger[i]
+		key := entry.key()
+		current, ok := net[key]
+		if !ok {
+			order = append(order, key)
+			current = entry
+			current.Points = 0
+			current.Reversal = false
+		}
+		current.Points += entry.Points
+		net[key] = current
+	}
+
+	var entries []LedgerEntry
+	for _, key := range order {
+		if net[key].Points != 0 {
+			entries = append(entries, net[key])
+		}
+	}
+	return entries
+}
+
+// indexLedger brings the index of entries by PR up to date with the ledger.
+// Entries appended directly to the ledger are indexed on the next call, and
+// the index is rebuilt if the ledger was replaced by a shorter one.
+func (d *KarmaData) indexLedger() {
+	if d.prLedger == nil || d.indexedLedger > len(d.Ledger) {
+		d.prLedger = make(map[int][]int)
+		d.indexedLedger = 0
+	}
+	for i := d.indexedLedger; i < len(d.Ledger); i++ {
+		prNumber := d.Ledger[i].PRNumber
+		d.prLedger[prNumber] = append(d.prLedger[prNumber], i)
+	}
+	d.indexedLedger = len(d.Ledger)
+}
+
+// PRTotals returns the points per user currently counted for a PR
+func (d *KarmaData) PRTotals(prNumber int) map[string]int {
+	totals := make(map[string]int)
+	for _, entry := range d.PREntries(prNumber) {
+		totals[entry.User] += entry.Points
+	}
+	return totals
+}
+
+// Totals derives the points per user from the baseline and the ledger
+func (d *KarmaData) Totals() map[string]int {
+	totals := make(map[string]int)
+	for username, points := range d.Baseline {
+		totals[username] += points
+	}
+	for _, entry := range d.Ledger {
+		totals[entry.User] += entry.Points
+	}
+	for username, points := range totals {
+		if points == 0 {
+			delete(totals, username)
+		}
+	}
+	return totals
+}
+
+// TotalsSince derives the points per user from the ledger entries for
+// activity at or after since. The baseline isn't dated, so it is left out.
+func (d *KarmaData) TotalsSince(since time.Time) map[string]int {
+	totals := make(map[string]int)
+	for _, entry := range d.Ledger {
+		if !entry.Timestamp.Before(since) {
+			totals[entry.User] += entry.Points
+		}
+	}
+	for username, points := range totals {
+		if points == 0 {
+			delete(totals, username)
+		}
+	}
+	return totals
+}
+
+// HasPRRecord reports whether the ledger holds the awards of a processed PR,
+// which is required to re-score it. PRs processed by versions without a
+// ledger only count towards the baseline.
+func (d *KarmaData) HasPRRecord(prNumber int) bool {
+	_, processed := d.ProcessedPRs[prNumber]
+	return processed && !d.LegacyPRs[prNumber]
+}
+
+// aggregateEntries converts per-user points into ledger entries that are not
+// tied to individual events
+func aggregateEntries(reviewerKarma map[string]int, timestamp time.Time) []LedgerEntry {
+	usernames := make([]string, 0, len(reviewerKarma))
+	for username := range reviewerKarma {
+		usernames = append(usernames, username)
+	}
+	sort.Strings(usernames)
+
+	entries := make([]LedgerEntry, 0, len(usernames))
+	for _, username := range usernames {
+		entries = append(entries, LedgerEntry{
+			User:      username,
+			Points:    reviewerKarma[username],
+			Timestamp: timestamp,
+		})
+	}
+	return entries
+}
+
+// importLegacy moves data written before the ledger existed into it. Those
+// files only kept totals, which can't be attributed to a PR, so they become
+// the baseline and every processed PR is marked as legacy.
+func (d *KarmaData) importLegacy() {
+	d.Ledger = []LedgerEntry{}
+	d.prLedger = nil
+	d.Baseline = make(map[string]int)
+	for username, points := range d.Reviewers {
+		if points != 0 {
+			d.Baseline[username] = points
+		}
+	}
+
+	for prNumber := range d.ProcessedPRs {
+		d.LegacyPRs[prNumber] = true
+	}
+}
@@ -0,0 +1,181 @@
+package storage
+
+import (
+	"maps"
+	"slices"
+	"sort"
+)
+
+// mixedFingerprint marks data scored partly with different scoring
+// configurations. It matches no configuration, so the next incremental
+// update rescores the stored events.
+const mixedFingerprint = "mixed"
+
+// Rescorer scores the stored events of a PR into ledger entries
+type Rescorer func(prNumber int, events []Event) []LedgerEntry
+
+// Merge combines two generations of karma data that were both derived from
+// base, such as by concurrent runs. theirs is the latest stored generation
+// and ours has unsaved changes. The PRs recorded in ours are re-recorded on
+// top of theirs, so the ledger stays append-only. When both changed the same
+// PR and stored its events, the events either side added or changed are
+// combined and scored with rescore. Without events or a rescore function,
+// the scoring of the most recent activity wins. None of the arguments are
+// modified.
+func Merge(base, ours, theirs *KarmaData, rescore Rescorer) *KarmaData {
+	merged := theirs.clone()
+	merged.dirtyPRs = nil
+
+	prNumbers := make([]int, 0, len(ours.dirtyPRs))
+	for prNumber := range ours.dirtyPRs {
+		prNumbers = append(prNumbers, prNumber)
+	}
+	sort.Ints(prNumbers)
+
+	applied := 0
+	for _, prNumber := range prNumbers {
+		processedAt := ours.ProcessedPRs[prNumber]
+		events := ours.Events[prNumber]
+		entries := ours.PREntries(prNumber)
+
+		if prChanged(base, theirs, prNumber) {
+			theirEvents, ok := theirs.Events[prNumber]
+			switch {
+			case rescore != nil && ok && events != nil:
+				events = mergeEvents(base.Events[prNumber], events, theirEvents)
+				entries = rescore(prNumber, events)
+				if theirs.ProcessedPRs[prNumber].After(processedAt) {
+					processedAt = theirs.ProcessedPRs[prNumber]
+				}
+			case theirs.ProcessedPRs[prNumber].After(processedAt):
+				continue // They scored newer activity on the PR
+			}
+		}
+
+		merged.RecordPR(prNumber, events, entries, processedAt)
+		applied++
+	}
+
+	// Keep the fingerprint only if every PR was scored with it
+	theirsChanged := theirs.ScoringFingerprint != base.ScoringFingerprint || changedPRs(base, theirs) > 0
+	oursChanged := ours.ScoringFingerprint != base.ScoringFingerprint || applied > 0
+	switch {
+	case ours.ScoringFingerprint == theirs.ScoringFingerprint, !oursChanged:
+	case !theirsChanged:
+		merged.ScoringFingerprint = ours.ScoringFingerprint
+	default:
+		merged.ScoringFingerprint = mixedFingerprint
+	}
+
+	if ours.LastUpdated.After(merged.LastUpdated) {
+		merged.LastUpdated = ours.LastUpdated
+	}
+
+	return merged
+}
+
+// eventKey identifies a stored event
+type eventKey struct {
+	kind string
+	id   int64
+}
+
+// mergeEvents combines the events two sides stored for a PR since base. An
+// event either side added is kept and one either side removed is dropped.
+// When both kept an event, our version is used if we changed it.
+func mergeEvents(base, ours, theirs []Event) []Event {
+	baseEvents := make(map[eventKey]Event, len(base))
+	for _, event := range base {
+		baseEvents[eventKey{event.Kind, event.ID}] = event
+	}
+	ourEvents := make(map[eventKey]Event, len(ours))
+	for _, event := range ours {
+		ourEvents[eventKey{event.Kind, event.ID}] = event
+	}
+	theirKeys := make(map[eventKey]bool, len(theirs))
+
+	merged := make([]Event, 0, len(theirs)+len(ours))
+	for _, event := range theirs {
+		key := eventKey{event.Kind, event.ID}
+		theirKeys[key] = true
+		baseEvent, inBase := baseEvents[key]
+		ourEvent, inOurs := ourEvents[key]
+		switch {
+		case inBase && !inOurs:
+			continue // We removed it
+		case inOurs && (!inBase || !sameEvent(ourEvent, baseEvent)):
+			event = ourEvent
+		}
+		merged = append(merged, event)
+	}
+	for _, event := range ours {
+		key := eventKey{event.Kind, event.ID}
+		if _, inBase := baseEvents[key]; !theirKeys[key] && !inBase {
+			merged = append(merged, event)
+		}
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].CreatedAt.Before(merged[j].CreatedAt)
+	})
+	return merged
+}
+
+// sameEvent reports whether two stored events are identical
+func sameEvent(a, b Event) bool {
+	a.CreatedAt, b.CreatedAt = a.CreatedAt.UTC(), b.CreatedAt.UTC()
+	return a == b
+}
+
+// prChanged reports whether a PR was re-recorded in data since base
+func prChanged(base, data *KarmaData, prNumber int) bool {
+	baseProcessed, inBase := base.ProcessedPRs[prNumber]
+	processed, inData := data.ProcessedPRs[prNumber]
+	if inBase != inData || !baseProcessed.Equal(processed) {
+		return true
+	}
+	return !sameAwards(base.PREntries(prNumber), data.PREntries(prNumber))
+}
+
+// changedPRs counts the PRs re-recorded in data since base
+func changedPRs(base, data *KarmaData) int {
+	changed := 0
+	for prNumber := range data.ProcessedPRs {
+		if prChanged(base, data, prNumber) {
+			changed++
+		}
+	}
+	return changed
+}
+
+// sameAwards reports whether two sets of netted entries award the same points
+func sameAwards(a, b []LedgerEntry) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	points := make(map[ledgerKey]int, len(a))
+	for _, entry := range a {
+		points[entry.key()] = entry.Points
+	}
+	for _, entry := range b {
+		if current, ok := points[entry.key()]; !ok || current != entry.Points {
+			return false
+		}
+	}
+	return true
+}
+
+// clone returns a copy of d that can be changed without affecting d. Event
+// slices are shared, since RecordPR replaces them rather than modifying them.
+func (d *KarmaData) clone() *KarmaData {
+	c := *d
+	c.Reviewers = maps.Clone(d.Reviewers)
+	c.ProcessedPRs = maps.Clone(d.ProcessedPRs)
+	c.Ledger = slices.Clone(d.Ledger)
+	c.Events = maps.Clone(d.Events)
+	c.Baseline = maps.Clone(d.Baseline)
+	c.LegacyPRs = maps.Clone(d.LegacyPRs)
+	c.dirtyPRs = maps.Clone(d.dirtyPRs)
+	c.prLedger = nil // Rebuilt on use, its slices must not be shared
+	return &c
+}
@@ -0,0 +1,174 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMerge(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	award := func(user string, points int) []LedgerEntry {
+		return []LedgerEntry{{User: user, Points: points}}
+	}
+
+	base := newKarmaData(day(1))
+	base.ScoringFingerprint = "v1"
+	base.RecordPR(1, nil, award("alice", 1), day(1))
+	base.RecordPR(2, nil, award("bob", 1), day(1))
+	base.dirtyPRs = nil
+
+	tests := []struct {
+		name        string
+		ours        func(d *KarmaData)
+		theirs      func(d *KarmaData)
+		expected    map[string]int
+		fingerprint string
+	}{
+		{
+			name:        "Disjoint PRs",
+			ours:        func(d *KarmaData) { d.RecordPR(3, nil, award("carol", 2), day(2)) },
+			theirs:      func(d *KarmaData) { d.RecordPR(4, nil, award("dave", 3), day(2)) },
+			expected:    map[string]int{"alice": 1, "bob": 1, "carol": 2, "dave": 3},
+			fingerprint: "v1",
+		},
+		{
+			name:        "Our newer scoring of the same PR wins",
+			ours:        func(d *KarmaData) { d.RecordPR(1, nil, award("alice", 5), day(3)) },
+			theirs:      func(d *KarmaData) { d.RecordPR(1, nil, award("alice", 2), day(2)) },
+			expected:    map[string]int{"alice": 5, "bob": 1},
+			fingerprint: "v1",
+		},
+		{
+			name:        "Their newer scoring of the same PR wins",
+			ours:        func(d *KarmaData) { d.RecordPR(1, nil, award("alice", 5), day(2)) },
+			theirs:      func(d *KarmaData) { d.RecordPR(1, nil, award("alice", 2), day(3)) },
+			expected:    map[string]int{"alice": 2, "bob": 1},
+			fingerprint: "v1",
+		},
+		{
+			name: "Our rescore with their unchanged data",
+			ours: func(d *KarmaData) {
+				d.ScoringFingerprint = "v2"
+				d.RecordPR(1, nil, award("alice", 4), day(1))
+			},
+			theirs:      func(d *KarmaData) {},
+			expected:    map[string]int{"alice": 4, "bob": 1},
+			fingerprint: "v2",
+		},
+		{
+			name: "Our rescore with their new PR",
+			ours: func(d *KarmaData) {
+				d.ScoringFingerprint = "v2"
+				d.RecordPR(1, nil, award("alice", 4), day(1))
+			},
+			theirs:      func(d *KarmaData) { d.RecordPR(3, nil, award("carol", 2), day(2)) },
+			expected:    map[string]int{"alice": 4, "bob": 1, "carol": 2},
+			fingerprint: mixedFingerprint,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ours, theirs := base.clone(), base.clone()
+			tt.ours(ours)
+			tt.theirs(theirs)
+			theirsLedger := len(theirs.Ledger)
+
+			merged := Merge(base, ours, theirs, nil)
+
+			if len(merged.Reviewers) != len(tt.expected) {
+				t.Errorf("Expected reviewers %v, got %v", tt.expected, merged.Reviewers)
+			}
+			for username, points := range tt.expected {
+				if merged.Reviewers[username] != points {
+					t.Errorf("Expected %s to have %d points, got %d", username, points, merged.Reviewers[username])
+				}
+			}
+			totals := merged.Totals()
+			for username, points := range merged.Reviewers {
+				if totals[username] != points {
+					t.Errorf("Expected %s's total %d to match the ledger, got %d", username, points, totals[username])
+				}
+			}
+			if merged.ScoringFingerprint != tt.fingerprint {
+				t.Errorf("Expected fingerprint %q, got %q", tt.fingerprint, merged.ScoringFingerprint)
+			}
+
+			// Their ledger is kept as is and only appended to
+			if len(theirs.Ledger) != theirsLedger || len(merged.Ledger) < theirsLedger {
+				t.Errorf("Expected their ledger of %d entries to be kept, got %d merged", theirsLedger, len(merged.Ledger))
+			}
+			for i := range theirs.Ledger {
+				if merged.Ledger[i] != theirs.Ledger[i] {
+					t.Errorf("Expected ledger entry %d to be unchanged, got %+v", i, merged.Ledger[i])
+				}
+			}
+		})
+	}
+}
+
+func TestMergeCombinesEventsOfTheSamePR(t *testing.T) {
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	event := func(kind string, id int64, user string) Event {
+		return Event{Kind: kind, ID: id, User: user, CreatedAt: at.Add(time.Duration(id) * time.Hour)}
+	}
+	// Every event earns its user a point
+	rescore := func(prNumber int, events []Event) []LedgerEntry {
+		entries := make([]LedgerEntry, len(events))
+		for i, event := range events {
+			entries[i] = LedgerEntry{EventKind: event.Kind, EventID: event.ID, User: event.User, Points: 1, Timestamp: event.CreatedAt}
+		}
+		return entries
+	}
+	record := func(d *KarmaData, events ...Event) {
+		d.RecordPR(1, events, rescore(1, events), at)
+	}
+
+	base := newKarmaData(at)
+	record(base, event("review", 1, "alice"))
+	base.dirtyPRs = nil
+
+	tests := []struct {
+		name     string
+		ours     []Event
+		theirs   []Event
+		expected map[string]int
+	}{
+		{
+			// A review with inline comments triggers two runs at once, and
+			// event mode keeps the stored processed time
+			name:     "Both added an event",
+			ours:     []Event{event("review", 1, "alice"), event("review_comment", 3, "carol")},
+			theirs:   []Event{event("review", 1, "alice"), event("review", 2, "bob")},
+			expected: map[string]int{"alice": 1, "bob": 1, "carol": 1},
+		},
+		{
+			name:     "They removed an event",
+			ours:     []Event{event("review", 1, "alice"), event("review_comment", 3, "carol")},
+			theirs:   nil,
+			expected: map[string]int{"carol": 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ours, theirs := base.clone(), base.clone()
+			record(ours, tt.ours...)
+			theirs.RecordPR(1, append([]Event{}, tt.theirs...), rescore(1, tt.theirs), at)
+
+			merged := Merge(base, ours, theirs, rescore)
+
+			if len(merged.Reviewers) != len(tt.expected) {
+				t.Errorf("Expected reviewers %v, got %v", tt.expected, merged.Reviewers)
+			}
+			for username, points := range tt.expected {
+				if merged.Reviewers[username] != points {
+					t.Errorf("Expected %s to have %d points, got %d", username, points, merged.Reviewers[username])
+				}
+			}
+			if len(merged.Events[1]) != len(tt.expected) {
+				t.Errorf("Expected %d merged events, got %+v", len(tt.expected), merged.Events[1])
+			}
+		})
+	}
+}
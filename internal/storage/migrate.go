@@ -0,0 +1,264 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// CurrentSchemaVersion is the schema version of data files written by Save
+const CurrentSchemaVersion = 2
+
+// document is a data file decoded only down to its top-level keys, so
+// migrations can reshape files whose layout differs from KarmaData
+type document map[string]json.RawMessage
+
+// Migration upgrades a data file from one schema version to the next
+type Migration struct {
+	From        int
+	Description string
+	Apply       func(doc document) error
+}
+
+// migrations is the registry of schema upgrades, keyed by the version they
+// upgrade from. Every version below CurrentSchemaVersion must have one.
+var migrations = map[int]Migration{
+	1: {
+		From:        1,
+		Description: "move totals into the per-event ledger",
+		Apply:       migrateToLedger,
+	},
+}
+
+// MigrationResult describes the upgrade of a data file to the current schema
+type MigrationResult struct {
+	FromVersion int
+	ToVersion   int
+	Applied     []Migration
+	Changes     []string // Top-level keys added, removed or changed
+}
+
+// Migrate upgrades the stored data to the current schema version. With dryRun
+// the data is left untouched and the result only describes what would change.
+func (s *Storage) Migrate(dryRun bool) (*MigrationResult, error) {
+	migrator, ok := s.backend.(Migrator)
+	if !ok {
+		return nil, fmt.Errorf("storage backend %T does not support migrations", s.backend)
+	}
+	return migrator.Migrate(dryRun)
+}
+
+// Migrate upgrades the data file to the current schema version. With dryRun
+// the file is left untouched and the result only describes what would change.
+func (b *FileBackend) Migrate(dryRun bool) (*MigrationResult, error) {
+	raw, err := os.ReadFile(b.filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read karma data: %w", err)
+	}
+
+	original, err := decodeDocument(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	// Migrate a copy so the original can be compared afterwards
+	doc := make(document, len(original))
+	for key, value := range original {
+		doc[key] = value
+	}
+
+	result, err := migrateDocument(doc)
+	if err != nil {
+		return nil, err
+	}
+	result.Changes = documentChanges(original, doc)
+
+	if dryRun || len(result.Applied) == 0 {
+		return result, nil
+	}
+
+	data, err := documentKarmaData(doc)
+	if err != nil {
+		return nil, err
+	}
+	data.LastUpdated = time.Now()
+	if err := b.Save(data); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// decodeDocument decodes the top-level keys of a data file
+func decodeDocument(raw []byte) (document, error) {
+	doc := make(document)
+	if len(bytes.TrimSpace(raw)) == 0 {
+		return doc, nil
+	}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal karma data: %w", err)
+	}
+	return doc, nil
+}
+
+// schemaVersion returns the schema version of a document. Files written
+// before the version was stored are version 1, or version 2 if they already
+// have a ledger.
+func schemaVersion(doc document) (int, error) {
+	raw, ok := doc["schema_version"]
+	if !ok {
+		if _, hasLedger := doc["ledger"]; hasLedger {
+			return 2, nil
+		}
+		return 1, nil
+	}
+
+	var version int
+	if err := json.Unmarshal(raw, &version); err != nil {
+		return 0, fmt.Errorf("invalid schema version %s: %w", raw, err)
+	}
+	return version, nil
+}
+
+// migrateDocument applies every migration needed to bring doc to the
+// current schema version, in order
+func migrateDocument(doc document) (*MigrationResult, error) {
+	version, err := schemaVersion(doc)
+	if err != nil {
+		return nil, err
+	}
+	if version > CurrentSchemaVersion {
+		return nil, fmt.Errorf("karma data has schema version %d, but this version of reviewer-karma only supports up to %d; please upgrade", version, CurrentSchemaVersion)
+	}
+
+	result := &MigrationResult{FromVersion: version, ToVersion: CurrentSchemaVersion}
+	for ; version < CurrentSchemaVersion; version++ {
+		migration, ok := migrations[version]
+		if !ok {
+			return nil, fmt.Errorf("no migration from schema version %d", version)
+		}
+		if err := migration.Apply(doc); err != nil {
+			return nil, fmt.Errorf("failed to migrate karma data from schema version %d: %w", version, err)
+		}
+		result.Applied = append(result.Applied, migration)
+	}
+
+	doc["schema_version"] = json.RawMessage(fmt.Sprint(CurrentSchemaVersion))
+	return result, nil
+}
+
+// documentKarmaData decodes a document at the current schema version
+func documentKarmaData(doc document) (*KarmaData, error) {
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal karma data: %w", err)
+	}
+
+	var karmaData KarmaData
+	if err := json.Unmarshal(raw, &karmaData); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal karma data: %w", err)
+	}
+
+	// Initialize maps if they're nil
+	if karmaData.Reviewers == nil {
+		karmaData.Reviewers = make(map[string]int)
+	}
+	if karmaData.ProcessedPRs == nil {
+		karmaData.ProcessedPRs = make(map[int]time.Time)
+	}
+	if karmaData.Ledger == nil {
+		karmaData.Ledger = []LedgerEntry{}
+	}
+	if karmaData.Events == nil {
+		karmaData.Events = make(map[int][]Event)
+	}
+	if karmaData.LegacyPRs == nil {
+		karmaData.LegacyPRs = make(map[int]bool)
+	}
+
+	// Totals are always derived from the ledger
+	karmaData.Reviewers = karmaData.Totals()
+
+	return &karmaData, nil
+}
+
+// documentChanges lists the top-level keys that differ between two documents
+func documentChanges(before, after document) []string {
+	keys := make(map[string]bool)
+	for key := range before {
+		keys[key] = true
+	}
+	for key := range after {
+		keys[key] = true
+	}
+
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	var changes []string
+	for _, key := range sorted {
+		old, hadKey := before[key]
+		updated, hasKey := after[key]
+		switch {
+		case !hadKey:
+			changes = append(changes, "added "+key)
+		case !hasKey:
+			changes = append(changes, "removed "+key)
+		case !jsonEqual(old, updated):
+			changes = append(changes, "changed "+key)
+		}
+	}
+	return changes
+}
+
+// jsonEqual reports whether two JSON values are equal, ignoring formatting
+func jsonEqual(a, b json.RawMessage) bool {
+	var bufA, bufB bytes.Buffer
+	if json.Compact(&bufA, a) != nil || json.Compact(&bufB, b) != nil {
+		return bytes.Equal(a, b)
+	}
+	return bytes.Equal(bufA.Bytes(), bufB.Bytes())
+}
+
+// migrateToLedger upgrades version 1, which only stored the totals and the
+// processed PRs, to the ledger layout
+func migrateToLedger(doc document) error {
+	var v1 struct {
+		Reviewers    map[string]int    `json:"reviewers"`
+		LastUpdated  time.Time         `json:"last_updated"`
+		ProcessedPRs map[int]time.Time `json:"processed_prs"`
+	}
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(raw, &v1); err != nil {
+		return err
+	}
+
+	data := newKarmaData(v1.LastUpdated)
+	for username, points := range v1.Reviewers {
+		data.Reviewers[username] = points
+	}
+	for prNumber, processedAt := range v1.ProcessedPRs {
+		data.ProcessedPRs[prNumber] = processedAt
+	}
+	data.importLegacy()
+	data.Reviewers = data.Totals()
+
+	raw, err = json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	for key := range doc {
+		delete(doc, key)
+	}
+	return json.Unmarshal(raw, &doc)
+}
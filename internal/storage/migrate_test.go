@@ -0,0 +1,121 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// Written by the first release, before per-PR points were stored
+const versionOneData = `{
+  "reviewers": {"alice": 7, "bob": 3},
+  "last_updated": "2024-01-02T00:00:00Z",
+  "processed_prs": {"1": "2024-01-01T00:00:00Z", "2": "2024-01-02T00:00:00Z"}
+}`
+
+func writeDataFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), ".karma-data.json")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write data file: %v", err)
+	}
+	return path
+}
+
+func TestStorage_MigrateDryRun(t *testing.T) {
+	path := writeDataFile(t, versionOneData)
+
+	result, err := NewStorage(path).Migrate(true)
+	if err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+
+	if result.FromVersion != 1 || result.ToVersion != CurrentSchemaVersion {
+		t.Errorf("Expected migration from 1 to %d, got %d to %d", CurrentSchemaVersion, result.FromVersion, result.ToVersion)
+	}
+	if len(result.Applied) != 1 {
+		t.Errorf("Expected 1 migration, got %d", len(result.Applied))
+	}
+	for _, change := range []string{"added ledger", "added schema_version", "added baseline"} {
+		if !slices.Contains(result.Changes, change) {
+			t.Errorf("Expected change %q, got %v", change, result.Changes)
+		}
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read data file: %v", err)
+	}
+	if string(content) != versionOneData {
+		t.Errorf("Expected dry run to leave the file untouched, got:\n%s", content)
+	}
+}
+
+func TestStorage_Migrate(t *testing.T) {
+	path := writeDataFile(t, versionOneData)
+	storage := NewStorage(path)
+
+	if _, err := storage.Migrate(false); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read data file: %v", err)
+	}
+	if !strings.Contains(string(content), `"schema_version": 2`) {
+		t.Errorf("Expected the file to be at schema version 2, got:\n%s", content)
+	}
+
+	data, err := storage.Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	if data.Reviewers["alice"] != 7 || data.Reviewers["bob"] != 3 {
+		t.Errorf("Expected totals to be kept, got %v", data.Reviewers)
+	}
+
+	// Migrating again is a no-op
+	result, err := storage.Migrate(false)
+	if err != nil {
+		t.Fatalf("Failed to migrate again: %v", err)
+	}
+	if len(result.Applied) != 0 {
+		t.Errorf("Expected no migrations, got %d", len(result.Applied))
+	}
+}
+
+func TestStorage_LoadSchemaVersions(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{"version 1", versionOneData, false},
+		{"ledger without version", `{"reviewers": {}, "processed_prs": {}, "ledger": []}`, false},
+		{"current version", `{"schema_version": 2, "ledger": []}`, false},
+		{"future version", `{"schema_version": 99, "ledger": []}`, true},
+	}
+
+	for _, test := range tests {
+		_, err := NewStorage(writeDataFile(t, test.content)).Load()
+		if (err != nil) != test.wantErr {
+			t.Errorf("%s: expected error %v, got: %v", test.name, test.wantErr, err)
+		}
+	}
+}
+
+func TestMigrationRegistryIsComplete(t *testing.T) {
+	for version := 1; version < CurrentSchemaVersion; version++ {
+		migration, ok := migrations[version]
+		if !ok {
+			t.Errorf("Expected a migration from schema version %d", version)
+			continue
+		}
+		if migration.From != version || migration.Description == "" {
+			t.Errorf("Migration from version %d is misconfigured: %+v", version, migration)
+		}
+	}
+}
@@ -0,0 +1,491 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	// Pure Go SQLite driver, so the binary still builds without cgo
+	_ "modernc.org/sqlite"
+)
+
+// sqliteDriver is the database/sql driver name registered by modernc.org/sqlite
+const sqliteDriver = "sqlite"
+
+// sqliteSchema creates the tables of the SQLite backend. Ledger rows are
+// numbered by their position in the append-only ledger.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS meta (
+	name  TEXT PRIMARY KEY,
+	value TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS processed_prs (
+	pr           INTEGER PRIMARY KEY,
+	processed_at TEXT NOT NULL,
+	legacy       INTEGER NOT NULL DEFAULT 0,
+	has_events   INTEGER NOT NULL DEFAULT 0
+);
+CREATE TABLE IF NOT EXISTS ledger (
+	seq         INTEGER PRIMARY KEY,
+	pr          INTEGER NOT NULL,
+	event_kind  TEXT NOT NULL,
+	event_id    INTEGER NOT NULL,
+	username    TEXT NOT NULL,
+	rule        TEXT NOT NULL,
+	points      INTEGER NOT NULL,
+	occurred_at TEXT NOT NULL,
+	recorded_at TEXT NOT NULL,
+	reversal    INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS ledger_pr ON ledger (pr);
+CREATE INDEX IF NOT EXISTS ledger_username ON ledger (username);
+CREATE TABLE IF NOT EXISTS events (
+	pr         INTEGER NOT NULL,
+	seq        INTEGER NOT NULL,
+	kind       TEXT NOT NULL,
+	id         INTEGER NOT NULL,
+	pr_author  TEXT NOT NULL,
+	username   TEXT NOT NULL,
+	body       TEXT NOT NULL,
+	state      TEXT NOT NULL,
+	reaction   TEXT NOT NULL,
+	created_at TEXT NOT NULL,
+	PRIMARY KEY (pr, seq)
+);
+CREATE TABLE IF NOT EXISTS baseline (
+	username TEXT PRIMARY KEY,
+	points   INTEGER NOT NULL
+);
+`
+
+// SQLiteBackend stores karma data in a SQLite database, one row per ledger
+// entry, event and processed PR. Saving only writes the rows that changed,
+// and the history can be queried with SQL directly. Like FileBackend, it only
+// detects conflicts between runs sharing the database file.
+type SQLiteBackend struct {
+	db *sql.DB
+
+	loaded     bool
+	generation string // Generation of the data when it was loaded
+}
+
+// NewSQLiteBackend opens or creates the SQLite database at path
+func NewSQLiteBackend(path string) (*SQLiteBackend, error) {
+	db, err := sql.Open(sqliteDriver, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open karma database: %w", err)
+	}
+
+	// A single connection keeps writes serialized
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create karma database schema: %w", err)
+	}
+
+	_, err = db.Exec(`INSERT OR IGNORE INTO meta (name, value) VALUES ('schema_version', ?)`, strconv.Itoa(CurrentSchemaVersion))
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create karma database schema: %w", err)
+	}
+
+	return &SQLiteBackend{db: db}, nil
+}
+
+// Load reads all karma data from the database
+func (b *SQLiteBackend) Load() (*KarmaData, error) {
+	data := newKarmaData(time.Time{})
+
+	meta, err := b.meta()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read karma data: %w", err)
+	}
+	if err := checkSQLiteVersion(meta); err != nil {
+		return nil, err
+	}
+	if value := meta["last_updated"]; value != "" {
+		if data.LastUpdated, err = parseSQLiteTime(value); err != nil {
+			return nil, fmt.Errorf("failed to read karma data: %w", err)
+		}
+	}
+	data.ScoringFingerprint = meta["scoring_fingerprint"]
+	b.generation, b.loaded = meta["generation"], true
+
+	if err := b.loadRows(data); err != nil {
+		return nil, fmt.Errorf("failed to read karma data: %w", err)
+	}
+
+	// Totals are always derived from the ledger
+	data.Reviewers = data.Totals()
+
+	return data, nil
+}
+
+// meta returns the name/value pairs of the meta table
+func (b *SQLiteBackend) meta() (map[string]string, error) {
+	rows, err := b.db.Query(`SELECT name, value FROM meta`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	meta := make(map[string]string)
+	for rows.Next() {
+		var name, value string
+		if err := rows.Scan(&name, &value); err != nil {
+			return nil, err
+		}
+		meta[name] = value
+	}
+	return meta, rows.Err()
+}
+
+// checkSQLiteVersion rejects databases written by a newer version
+func checkSQLiteVersion(meta map[string]string) error {
+	version, err := strconv.Atoi(meta["schema_version"])
+	if err != nil {
+		return fmt.Errorf("invalid schema version %q: %w", meta["schema_version"], err)
+	}
+	if version > CurrentSchemaVersion {
+		return fmt.Errorf("karma data has schema version %d, but this version of reviewer-karma only supports up to %d; please upgrade", version, CurrentSchemaVersion)
+	}
+	return nil
+}
+
+// loadRows reads the processed PRs, events, ledger and baseline into data
+func (b *SQLiteBackend) loadRows(data *KarmaData) error {
+	rows, err := b.db.Query(`SELECT pr, processed_at, legacy, has_events FROM processed_prs`)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var prNumber int
+		var processedAt string
+		var legacy, hasEvents bool
+		if err := rows.Scan(&prNumber, &processedAt, &legacy, &hasEvents); err != nil {
+			rows.Close()
+			return err
+		}
+		if data.ProcessedPRs[prNumber], err = parseSQLiteTime(processedAt); err != nil {
+			rows.Close()
+			return err
+		}
+		if legacy {
+			data.LegacyPRs[prNumber] = true
+		}
+		if hasEvents {
+			data.Events[prNumber] = []Event{}
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = b.db.Query(`SELECT pr, kind, id, pr_author, username, body, state, reaction, created_at FROM events ORDER BY pr, seq`)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var prNumber int
+		var event Event
+		var createdAt string
+		if err := rows.Scan(&prNumber, &event.Kind, &event.ID, &event.PRAuthor, &event.User, &event.Body, &event.State, &event.Reaction, &createdAt); err != nil {
+			rows.Close()
+			return err
+		}
+		if event.CreatedAt, err = parseSQLiteTime(createdAt); err != nil {
+			rows.Close()
+			return err
+		}
+		data.Events[prNumber] = append(data.Events[prNumber], event)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = b.db.Query(`SELECT pr, event_kind, event_id, username, rule, points, occurred_at, recorded_at, reversal FROM ledger ORDER BY seq`)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var entry LedgerEntry
+		var timestamp, recordedAt string
+		if err := rows.Scan(&entry.PRNumber, &entry.EventKind, &entry.EventID, &entry.User, &entry.Rule, &entry.Points, &timestamp, &recordedAt, &entry.Reversal); err != nil {
+			rows.Close()
+			return err
+		}
+		if entry.Timestamp, err = parseSQLiteTime(timestamp); err != nil {
+			rows.Close()
+			return err
+		}
+		if entry.RecordedAt, err = parseSQLiteTime(recordedAt); err != nil {
+			rows.Close()
+			return err
+		}
+		data.Ledger = append(data.Ledger, entry)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = b.db.Query(`SELECT username, points FROM baseline`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var username string
+		var points int
+		if err := rows.Scan(&username, &points); err != nil {
+			return err
+		}
+		if data.Baseline == nil {
+			data.Baseline = make(map[string]int)
+		}
+		data.Baseline[username] = points
+	}
+	return rows.Err()
+}
+
+// Save writes karma data to the database in a single transaction. Only
+// ledger entries appended since the last save and PRs recorded since then
+// are written.
+func (b *SQLiteBackend) Save(data *KarmaData) error {
+	tx, err := b.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to write karma data: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Every save bumps the generation, so a save on top of data that was
+	// changed since it was loaded can be detected
+	var generation string
+	err = tx.QueryRow(`SELECT value FROM meta WHERE name = 'generation'`).Scan(&generation)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("failed to write karma data: %w", err)
+	}
+	if b.loaded && generation != b.generation {
+		return ErrConflict
+	}
+	next := "1"
+	if n, err := strconv.Atoi(generation); err == nil {
+		next = strconv.Itoa(n + 1)
+	}
+
+	if err := saveSQLite(tx, data, next); err != nil {
+		return fmt.Errorf("failed to write karma data: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to write karma data: %w", err)
+	}
+
+	b.generation, b.loaded = next, true
+	return nil
+}
+
+// saveSQLite writes the changes in data within tx as the given generation
+func saveSQLite(tx *sql.Tx, data *KarmaData, generation string) error {
+	meta := map[string]string{
+		"generation":          generation,
+		"schema_version":      strconv.Itoa(data.SchemaVersion),
+		"last_updated":        formatSQLiteTime(data.LastUpdated),
+		"scoring_fingerprint": data.ScoringFingerprint,
+	}
+	for name, value := range meta {
+		if _, err := tx.Exec(`INSERT OR REPLACE INTO meta (name, value) VALUES (?, ?)`, name, value); err != nil {
+			return err
+		}
+	}
+
+	if err := saveLedger(tx, data.Ledger); err != nil {
+		return err
+	}
+	if err := savePRs(tx, data); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM baseline`); err != nil {
+		return err
+	}
+	for username, points := range data.Baseline {
+		if _, err := tx.Exec(`INSERT INTO baseline (username, points) VALUES (?, ?)`, username, points); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// saveLedger appends the ledger entries that are not stored yet. A ledger
+// that doesn't continue the stored one, such as after Clear, replaces it.
+func saveLedger(tx *sql.Tx, ledger []LedgerEntry) error {
+	var stored int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM ledger`).Scan(&stored); err != nil {
+		return err
+	}
+
+	continues, err := continuesLedger(tx, ledger, stored)
+	if err != nil {
+		return err
+	}
+	if !continues {
+		if _, err := tx.Exec(`DELETE FROM ledger`); err != nil {
+			return err
+		}
+		stored = 0
+	}
+
+	insert, err := tx.Prepare(`INSERT INTO ledger (seq, pr, event_kind, event_id, username, rule, points, occurred_at, recorded_at, reversal)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer insert.Close()
+
+	for i := stored; i < len(ledger); i++ {
+		entry := ledger[i]
+		_, err := insert.Exec(i+1, entry.PRNumber, entry.EventKind, entry.EventID, entry.User, entry.Rule, entry.Points,
+			formatSQLiteTime(entry.Timestamp), formatSQLiteTime(entry.RecordedAt), entry.Reversal)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// continuesLedger reports whether the first stored entries of ledger are the
+// ones in the database, judged by the last stored entry
+func continuesLedger(tx *sql.Tx, ledger []LedgerEntry, stored int) (bool, error) {
+	if stored == 0 {
+		return true, nil
+	}
+	if stored > len(ledger) {
+		return false, nil
+	}
+
+	var prNumber, points int
+	var username, recordedAt string
+	err := tx.QueryRow(`SELECT pr, username, points, recorded_at FROM ledger WHERE seq = ?`, stored).Scan(&prNumber, &username, &points, &recordedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	last := ledger[stored-1]
+	return last.PRNumber == prNumber && last.User == username && last.Points == points &&
+		formatSQLiteTime(last.RecordedAt) == recordedAt, nil
+}
+
+// savePRs writes the processed PRs and events recorded since the last save
+// and those not stored yet, and deletes PRs that are no longer in data
+func savePRs(tx *sql.Tx, data *KarmaData) error {
+	stored := make(map[int]bool)
+	rows, err := tx.Query(`SELECT pr FROM processed_prs`)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var prNumber int
+		if err := rows.Scan(&prNumber); err != nil {
+			rows.Close()
+			return err
+		}
+		stored[prNumber] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for prNumber := range stored {
+		if _, processed := data.ProcessedPRs[prNumber]; processed {
+			continue
+		}
+		if err := deletePR(tx, prNumber); err != nil {
+			return err
+		}
+	}
+
+	insertEvent, err := tx.Prepare(`INSERT INTO events (pr, seq, kind, id, pr_author, username, body, state, reaction, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer insertEvent.Close()
+
+	for prNumber, processedAt := range data.ProcessedPRs {
+		if stored[prNumber] && !data.dirtyPRs[prNumber] {
+			continue
+		}
+		if err := deletePR(tx, prNumber); err != nil {
+			return err
+		}
+
+		events, hasEvents := data.Events[prNumber]
+		_, err := tx.Exec(`INSERT INTO processed_prs (pr, processed_at, legacy, has_events) VALUES (?, ?, ?, ?)`,
+			prNumber, formatSQLiteTime(processedAt), data.LegacyPRs[prNumber], hasEvents)
+		if err != nil {
+			return err
+		}
+
+		for i, event := range events {
+			_, err := insertEvent.Exec(prNumber, i, event.Kind, event.ID, event.PRAuthor, event.User, event.Body,
+				event.State, event.Reaction, formatSQLiteTime(event.CreatedAt))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// deletePR deletes a processed PR and its events
+func deletePR(tx *sql.Tx, prNumber int) error {
+	if _, err := tx.Exec(`DELETE FROM processed_prs WHERE pr = ?`, prNumber); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`DELETE FROM events WHERE pr = ?`, prNumber)
+	return err
+}
+
+// Migrate reports the schema version of the database. Its tables are always
+// created at the current schema version, so there is nothing to upgrade.
+func (b *SQLiteBackend) Migrate(dryRun bool) (*MigrationResult, error) {
+	meta, err := b.meta()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read karma data: %w", err)
+	}
+	if err := checkSQLiteVersion(meta); err != nil {
+		return nil, err
+	}
+	return &MigrationResult{FromVersion: CurrentSchemaVersion, ToVersion: CurrentSchemaVersion}, nil
+}
+
+// Close closes the database
+func (b *SQLiteBackend) Close() error {
+	return b.db.Close()
+}
+
+// formatSQLiteTime formats a time for storage, keeping its offset
+func formatSQLiteTime(t time.Time) string {
+	return t.Format(time.RFC3339Nano)
+}
+
+// parseSQLiteTime parses a time written by formatSQLiteTime
+func parseSQLiteTime(value string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q: %w", value, err)
+	}
+	return t, nil
+}
@@ -0,0 +1,30 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestSQLiteBackend(t *testing.T) {
+	open := func(path string) (Backend, error) {
+		return NewSQLiteBackend(path)
+	}
+	testBackend(t, filepath.Join(t.TempDir(), "karma.db"), open)
+	testBackendConflict(t, filepath.Join(t.TempDir(), "karma.db"), open)
+}
+
+func TestSQLiteBackend_Migrate(t *testing.T) {
+	backend, err := NewSQLiteBackend(filepath.Join(t.TempDir(), "karma.db"))
+	if err != nil {
+		t.Fatalf("Failed to open backend: %v", err)
+	}
+	defer backend.Close()
+
+	result, err := NewStorageWithBackend(backend).Migrate(false)
+	if err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	if len(result.Applied) != 0 || result.ToVersion != CurrentSchemaVersion {
+		t.Errorf("Expected no migrations to version %d, got %+v", CurrentSchemaVersion, result)
+	}
+}
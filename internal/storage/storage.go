@@ -1,103 +1,118 @@
 package storage
 
 import (
-	"encoding/json"
-	"fmt"
-	"os"
 	"time"
 )
 
 // KarmaData represents the stored karma data
 type KarmaData struct {
-	Reviewers    map[string]int    `json:"reviewers"`
+	SchemaVersion int `json:"schema_version"`
+
+	Reviewers    map[string]int    `json:"reviewers"` // Totals derived from Baseline and Ledger
 	LastUpdated  time.Time         `json:"last_updated"`
 	ProcessedPRs map[int]time.Time `json:"processed_prs"` // PR number -> last processed time
+	Ledger       []LedgerEntry     `json:"ledger"`
+	Events       map[int][]Event   `json:"events"`               // PR number -> raw events of its latest scoring
+	Baseline     map[string]int    `json:"baseline,omitempty"`   // Points from before the ledger existed
+	LegacyPRs    map[int]bool      `json:"legacy_prs,omitempty"` // PRs whose points are only in Baseline
+
+	// ScoringFingerprint identifies the scoring configuration the ledger was
+	// last scored with
+	ScoringFingerprint string `json:"scoring_fingerprint,omitempty"`
+
+	// dirtyPRs are the PRs recorded since the data was last saved, so
+	// backends that store PRs separately only write those
+	dirtyPRs map[int]bool
+
+	// prLedger indexes the positions of each PR's entries in the first
+	// indexedLedger entries of the ledger
+	prLedger      map[int][]int
+	indexedLedger int
 }
 
 // Storage handles persistence of karma data
 type Storage struct {
-	filePath string
+	backend Backend
 }
 
-// NewStorage creates a new storage instance
+// NewStorage creates a new storage instance backed by a JSON file
 func NewStorage(filePath string) *Storage {
+	return NewStorageWithBackend(NewFileBackend(filePath))
+}
+
+// NewStorageWithBackend creates a new storage instance on the given backend
+func NewStorageWithBackend(backend Backend) *Storage {
 	return &Storage{
-		filePath: filePath,
+		backend: backend,
 	}
 }
 
-// Load loads karma data from file
+// Load loads karma data from the backend
 func (s *Storage) Load() (*KarmaData, error) {
-	data, err := os.ReadFile(s.filePath)
+	data, err := s.backend.Load()
 	if err != nil {
-		if os.IsNotExist(err) {
-			// File doesn't exist, return empty data
-			return &KarmaData{
-				Reviewers:    make(map[string]int),
-				LastUpdated:  time.Time{},
-				ProcessedPRs: make(map[int]time.Time),
-			}, nil
-		}
-		return nil, fmt.Errorf("failed to read karma data: %w", err)
+		return nil, err
 	}
+	data.indexLedger()
+	return data, nil
+}
 
-	// Handle empty file
-	if len(data) == 0 {
-		return &KarmaData{
-			Reviewers:    make(map[string]int),
-			LastUpdated:  time.Time{},
-			ProcessedPRs: make(map[int]time.Time),
-		}, nil
+// Save saves karma data to the backend
+func (s *Storage) Save(data *KarmaData) error {
+	// Data built by hand with totals but no ledger, as older callers did,
+	// is upgraded like a version 1 file
+	if data.SchemaVersion == 0 && data.Ledger == nil {
+		if data.ProcessedPRs == nil {
+			data.ProcessedPRs = make(map[int]time.Time)
+		}
+		data.LegacyPRs = make(map[int]bool)
+		data.importLegacy()
 	}
 
-	var karmaData KarmaData
-	if err := json.Unmarshal(data, &karmaData); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal karma data: %w", err)
-	}
+	data.SchemaVersion = CurrentSchemaVersion
+	data.LastUpdated = time.Now()
 
-	// Initialize maps if they're nil
-	if karmaData.Reviewers == nil {
-		karmaData.Reviewers = make(map[string]int)
-	}
-	if karmaData.ProcessedPRs == nil {
-		karmaData.ProcessedPRs = make(map[int]time.Time)
+	if err := s.backend.Save(data); err != nil {
+		return err
 	}
-
-	return &karmaData, nil
+	data.dirtyPRs = nil
+	return nil
 }
 
-// Save saves karma data to file
-func (s *Storage) Save(data *KarmaData) error {
-	data.LastUpdated = time.Now()
-
-	jsonData, err := json.MarshalIndent(data, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal karma data: %w", err)
-	}
+// Close releases the resources held by the backend
+func (s *Storage) Close() error {
+	return s.backend.Close()
+}
 
-	if err := os.WriteFile(s.filePath, jsonData, 0644); err != nil {
-		return fmt.Errorf("failed to write karma data: %w", err)
-	}
+// UpdateKarma records the reviewer points of a PR and marks it processed now.
+// If the PR was scored before, its previous points are replaced.
+func (s *Storage) UpdateKarma(prNumber int, reviewerKarma map[string]int) error {
+	return s.UpdateKarmaAt(prNumber, reviewerKarma, time.Now())
+}
 
-	return nil
+// UpdateKarmaAt records the reviewer points of a PR and marks it processed at
+// the given time. The points are not tied to individual events, so the PR
+// can't be re-scored later; use RecordPR to keep a per-event ledger.
+func (s *Storage) UpdateKarmaAt(prNumber int, reviewerKarma map[string]int, processedAt time.Time) error {
+	return s.RecordPR(prNumber, nil, aggregateEntries(reviewerKarma, processedAt), processedAt)
 }
 
-// UpdateKarma updates karma data with new reviewer points
-func (s *Storage) UpdateKarma(prNumber int, reviewerKarma map[string]int) error {
-	data, err := s.Load()
+// RecordPR stores the raw events of a PR and the ledger entries scored from
+// them, and marks it processed at the given time. If the PR was scored
+// before, its changed awards are reversed so totals stay correct. Use Begin
+// to record many PRs with a single write.
+func (s *Storage) RecordPR(prNumber int, events []Event, entries []LedgerEntry, processedAt time.Time) error {
+	tx, err := s.Begin()
 	if err != nil {
 		return err
 	}
 
-	// Update reviewer karma
-	for username, points := range reviewerKarma {
-		data.Reviewers[username] += points
+	err = tx.Apply(PRUpdate{PRNumber: prNumber, Events: events, Entries: entries, ProcessedAt: processedAt})
+	if err != nil {
+		return err
 	}
 
-	// Mark PR as processed
-	data.ProcessedPRs[prNumber] = time.Now()
-
-	return s.Save(data)
+	return tx.Commit()
 }
 
 // GetProcessedPRs returns a map of processed PR numbers
@@ -122,28 +137,28 @@ func (s *Storage) GetKarmaData() (*KarmaData, error) {
 
 // Clear clears all stored data
 func (s *Storage) Clear() error {
-	data := &KarmaData{
-		Reviewers:    make(map[string]int),
-		LastUpdated:  time.Now(),
-		ProcessedPRs: make(map[int]time.Time),
-	}
-	return s.Save(data)
+	return s.Save(newKarmaData(time.Now()))
 }
 
 // NewEmptyKarmaData creates a new empty KarmaData instance
 func NewEmptyKarmaData() *KarmaData {
-	return &KarmaData{
-		Reviewers:    make(map[string]int),
-		LastUpdated:  time.Now(),
-		ProcessedPRs: make(map[int]time.Time),
-	}
+	return newKarmaData(time.Now())
 }
 
 // CreateEmptyKarmaData creates a new empty KarmaData instance
 func CreateEmptyKarmaData() *KarmaData {
+	return newKarmaData(time.Now())
+}
+
+// newKarmaData creates an empty KarmaData with all maps initialized
+func newKarmaData(lastUpdated time.Time) *KarmaData {
 	return &KarmaData{
-		Reviewers:    make(map[string]int),
-		LastUpdated:  time.Now(),
-		ProcessedPRs: make(map[int]time.Time),
+		SchemaVersion: CurrentSchemaVersion,
+		Reviewers:     make(map[string]int),
+		LastUpdated:   lastUpdated,
+		ProcessedPRs:  make(map[int]time.Time),
+		Ledger:        []LedgerEntry{},
+		Events:        make(map[int][]Event),
+		LegacyPRs:     make(map[int]bool),
 	}
 }
@@ -2,8 +2,12 @@ package storage
 
 import (
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/master-wayne7/reviewer-karma-action/internal/atomicfile"
 )
 
 func TestStorage_LoadSave(t *testing.T) {
@@ -13,6 +17,7 @@ func TestStorage_LoadSave(t *testing.T) {
 		t.Fatalf("Failed to create temp file: %v", err)
 	}
 	defer os.Remove(tmpFile.Name())
+	defer os.Remove(atomicfile.BackupPath(tmpFile.Name()))
 	defer tmpFile.Close()
 
 	storage := NewStorage(tmpFile.Name())
@@ -72,6 +77,7 @@ func TestStorage_UpdateKarma(t *testing.T) {
 		t.Fatalf("Failed to create temp file: %v", err)
 	}
 	defer os.Remove(tmpFile.Name())
+	defer os.Remove(atomicfile.BackupPath(tmpFile.Name()))
 	defer tmpFile.Close()
 
 	storage := NewStorage(tmpFile.Name())
@@ -142,6 +148,7 @@ func TestStorage_Clear(t *testing.T) {
 		t.Fatalf("Failed to create temp file: %v", err)
 	}
 	defer os.Remove(tmpFile.Name())
+	defer os.Remove(atomicfile.BackupPath(tmpFile.Name()))
 	defer tmpFile.Close()
 
 	storage := NewStorage(tmpFile.Name())
@@ -173,3 +180,253 @@ func TestStorage_Clear(t *testing.T) {
 		t.Errorf("Expected 0 processed PRs after clear, got %d", len(data.ProcessedPRs))
 	}
 }
+
+func TestStorage_UpdateKarmaReplacesPreviousContribution(t *testing.T) {
+	// Create temporary file
+	tmpFile, err := os.CreateTemp("", "karma_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpFile.Name())
+	defer os.Remove(atomicfile.BackupPath(tmpFile.Name()))
+	defer tmpFile.Close()
+
+	storage := NewStorage(tmpFile.Name())
+
+	if err := storage.UpdateKarma(1, map[string]int{"alice": 5, "bob": 2}); err != nil {
+		t.Fatalf("Failed to update karma: %v", err)
+	}
+	if err := storage.UpdateKarma(2, map[string]int{"alice": 1}); err != nil {
+		t.Fatalf("Failed to update karma: %v", err)
+	}
+
+	// Re-score PR 1 after new activity
+	processedAt := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	if err := storage.UpdateKarmaAt(1, map[string]int{"alice": 6, "carol": 3}, processedAt); err != nil {
+		t.Fatalf("Failed to re-score karma: %v", err)
+	}
+
+	data, err := storage.Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+
+	if data.Reviewers["alice"] != 7 {
+		t.Errorf("Expected alice to have 7 points, got %d", data.Reviewers["alice"])
+	}
+
+	if _, ok := data.Reviewers["bob"]; ok {
+		t.Errorf("Expected bob to be removed, got %d points", data.Reviewers["bob"])
+	}
+
+	if data.Reviewers["carol"] != 3 {
+		t.Errorf("Expected carol to have 3 points, got %d", data.Reviewers["carol"])
+	}
+
+	if !data.ProcessedPRs[1].Equal(processedAt) {
+		t.Errorf("Expected PR 1 processed at %v, got %v", processedAt, data.ProcessedPRs[1])
+	}
+
+	if !data.HasPRRecord(2) || data.HasPRRecord(3) {
+		t.Error("Expected a record for PR 2 only")
+	}
+}
+
+func TestKarmaData_RecordPRReversesPreviousEntries(t *testing.T) {
+	data := NewEmptyKarmaData()
+	reviewedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	data.RecordPR(1, nil, []LedgerEntry{
+		{EventKind: "review", EventID: 10, User: "alice", Rule: "review", Points: 3, Timestamp: reviewedAt},
+		{EventKind: "review", EventID: 11, User: "bob", Rule: "review", Points: 1, Timestamp: reviewedAt},
+	}, reviewedAt)
+
+	// Re-score with bob's review now earning more
+	data.RecordPR(1, nil, []LedgerEntry{
+		{EventKind: "review", EventID: 10, User: "alice", Rule: "review", Points: 3, Timestamp: reviewedAt},
+		{EventKind: "review", EventID: 11, User: "bob", Rule: "review", Points: 2, Timestamp: reviewedAt},
+	}, reviewedAt)
+
+	// The ledger only grows: 2 original entries, then a reversal and a new
+	// entry for bob's changed award. alice's unchanged award is kept.
+	if len(data.Ledger) != 4 {
+		t.Fatalf("Expected 4 ledger entries, got %d", len(data.Ledger))
+	}
+
+	reversals := 0
+	for _, entry := range data.Ledger {
+		if entry.Reversal {
+			reversals++
+			if entry.Points >= 0 {
+				t.Errorf("Expected reversal to have negative points, got %d", entry.Points)
+			}
+		}
+	}
+	if reversals != 1 {
+		t.Errorf("Expected 1 reversal entry, got %d", reversals)
+	}
+
+	if data.Reviewers["alice"] != 3 || data.Reviewers["bob"] != 2 {
+		t.Errorf("Unexpected totals: %v", data.Reviewers)
+	}
+
+	if len(data.PREntries(1)) != 2 {
+		t.Errorf("Expected 2 current entries for PR 1, got %d", len(data.PREntries(1)))
+	}
+
+	totals := data.Totals()
+	if len(totals) != len(data.Reviewers) || totals["alice"] != 3 || totals["bob"] != 2 {
+		t.Errorf("Expected derived totals to match %v, got %v", data.Reviewers, totals)
+	}
+}
+
+func TestKarmaData_PREntriesIndex(t *testing.T) {
+	data := NewEmptyKarmaData()
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for prNumber := 1; prNumber <= 3; prNumber++ {
+		data.RecordPR(prNumber, nil, []LedgerEntry{{EventKind: "review", EventID: int64(prNumber), User: "alice", Points: prNumber}}, at)
+	}
+
+	// A clone extends its own index without changing the original's
+	clone := data.clone()
+	clone.RecordPR(2, nil, []LedgerEntry{{EventKind: "review", EventID: 2, User: "alice", Points: 5}}, at)
+	if entries := clone.PREntries(2); len(entries) != 1 || entries[0].Points != 5 {
+		t.Errorf("Expected the clone's rescored entry for PR 2, got %+v", entries)
+	}
+	if entries := data.PREntries(2); len(entries) != 1 || entries[0].Points != 2 {
+		t.Errorf("Expected the original entry for PR 2, got %+v", entries)
+	}
+
+	// Entries appended to the ledger directly are indexed on the next read
+	data.Ledger = append(data.Ledger, LedgerEntry{PRNumber: 4, User: "bob", Points: 1})
+	if entries := data.PREntries(4); len(entries) != 1 || entries[0].User != "bob" {
+		t.Errorf("Expected the appended entry for PR 4, got %+v", entries)
+	}
+	if entries := data.PREntries(1); len(entries) != 1 || entries[0].Points != 1 {
+		t.Errorf("Expected PR 1's entry to be unaffected, got %+v", entries)
+	}
+}
+
+func TestKarmaData_TotalsSince(t *testing.T) {
+	data := NewEmptyKarmaData()
+	data.Baseline = map[string]int{"carol": 10}
+	since := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	data.RecordPR(1, nil, []LedgerEntry{
+		{EventKind: "review", EventID: 10, User: "alice", Rule: "review", Points: 3, Timestamp: since.AddDate(0, 0, -1)},
+		{EventKind: "review", EventID: 11, User: "bob", Rule: "review", Points: 2, Timestamp: since},
+	}, since)
+	data.RecordPR(2, nil, []LedgerEntry{
+		{EventKind: "review", EventID: 20, User: "alice", Rule: "review", Points: 1, Timestamp: since.AddDate(0, 0, 1)},
+	}, since)
+
+	// Dropping bob's award reverses it with the original timestamp
+	data.RecordPR(1, nil, []LedgerEntry{
+		{EventKind: "review", EventID: 10, User: "alice", Rule: "review", Points: 3, Timestamp: since.AddDate(0, 0, -1)},
+	}, since.AddDate(0, 0, 2))
+
+	totals := data.TotalsSince(since)
+	if len(totals) != 1 || totals["alice"] != 1 {
+		t.Errorf("Expected only alice's later point, got %v", totals)
+	}
+
+	all := data.TotalsSince(time.Time{})
+	if len(all) != 1 || all["alice"] != 4 {
+		t.Errorf("Expected the ledger without the baseline, got %v", all)
+	}
+}
+
+func TestStorage_LoadImportsLegacyData(t *testing.T) {
+	tmpFile, err := os.CreateTemp("", "karma_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpFile.Name())
+	defer os.Remove(atomicfile.BackupPath(tmpFile.Name()))
+	defer tmpFile.Close()
+
+	// The baseline layout only kept totals and processed PRs
+	legacy := `{
+  "reviewers": {"alice": 7, "bob": 3},
+  "last_updated": "2024-01-02T00:00:00Z",
+  "processed_prs": {"1": "2024-01-01T00:00:00Z", "2": "2024-01-02T00:00:00Z"}
+}`
+	if _, err := tmpFile.WriteString(legacy); err != nil {
+		t.Fatalf("Failed to write legacy data: %v", err)
+	}
+
+	data, err := NewStorage(tmpFile.Name()).Load()
+	if err != nil {
+		t.Fatalf("Failed to load legacy data: %v", err)
+	}
+
+	if data.Reviewers["alice"] != 7 || data.Reviewers["bob"] != 3 {
+		t.Errorf("Expected totals to be kept, got %v", data.Reviewers)
+	}
+
+	if data.Baseline["alice"] != 7 || data.Baseline["bob"] != 3 {
+		t.Errorf("Expected the totals to become the baseline, got %v", data.Baseline)
+	}
+
+	if len(data.Ledger) != 0 {
+		t.Errorf("Expected an empty ledger, got %v", data.Ledger)
+	}
+
+	if data.HasPRRecord(1) || data.HasPRRecord(2) {
+		t.Error("Expected no PR records")
+	}
+}
+
+func TestStorage_LoadFallsBackToBackup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".karma-data.json")
+	storage := NewStorage(path)
+
+	if err := storage.UpdateKarma(1, map[string]int{"alice": 5}); err != nil {
+		t.Fatalf("Failed to update karma: %v", err)
+	}
+	if err := storage.UpdateKarma(2, map[string]int{"bob": 3}); err != nil {
+		t.Fatalf("Failed to update karma: %v", err)
+	}
+
+	// Simulate a runner killed half way through an older, non-atomic write
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read data: %v", err)
+	}
+	if err := os.WriteFile(path, content[:len(content)/2], 0644); err != nil {
+		t.Fatalf("Failed to truncate data: %v", err)
+	}
+
+	data, err := storage.Load()
+	if err != nil {
+		t.Fatalf("Expected to recover from the backup, got: %v", err)
+	}
+
+	// The backup is the generation before PR 2 was recorded
+	if data.Reviewers["alice"] != 5 || data.ProcessedPRs[2] != (time.Time{}) {
+		t.Errorf("Expected the previous generation, got reviewers %v", data.Reviewers)
+	}
+
+	// Saving again must not replace the good backup with the corrupt file
+	if err := storage.Save(data); err != nil {
+		t.Fatalf("Failed to save data: %v", err)
+	}
+	backup, err := os.ReadFile(atomicfile.BackupPath(path))
+	if err != nil {
+		t.Fatalf("Failed to read backup: %v", err)
+	}
+	if !strings.Contains(string(backup), `"alice"`) {
+		t.Errorf("Expected backup to keep the previous generation, got:\n%s", backup)
+	}
+}
+
+func TestStorage_LoadCorruptWithoutBackup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".karma-data.json")
+	if err := os.WriteFile(path, []byte(`{"reviewers": {"alice"`), 0644); err != nil {
+		t.Fatalf("Failed to write data: %v", err)
+	}
+
+	if _, err := NewStorage(path).Load(); err == nil {
+		t.Error("Expected an error for corrupt data without a backup")
+	}
+}
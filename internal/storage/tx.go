@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrTxDone is returned when a transaction is used after it was committed
+var ErrTxDone = errors.New("transaction has already been committed")
+
+// PRUpdate is the result of scoring one pull request
+type PRUpdate struct {
+	PRNumber    int
+	Events      []Event // Raw events kept for rescoring, nil to keep none
+	Entries     []LedgerEntry
+	ProcessedAt time.Time
+}
+
+// maxCommitAttempts is how often Commit merges and retries on a conflict
+const maxCommitAttempts = 5
+
+// Tx batches updates to the karma data. The data is read once by Begin, every
+// update is applied in memory, and Commit writes the result once.
+type Tx struct {
+	storage *Storage
+	base    *KarmaData // The data as loaded, to merge concurrent changes against
+	data    *KarmaData
+	rescore Rescorer // Scores PRs both sides changed when merging
+	done    bool
+}
+
+// Begin loads the karma data and starts a transaction on it
+func (s *Storage) Begin() (*Tx, error) {
+	data, err := s.Load()
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{storage: s, base: data.clone(), data: data}, nil
+}
+
+// SetRescorer sets how Commit scores the combined events of a PR that a
+// concurrent run changed as well. Without one, the more recently processed
+// scoring of the PR wins.
+func (tx *Tx) SetRescorer(rescore Rescorer) {
+	tx.rescore = rescore
+}
+
+// Data returns the transaction's in-memory karma data, including the updates
+// applied so far. Changes made to it directly are saved by Commit as well.
+func (tx *Tx) Data() *KarmaData {
+	return tx.data
+}
+
+// Apply records a scored pull request in memory
+func (tx *Tx) Apply(update PRUpdate) error {
+	if tx.done {
+		return ErrTxDone
+	}
+	tx.data.RecordPR(update.PRNumber, update.Events, update.Entries, update.ProcessedAt)
+	return nil
+}
+
+// Commit writes the karma data to the backend. If the stored data was changed
+// since Begin, such as by a concurrent run, the transaction's changes are
+// merged into the latest data and written again. The transaction can't be
+// used afterwards.
+func (tx *Tx) Commit() error {
+	if err := tx.Checkpoint(); err != nil {
+		return err
+	}
+	tx.done = true
+	return nil
+}
+
+// Checkpoint writes the updates applied so far like Commit, but keeps the
+// transaction open, so a long run that is interrupted keeps its progress
+func (tx *Tx) Checkpoint() error {
+	if tx.done {
+		return ErrTxDone
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := tx.storage.Save(tx.data)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, ErrConflict) || attempt == maxCommitAttempts {
+			return err
+		}
+
+		theirs, err := tx.storage.Load()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("🔀 Karma data was changed by another run, merging and retrying (attempt %d of %d)\n", attempt+1, maxCommitAttempts)
+
+		// Data keeps pointing to the same value for callers holding it
+		*tx.data = *Merge(tx.base, tx.data, theirs, tx.rescore)
+		tx.base = theirs
+	}
+
+	// Later changes are merged against what was just written
+	tx.base = tx.data.clone()
+	return nil
+}
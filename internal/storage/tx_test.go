@@ -0,0 +1,163 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/master-wayne7/reviewer-karma-action/internal/atomicfile"
+)
+
+func TestTx_CommitWritesOnce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".karma-data.json")
+	storage := NewStorage(path)
+
+	tx, err := storage.Begin()
+	if err != nil {
+		t.Fatalf("Failed to begin: %v", err)
+	}
+
+	processedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for prNumber := 1; prNumber <= 100; prNumber++ {
+		err := tx.Apply(PRUpdate{
+			PRNumber: prNumber,
+			Entries: []LedgerEntry{
+				{EventKind: "review", EventID: int64(prNumber), User: "alice", Rule: "review", Points: 1},
+				{EventKind: "review", EventID: int64(1000 + prNumber), User: "bob", Rule: "review", Points: prNumber % 3},
+			},
+			ProcessedAt: processedAt,
+		})
+		if err != nil {
+			t.Fatalf("Failed to apply PR %d: %v", prNumber, err)
+		}
+	}
+
+	// Re-apply one PR with different points
+	err = tx.Apply(PRUpdate{
+		PRNumber:    7,
+		Entries:     []LedgerEntry{{EventKind: "review", EventID: 7, User: "alice", Rule: "review", Points: 5}},
+		ProcessedAt: processedAt,
+	})
+	if err != nil {
+		t.Fatalf("Failed to re-apply PR 7: %v", err)
+	}
+
+	// Nothing is written before Commit
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("Expected no data file before commit, got: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+
+	// A single write leaves no previous generation to back up
+	if _, err := os.Stat(atomicfile.BackupPath(path)); !os.IsNotExist(err) {
+		t.Errorf("Expected a single write, but a backup exists: %v", err)
+	}
+
+	loaded, err := storage.Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+
+	inMemory := tx.Data().Reviewers
+	if len(loaded.Reviewers) != len(inMemory) {
+		t.Errorf("Expected %d reviewers on disk, got %d", len(inMemory), len(loaded.Reviewers))
+	}
+	for username, points := range inMemory {
+		if loaded.Reviewers[username] != points {
+			t.Errorf("Expected %s to have %d points on disk, got %d", username, points, loaded.Reviewers[username])
+		}
+	}
+
+	// alice: 99 PRs at 1 point and PR 7 at 5; bob: PR 7's award was removed
+	if inMemory["alice"] != 104 || inMemory["bob"] != 99 {
+		t.Errorf("Unexpected totals: %v", inMemory)
+	}
+
+	if len(loaded.ProcessedPRs) != 100 {
+		t.Errorf("Expected 100 processed PRs, got %d", len(loaded.ProcessedPRs))
+	}
+}
+
+func TestTx_UseAfterCommit(t *testing.T) {
+	tx, err := NewStorage(filepath.Join(t.TempDir(), ".karma-data.json")).Begin()
+	if err != nil {
+		t.Fatalf("Failed to begin: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+
+	if err := tx.Apply(PRUpdate{PRNumber: 1}); !errors.Is(err, ErrTxDone) {
+		t.Errorf("Expected ErrTxDone from Apply, got: %v", err)
+	}
+	if err := tx.Commit(); !errors.Is(err, ErrTxDone) {
+		t.Errorf("Expected ErrTxDone from Commit, got: %v", err)
+	}
+}
+
+func TestTx_Checkpoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".karma-data.json")
+	processedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	update := func(prNumber int, user string) PRUpdate {
+		return PRUpdate{
+			PRNumber:    prNumber,
+			Entries:     []LedgerEntry{{EventKind: "review", EventID: int64(prNumber), User: user, Rule: "review", Points: 1}},
+			ProcessedAt: processedAt,
+		}
+	}
+
+	tx, err := NewStorage(path).Begin()
+	if err != nil {
+		t.Fatalf("Failed to begin: %v", err)
+	}
+	if err := tx.Apply(update(1, "alice")); err != nil {
+		t.Fatalf("Failed to apply PR 1: %v", err)
+	}
+	if err := tx.Checkpoint(); err != nil {
+		t.Fatalf("Failed to checkpoint: %v", err)
+	}
+
+	loaded, err := NewStorage(path).Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	if loaded.Reviewers["alice"] != 1 {
+		t.Errorf("Expected the checkpoint to be written, got %v", loaded.Reviewers)
+	}
+
+	// Another run commits between the checkpoint and the commit
+	other, err := NewStorage(path).Begin()
+	if err != nil {
+		t.Fatalf("Failed to begin other transaction: %v", err)
+	}
+	if err := other.Apply(update(2, "bob")); err != nil {
+		t.Fatalf("Failed to apply PR 2: %v", err)
+	}
+	if err := other.Commit(); err != nil {
+		t.Fatalf("Failed to commit other transaction: %v", err)
+	}
+
+	if err := tx.Apply(update(3, "alice")); err != nil {
+		t.Fatalf("Failed to apply PR 3: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+
+	loaded, err = NewStorage(path).Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	if loaded.Reviewers["alice"] != 2 || loaded.Reviewers["bob"] != 1 {
+		t.Errorf("Expected alice 2 and bob 1, got %v", loaded.Reviewers)
+	}
+	if len(loaded.ProcessedPRs) != 3 {
+		t.Errorf("Expected 3 processed PRs, got %d", len(loaded.ProcessedPRs))
+	}
+}